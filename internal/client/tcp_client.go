@@ -66,14 +66,15 @@ func (cli *TCPClient) Start() {
 }
 
 func (cli *TCPClient) Stop() {
-	cli.Session.Conn.Close()
+	cli.Session.Close()
 }
 
 func (cli *TCPClient) Send(msg model.JT808Msg) {
 	pg := protocol.NewPipeline(cli.Session.Conn)
 
 	// 记录value ctx
-	ctx := context.WithValue(context.Background(), model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
+	ctx := context.WithValue(context.Background(), model.SessionCtxKey{}, cli.Session)
+	ctx = context.WithValue(ctx, model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
 
 	err := pg.ProcessConnWrite(ctx)
 
@@ -81,25 +81,11 @@ func numberStr2BCD(number string) []byte {
 	return bcd
 }
 
-// 对应JT808类型BYTE
-func ReadByte(pkt []byte, idx *int) uint8 {
-	ans := pkt[*idx]
-	*idx++
-	return ans
-}
-
 // 对应JT808类型BYTE
 func WriteByte(pkt []byte, num uint8) []byte {
 	return append(pkt, num)
 }
 
-// 对应JT808类型WORD
-func ReadWord(pkt []byte, idx *int) uint16 {
-	ans := binary.BigEndian.Uint16(pkt[*idx : *idx+2])
-	*idx += 2
-	return ans
-}
-
 // 对应JT808类型WORD
 func WriteWord(pkt []byte, num uint16) []byte {
 	numPkt := make([]byte, 2)
@@ -107,13 +93,6 @@ func WriteWord(pkt []byte, num uint16) []byte {
 	return append(pkt, numPkt...)
 }
 
-// 对应JT808类型DWORD
-func ReadDoubleWord(pkt []byte, idx *int) uint32 {
-	ans := binary.BigEndian.Uint32(pkt[*idx : *idx+4])
-	*idx += doubeWordLen
-	return ans
-}
-
 // 对应JT808类型DWORD
 func WriteDoubleWord(pkt []byte, num uint32) []byte {
 	numPkt := make([]byte, doubeWordLen)
@@ -121,49 +100,31 @@ func WriteDoubleWord(pkt []byte, num uint32) []byte {
 	return append(pkt, numPkt...)
 }
 
-// 对应JT808类型BYTE[n]
-func ReadBytes(pkt []byte, idx *int, n int) []byte {
-	ans := pkt[*idx : *idx+n]
-	*idx += n
-	return ans
-}
-
 // 对应JT808类型BYTE[n]
 func WriteBytes(pkt, arr []byte) []byte {
 	return append(pkt, arr...)
 }
 
-// 对应JT808类型BYTE[n]
-func ReadString(pkt []byte, idx *int, n int) string {
-	return string(ReadBytes(pkt, idx, n))
-}
-
 // 对应JT808类型BYTE[n]
 func WriteString(pkt []byte, str string) []byte {
 	arr := []byte(str)
 	return WriteBytes(pkt, arr)
 }
 
-// 对应JT808类型BCD[n]
-func ReadBCD(pkt []byte, idx *int, n int) string {
-	ans := bcd2NumberStr(pkt[*idx : *idx+n])
-	*idx += n
-	return ans
-}
-
 // 对应JT808类型BCD[n]
 func WriteBCD(pkt []byte, bcd string) []byte {
 	return append(pkt, numberStr2BCD(bcd)...)
 }
 
-// 对应JT808类型String
-func ReadGBK(pkt []byte, idx *int, n int) string {
-	gbk, err := GBK.GBK2UTF8(pkt[*idx : *idx+n])
-	*idx += n
-	if err != nil {
-		return ""
+// 对应JT808类型BCD[n]，写入固定的n字节。位数不足时左侧补0，超出时保留右侧的数字，如终端手机号
+func WriteFixedBCD(pkt []byte, bcd string, n int) []byte {
+	digits := n * 2
+	if len(bcd) < digits {
+		bcd = strings.Repeat("0", digits-len(bcd)) + bcd
+	} else {
+		bcd = bcd[len(bcd)-digits:]
 	}
-	return string(gbk)
+	return append(pkt, Str2Byte(bcd)...)
 }
 
 // 对应JT808类型String
@@ -175,13 +136,6 @@ func WriteGBK(pkt []byte, str string) []byte {
 	return append(pkt, gbk...)
 }
 
-// 输入JT808协议定义的时间format, 转换为time.Time
-func ReadTime(pkt []byte, idx *int) *time.Time {
-	timeStr := ReadBCD(pkt, idx, timeBCDLen)
-	timeIns := ParseTime(timeStr)
-	return &timeIns
-}
-
 // 输入time.Time, 转换为JT808协议定义的时间format
 func WriteTime(pkt []byte, timeIns time.Time) []byte {
 	return WriteBCD(pkt, FormatTime(timeIns))
@@ -120,34 +120,6 @@ func TestNumberStr2BCD(t *testing.T) {
 	}
 }
 
-func TestReadByte(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-	}
-	tests := []struct {
-		name string
-		args args
-		want uint8
-	}{
-		{
-			name: "case1: read 0x9a",
-			args: args{
-				pkt: []byte{0x9a},
-				idx: &[]int{0}[0],
-			},
-			want: 154,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := ReadByte(tt.args.pkt, tt.args.idx)
-			require.Equal(t, tt.want, got)
-			require.Equal(t, tt.args.idx, &[]int{1}[0])
-		})
-	}
-}
-
 func TestWriteByte(t *testing.T) {
 	type args struct {
 		pkt []byte
@@ -176,27 +148,6 @@ func TestWriteByte(t *testing.T) {
 	}
 }
 
-func TestReadWord(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-	}
-	tests := []struct {
-		name string
-		args args
-		want uint16
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := ReadWord(tt.args.pkt, tt.args.idx); got != tt.want {
-				t.Errorf("ReadWord() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestWriteWord(t *testing.T) {
 	type args struct {
 		pkt []byte
@@ -218,27 +169,6 @@ func TestWriteWord(t *testing.T) {
 	}
 }
 
-func TestReadDoubleWord(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-	}
-	tests := []struct {
-		name string
-		args args
-		want uint32
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := ReadDoubleWord(tt.args.pkt, tt.args.idx); got != tt.want {
-				t.Errorf("ReadDoubleWord() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestWriteDoubleWord(t *testing.T) {
 	type args struct {
 		pkt []byte
@@ -260,28 +190,6 @@ func TestWriteDoubleWord(t *testing.T) {
 	}
 }
 
-func TestReadString(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-		n   int
-	}
-	tests := []struct {
-		name string
-		args args
-		want string
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := ReadString(tt.args.pkt, tt.args.idx, tt.args.n); got != tt.want {
-				t.Errorf("ReadString() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestWriteString(t *testing.T) {
 	type args struct {
 		pkt []byte
@@ -303,28 +211,6 @@ func TestWriteString(t *testing.T) {
 	}
 }
 
-func TestReadBCD(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-		n   int
-	}
-	tests := []struct {
-		name string
-		args args
-		want string
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := ReadBCD(tt.args.pkt, tt.args.idx, tt.args.n); got != tt.want {
-				t.Errorf("ReadBCD() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestWriteBCD(t *testing.T) {
 	type args struct {
 		pkt []byte
@@ -346,32 +232,20 @@ func TestWriteBCD(t *testing.T) {
 	}
 }
 
-func TestReadGBK(t *testing.T) {
-	type args struct {
-		pkt []byte
-		idx *int
-		n   int
-	}
+func TestWriteFixedBCD(t *testing.T) {
 	tests := []struct {
 		name string
-		args args
-		want string
+		bcd  string
+		n    int
+		want []byte
 	}{
-		{
-			name: "case1: 京A12345",
-			args: args{
-				pkt: Str2Byte("BEA9413132333435"),
-				idx: &[]int{0}[0],
-				n:   8,
-			},
-			want: "京A12345",
-		},
+		{name: "case1: exact length", bcd: "013912345678", n: 6, want: Str2Byte("013912345678")},
+		{name: "case2: pad zero", bcd: "13912345678", n: 10, want: Str2Byte("00000000013912345678")},
+		{name: "case3: truncate", bcd: "00000000013912345678", n: 6, want: Str2Byte("013912345678")},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ReadGBK(tt.args.pkt, tt.args.idx, tt.args.n)
-			require.Equal(t, tt.want, got)
-			require.Equal(t, 8, *tt.args.idx)
+			require.Equal(t, tt.want, WriteFixedBCD(nil, tt.bcd, tt.n))
 		})
 	}
 }
@@ -0,0 +1,167 @@
+package hex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	GBK "github.com/fakeyanss/jt808-server-go/internal/codec/gbk"
+)
+
+var (
+	ErrShortRead    = errors.New("Fail to read, not enough bytes")
+	ErrInvalidField = errors.New("Fail to read, invalid field")
+)
+
+// ReadError 读取字段失败的位置信息
+type ReadError struct {
+	Field  string // 字段名
+	Offset int    // 字段起始下标
+	Want   int    // 需要读取的字节数
+	Remain int    // 剩余可读的字节数
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("%v: field=%s, offset=%d, want=%d, remain=%d", e.Err, e.Field, e.Offset, e.Want, e.Remain)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// Reader 带边界检查的游标读取器。
+//
+// 第一次读取失败后记录错误，后续读取均返回零值，解码结束时通过Err()统一检查
+type Reader struct {
+	pkt []byte
+	idx int
+	err error
+}
+
+func NewReader(pkt []byte) *Reader {
+	return &Reader{pkt: pkt}
+}
+
+// Idx 当前读取下标
+func (r *Reader) Idx() int {
+	return r.idx
+}
+
+// Len 剩余可读的字节数
+func (r *Reader) Len() int {
+	return len(r.pkt) - r.idx
+}
+
+// Err 第一次读取失败的错误
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Require 检查剩余长度是否足够读取n个字节，不足时记录错误。用于按数量读取列表前的检查
+func (r *Reader) Require(field string, n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if n < 0 || r.Len() < n {
+		r.setErr(field, n, ErrShortRead)
+		return false
+	}
+	return true
+}
+
+// Fail 记录字段校验失败，如长度或取值不合法
+func (r *Reader) Fail(field string, want int) {
+	r.setErr(field, want, ErrInvalidField)
+}
+
+func (r *Reader) setErr(field string, want int, err error) {
+	if r.err != nil {
+		return
+	}
+	r.err = &ReadError{Field: field, Offset: r.idx, Want: want, Remain: r.Len(), Err: err}
+}
+
+// 读取n个字节，长度不足时记录错误并返回nil
+func (r *Reader) next(field string, n int) []byte {
+	if !r.Require(field, n) {
+		return nil
+	}
+	b := r.pkt[r.idx : r.idx+n]
+	r.idx += n
+	return b
+}
+
+// 对应JT808类型BYTE
+func (r *Reader) ReadUint8(field string) uint8 {
+	b := r.next(field, 1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// 对应JT808类型WORD
+func (r *Reader) ReadWord(field string) uint16 {
+	b := r.next(field, 2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+// 对应JT808类型DWORD
+func (r *Reader) ReadDoubleWord(field string) uint32 {
+	b := r.next(field, doubeWordLen)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// 对应JT808类型BYTE[n]，返回的切片是拷贝
+func (r *Reader) ReadBytes(field string, n int) []byte {
+	b := r.next(field, n)
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// 对应JT808类型BYTE[n]
+func (r *Reader) ReadString(field string, n int) string {
+	return string(r.next(field, n))
+}
+
+// 对应JT808类型BCD[n]
+func (r *Reader) ReadBCD(field string, n int) string {
+	b := r.next(field, n)
+	if b == nil {
+		return ""
+	}
+	return bcd2NumberStr(b)
+}
+
+// 对应JT808类型String
+func (r *Reader) ReadGBK(field string, n int) string {
+	b := r.next(field, n)
+	if b == nil {
+		return ""
+	}
+	gbk, err := GBK.GBK2UTF8(b)
+	if err != nil {
+		return ""
+	}
+	return string(gbk)
+}
+
+// 读取JT808协议定义的BCD[6]时间
+func (r *Reader) ReadTime(field string) *time.Time {
+	timeStr := r.ReadBCD(field, timeBCDLen)
+	if r.err != nil {
+		return nil
+	}
+	timeIns := ParseTime(timeStr)
+	return &timeIns
+}
@@ -0,0 +1,63 @@
+package hex
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	r := NewReader(Str2Byte("01000200000003013912345678"))
+	require.Equal(t, uint8(1), r.ReadUint8("byte"))
+	require.Equal(t, uint16(2), r.ReadWord("word"))
+	require.Equal(t, uint32(3), r.ReadDoubleWord("dword"))
+	require.Equal(t, "013912345678", r.ReadBCD("bcd", 6))
+	require.Equal(t, 0, r.Len())
+	require.Nil(t, r.Err())
+}
+
+func TestReader_ReadGBK(t *testing.T) {
+	r := NewReader(Str2Byte("9aBEA9413132333435"))
+	require.Equal(t, uint8(154), r.ReadUint8("byte"))
+	require.Equal(t, "京A12345", r.ReadGBK("plate", 8))
+	require.Equal(t, 9, r.Idx())
+	require.Nil(t, r.Err())
+}
+
+func TestReader_shortRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    func(r *Reader)
+		wantErr *ReadError
+	}{
+		{
+			name:    "case1: short word",
+			read:    func(r *Reader) { r.ReadUint8("a"); r.ReadWord("b") },
+			wantErr: &ReadError{Field: "b", Offset: 1, Want: 2, Remain: 1, Err: ErrShortRead},
+		},
+		{
+			name:    "case2: negative length",
+			read:    func(r *Reader) { r.ReadBytes("a", -1) },
+			wantErr: &ReadError{Field: "a", Offset: 0, Want: -1, Remain: 2, Err: ErrShortRead},
+		},
+		{
+			name: "case3: keep first error",
+			read: func(r *Reader) {
+				r.ReadDoubleWord("a")
+				require.Equal(t, uint8(0), r.ReadUint8("b"))
+			},
+			wantErr: &ReadError{Field: "a", Offset: 0, Want: 4, Remain: 2, Err: ErrShortRead},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(Str2Byte("0102"))
+			tt.read(r)
+			require.ErrorIs(t, r.Err(), ErrShortRead)
+			var readErr *ReadError
+			require.True(t, errors.As(r.Err(), &readErr))
+			require.Equal(t, tt.wantErr, readErr)
+		})
+	}
+}
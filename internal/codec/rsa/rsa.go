@@ -0,0 +1,65 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// JT808中RSA公钥的模数n固定为128字节
+const ModulusLen = 128
+
+var ErrInvalidCiphertext = errors.New("Invalid rsa ciphertext length")
+
+// Encrypt 按PKCS#1 v1.5分段加密，每段明文最长为k-11字节，密文为k字节
+func Encrypt(pub *rsa.PublicKey, plain []byte) ([]byte, error) {
+	k := pub.Size()
+	chunkLen := k - 11
+	dst := make([]byte, 0, (len(plain)/chunkLen+1)*k)
+	for start := 0; start < len(plain); start += chunkLen {
+		end := start + chunkLen
+		if end > len(plain) {
+			end = len(plain)
+		}
+		block, err := rsa.EncryptPKCS1v15(rand.Reader, pub, plain[start:end])
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to encrypt by rsa")
+		}
+		dst = append(dst, block...)
+	}
+	return dst, nil
+}
+
+// Decrypt 按k字节分段解密
+func Decrypt(priv *rsa.PrivateKey, cipher []byte) ([]byte, error) {
+	k := priv.Size()
+	if len(cipher)%k != 0 {
+		return nil, ErrInvalidCiphertext
+	}
+	dst := make([]byte, 0, len(cipher))
+	for start := 0; start < len(cipher); start += k {
+		block, err := rsa.DecryptPKCS1v15(rand.Reader, priv, cipher[start:start+k])
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to decrypt by rsa")
+		}
+		dst = append(dst, block...)
+	}
+	return dst, nil
+}
+
+// PublicKey 由JT808消息中的e和n构造公钥
+func PublicKey(e uint32, n []byte) *rsa.PublicKey {
+	return &rsa.PublicKey{E: int(e), N: new(big.Int).SetBytes(n)}
+}
+
+// Modulus 将公钥的n转为固定128字节，高位补0
+func Modulus(pub *rsa.PublicKey) []byte {
+	n := pub.N.Bytes()
+	if len(n) >= ModulusLen {
+		return n[len(n)-ModulusLen:]
+	}
+	dst := make([]byte, ModulusLen-len(n), ModulusLen)
+	return append(dst, n...)
+}
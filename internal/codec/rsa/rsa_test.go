@@ -0,0 +1,47 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name      string
+		plain     []byte
+		wantBlock int
+	}{
+		{
+			name:      "case1: single block",
+			plain:     []byte("jt808"),
+			wantBlock: 1,
+		},
+		{
+			name:      "case2: multiple blocks",
+			plain:     bytes.Repeat([]byte{0x7e}, 300), // 117 * 2 < 300
+			wantBlock: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := PublicKey(uint32(priv.E), Modulus(&priv.PublicKey))
+			cipher, err := Encrypt(pub, tt.plain)
+			require.Nil(t, err)
+			require.Len(t, cipher, tt.wantBlock*ModulusLen)
+
+			plain, err := Decrypt(priv, cipher)
+			require.Nil(t, err)
+			require.Equal(t, tt.plain, plain)
+		})
+	}
+
+	_, err = Decrypt(priv, []byte{0x01})
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
@@ -43,9 +43,19 @@ type LogConf struct {
 }
 
 type serverConf struct {
-	Name   string      `yaml:"name" json:"name"`
-	Port   *servPort   `yaml:"port" json:"port"`
-	Banner *servBanner `yaml:"banner" json:"banner"`
+	Name          string          `yaml:"name" json:"name"`
+	Port          *servPort       `yaml:"port" json:"port"`
+	Banner        *servBanner     `yaml:"banner" json:"banner"`
+	TLS           *ServTLS        `yaml:"tls" json:"tls"`
+	WriteQueue    *ServWriteQueue `yaml:"writeQueue" json:"writeQueue"`
+	Admission     *ServAdmission  `yaml:"admission" json:"admission"`
+	Idle          *ServIdle       `yaml:"idle" json:"idle"`
+	ProxyProtocol *ServProxy      `yaml:"proxyProtocol" json:"proxyProtocol"`
+	Version       *ServVersion    `yaml:"version" json:"version"`
+	Encryption    *ServEncryption `yaml:"encryption" json:"encryption"`
+	Media         *ServMedia      `yaml:"media" json:"media"`
+	Upgrade       *ServUpgrade    `yaml:"upgrade" json:"upgrade"`
+	Geofence      *ServGeofence   `yaml:"geofence" json:"geofence"`
 }
 
 type servPort struct {
@@ -54,6 +64,71 @@ type servPort struct {
 	HTTPPort string `yaml:"httpPort" json:"httpPort"`
 }
 
+// TLS加密监听配置，配置了ClientCAFile时开启双向认证
+type ServTLS struct {
+	Enable       bool   `yaml:"enable" json:"enable"`
+	Port         string `yaml:"port" json:"port"`
+	CertFile     string `yaml:"certFile" json:"certFile"`
+	KeyFile      string `yaml:"keyFile" json:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile" json:"clientCAFile"`
+}
+
+// 会话发送队列配置
+type ServWriteQueue struct {
+	Capacity     int    `yaml:"capacity" json:"capacity"`         // 每个会话的队列容量
+	Overflow     string `yaml:"overflow" json:"overflow"`         // 队列满时的策略: block, dropNewest, dropOldest, close
+	WriteTimeout int    `yaml:"writeTimeout" json:"writeTimeout"` // 单次写超时时间，单位秒
+}
+
+// 连接准入配置，0表示不限制
+type ServAdmission struct {
+	MaxConns         int `yaml:"maxConns" json:"maxConns"`                 // 最大连接数
+	MaxConnsPerIP    int `yaml:"maxConnsPerIP" json:"maxConnsPerIP"`       // 单IP最大连接数
+	AcceptRate       int `yaml:"acceptRate" json:"acceptRate"`             // 每秒最多新建连接数
+	HandshakeTimeout int `yaml:"handshakeTimeout" json:"handshakeTimeout"` // 连接后完成注册或鉴权的超时时间，单位秒
+}
+
+// 连接空闲超时配置，单位秒，超时时间为0表示不限制
+type ServIdle struct {
+	PreAuthTimeout  int `yaml:"preAuthTimeout" json:"preAuthTimeout"`   // 未鉴权连接的空闲超时时间
+	PostAuthTimeout int `yaml:"postAuthTimeout" json:"postAuthTimeout"` // 已鉴权连接的空闲超时时间
+	ReapInterval    int `yaml:"reapInterval" json:"reapInterval"`       // 清理空闲连接的间隔
+}
+
+// PROXY protocol v1/v2配置，只解析来自可信地址段的连接
+type ServProxy struct {
+	Enable       bool     `yaml:"enable" json:"enable"`
+	TrustedCIDRs []string `yaml:"trustedCIDRs" json:"trustedCIDRs"` // 负载均衡的地址段，支持单个IP
+}
+
+// 协议版本配置，2011与2013版本的消息头相同，无法从消息中区分
+type ServVersion struct {
+	Version2011Phones []string `yaml:"version2011Phones" json:"version2011Phones"` // 使用2011版本协议的终端手机号
+}
+
+// 消息体RSA加密配置
+type ServEncryption struct {
+	PrivateKeyFile  string   `yaml:"privateKeyFile" json:"privateKeyFile"`   // 平台1024位RSA私钥，PEM格式，为空时启动时生成
+	MandatoryPhones []string `yaml:"mandatoryPhones" json:"mandatoryPhones"` // 必须加密通信的终端手机号
+}
+
+// 多媒体数据存储配置
+type ServMedia struct {
+	Dir string `yaml:"dir" json:"dir"` // 多媒体文件保存目录，按终端手机号分目录保存文件和索引
+}
+
+type ServUpgrade struct {
+	Dir           string `yaml:"dir" json:"dir"`                     // 升级包和升级任务保存目录
+	ResultTimeout int    `yaml:"resultTimeout" json:"resultTimeout"` // 下发升级包后等待升级结果和重新鉴权的超时时间，单位秒
+}
+
+// 平台侧电子围栏配置
+type ServGeofence struct {
+	Dir          string  `yaml:"dir" json:"dir"`                   // 围栏和绑定关系保存目录
+	Buffer       float64 `yaml:"buffer" json:"buffer"`             // 离开围栏的缓冲距离，单位米
+	ConfirmCount int     `yaml:"confirmCount" json:"confirmCount"` // 进出围栏需要连续确认的位置数
+}
+
 type servBanner struct {
 	Enable     bool   `yaml:"enable" json:"enable"`
 	BannerPath string `yaml:"bannerPath" json:"bannerPath"`
@@ -43,6 +43,10 @@ func TestLoad(t *testing.T) {
 						Enable:     true,
 						BannerPath: "./configs/banner.txt",
 					},
+					ProxyProtocol: &ServProxy{
+						Enable:       true,
+						TrustedCIDRs: []string{"10.0.0.0/8", "192.168.1.10"},
+					},
 				},
 			},
 		},
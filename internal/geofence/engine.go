@@ -0,0 +1,220 @@
+package geofence
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+const (
+	defaultBuffer       = 20 // 默认缓冲距离，单位米
+	defaultConfirmCount = 2  // 默认连续确认次数
+)
+
+// 终端与单个围栏的判断状态
+type fenceState struct {
+	inside    bool
+	enteredAt time.Time
+	dwelled   bool      // 本次进入后是否已产生停留事件
+	pending   int       // 与当前状态相反的连续位置数
+	pendingAt time.Time // 第一个相反位置的时间
+}
+
+// FenceState 终端当前所在的围栏
+type FenceState struct {
+	FenceID   string    `json:"fenceId"`
+	FenceName string    `json:"fenceName"`
+	EnteredAt time.Time `json:"enteredAt"`
+	Dwelled   bool      `json:"dwelled"`
+}
+
+// Engine 平台侧围栏判断，按终端绑定的围栏判断每个最新位置，产生进入、离开和停留事件。
+//
+// 进入需要位置在围栏内，离开需要位置在围栏外超过缓冲距离，且都需要连续多个位置确认，避免定位漂移导致反复进出
+type Engine struct {
+	buffer       float64 // 缓冲距离，单位米
+	confirmCount int     // 状态切换需要的连续位置数
+
+	index    *gridIndex
+	revision uint64                            // 索引对应的围栏版本号
+	states   map[string]map[string]*fenceState // key为终端手机号和围栏ID
+	mutex    *sync.Mutex
+}
+
+var engineSingleton *Engine
+var engineInitOnce sync.Once
+
+func GetEngine() *Engine {
+	engineInitOnce.Do(func() {
+		engineSingleton = &Engine{
+			buffer:       defaultBuffer,
+			confirmCount: defaultConfirmCount,
+			states:       make(map[string]map[string]*fenceState),
+			mutex:        &sync.Mutex{},
+		}
+	})
+	return engineSingleton
+}
+
+// SetHysteresis 设置缓冲距离(米)和连续确认次数，小于等于0时使用默认值
+func (e *Engine) SetHysteresis(buffer float64, confirmCount int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	if confirmCount <= 0 {
+		confirmCount = defaultConfirmCount
+	}
+	e.buffer, e.confirmCount = buffer, confirmCount
+	e.index = nil // 缓冲距离影响外接矩形，重建索引
+}
+
+// 围栏变更后重建索引
+func (e *Engine) refreshIndex() {
+	fences, rev := storage.GetFenceStore().Snapshot()
+	if e.index != nil && rev == e.revision {
+		return
+	}
+	e.index = newGridIndex(fences, e.buffer)
+	e.revision = rev
+}
+
+// Evaluate 判断终端的最新位置，未定位的位置不参与判断，产生的事件保存到事件缓存并回调
+func (e *Engine) Evaluate(dg *model.DeviceGeo) []*model.GeofenceEvent {
+	if dg == nil || dg.Location == nil || dg.Geo == nil || dg.Geo.LocationStatus == 0 {
+		return nil
+	}
+	lat, lng := dg.Location.Latitude, dg.Location.Longitude
+	if dg.Geo.LatitudeType == 1 {
+		lat = -lat
+	}
+	if dg.Geo.LongitudeType == 1 {
+		lng = -lng
+	}
+	t := dg.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	assigned := storage.GetFenceStore().GetAssignments(dg.Phone)
+
+	e.mutex.Lock()
+	states := e.states[dg.Phone]
+	if len(assigned) == 0 && len(states) == 0 {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.refreshIndex()
+	if states == nil {
+		states = make(map[string]*fenceState)
+		e.states[dg.Phone] = states
+	}
+
+	assignedSet := make(map[string]struct{}, len(assigned))
+	for _, id := range assigned {
+		assignedSet[id] = struct{}{}
+	}
+	events := make([]*model.GeofenceEvent, 0)
+	evaluated := make(map[string]struct{})
+	for _, f := range e.index.query(lat, lng) {
+		if _, ok := assignedSet[f.ID]; !ok {
+			continue
+		}
+		evaluated[f.ID] = struct{}{}
+		st, ok := states[f.ID]
+		if !ok {
+			st = &fenceState{}
+			states[f.ID] = st
+		}
+		events = e.transit(events, st, f, signedDistance(f, lat, lng), t)
+	}
+	// 不在索引查询结果中的围栏，距离一定超过缓冲距离
+	for id, st := range states {
+		if _, ok := evaluated[id]; ok {
+			continue
+		}
+		f, exist := e.index.fences[id]
+		if _, ok := assignedSet[id]; !ok || !exist {
+			delete(states, id) // 围栏已删除或解除绑定，不再产生事件
+			continue
+		}
+		events = e.transit(events, st, f, math.Inf(1), t)
+	}
+	for id, st := range states {
+		if !st.inside && st.pending == 0 {
+			delete(states, id)
+		}
+	}
+	if len(states) == 0 {
+		delete(e.states, dg.Phone)
+	}
+	e.mutex.Unlock()
+
+	cache := storage.GetGeofenceEventCache()
+	for _, ev := range events {
+		ev.Phone, ev.Latitude, ev.Longitude = dg.Phone, lat, lng
+		log.Debug().Str("device", ev.Phone).Str("fence", ev.FenceID).Str("event", string(ev.Type)).Msg("Geofence event")
+		cache.CacheEvent(ev)
+	}
+	return events
+}
+
+// 按有向距离更新状态，d<=0在围栏内，d>buffer在围栏外，之间的缓冲区保持原状态
+func (e *Engine) transit(events []*model.GeofenceEvent, st *fenceState, f *storage.Fence, d float64, t time.Time) []*model.GeofenceEvent {
+	newEvent := func(typ model.GeofenceEventType, duration time.Duration) *model.GeofenceEvent {
+		return &model.GeofenceEvent{FenceID: f.ID, FenceName: f.Name, Type: typ, Duration: int64(duration.Seconds()), Time: t}
+	}
+	switch {
+	case !st.inside && d <= 0, st.inside && d > e.buffer:
+		st.pending++
+		if st.pending == 1 {
+			st.pendingAt = t
+		}
+		if st.pending < e.confirmCount {
+			return events
+		}
+		st.pending = 0
+		if st.inside {
+			st.inside = false
+			return append(events, newEvent(model.GeofenceExit, st.pendingAt.Sub(st.enteredAt)))
+		}
+		st.inside, st.enteredAt, st.dwelled = true, st.pendingAt, false
+		events = append(events, newEvent(model.GeofenceEnter, 0))
+	default:
+		st.pending = 0
+	}
+	if st.inside && !st.dwelled && f.DwellTime > 0 && t.Sub(st.enteredAt) >= time.Duration(f.DwellTime)*time.Second {
+		st.dwelled = true
+		events = append(events, newEvent(model.GeofenceDwell, t.Sub(st.enteredAt)))
+	}
+	return events
+}
+
+// DeviceStates 终端当前所在的围栏
+func (e *Engine) DeviceStates(phone string) []*FenceState {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	res := make([]*FenceState, 0)
+	for id, st := range e.states[phone] {
+		if !st.inside {
+			continue
+		}
+		fs := &FenceState{FenceID: id, EnteredAt: st.enteredAt, Dwelled: st.dwelled}
+		if e.index != nil {
+			if f, ok := e.index.fences[id]; ok {
+				fs.FenceName = f.Name
+			}
+		}
+		res = append(res, fs)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].FenceID < res[j].FenceID
+	})
+	return res
+}
@@ -0,0 +1,88 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestEngine_Evaluate(t *testing.T) {
+	const phone = "013800000010"
+	store := storage.GetFenceStore()
+	dir := t.TempDir()
+	require.Nil(t, store.SetFenceDir(dir))
+	f, err := store.SaveFence(&storage.Fence{
+		Name:      "仓库",
+		Type:      storage.FenceCircle,
+		Center:    &storage.FencePoint{Latitude: 30, Longitude: 120},
+		Radius:    500,
+		DwellTime: 60,
+	})
+	require.Nil(t, err)
+	require.Nil(t, store.SetAssignments(phone, []string{f.ID}))
+	defer storage.GetGeofenceEventCache().DelEvents(phone)
+
+	engine := GetEngine()
+	engine.SetHysteresis(50, 2)
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		lat     float64 // 与圆心的纬度差，0.001度约111米
+		offset  time.Duration
+		fixed   bool
+		want    []model.GeofenceEventType
+		wantDur int64
+	}{
+		{name: "case1: outside", lat: 0.006, offset: 0, fixed: true},
+		{name: "case2: first inside report is pending", lat: 0.004, offset: 10 * time.Second, fixed: true},
+		{name: "case3: not located is ignored", lat: 0.006, offset: 15 * time.Second},
+		{name: "case4: confirm enter", lat: 0.003, offset: 20 * time.Second, fixed: true, want: []model.GeofenceEventType{model.GeofenceEnter}},
+		{name: "case5: jitter within buffer", lat: 0.0048, offset: 30 * time.Second, fixed: true},
+		{name: "case6: single jump outside", lat: 0.006, offset: 40 * time.Second, fixed: true},
+		{name: "case7: dwell", lat: 0.001, offset: 70 * time.Second, fixed: true, want: []model.GeofenceEventType{model.GeofenceDwell}, wantDur: 60},
+		{name: "case8: first outside report is pending", lat: 0.006, offset: 80 * time.Second, fixed: true},
+		{name: "case9: confirm exit", lat: 0.007, offset: 90 * time.Second, fixed: true, want: []model.GeofenceEventType{model.GeofenceExit}, wantDur: 70},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dg := &model.DeviceGeo{
+				Phone:    phone,
+				Geo:      &model.GeoMeta{},
+				Location: &model.Location{Latitude: 30 + tt.lat, Longitude: 120},
+				Time:     start.Add(tt.offset),
+			}
+			if tt.fixed {
+				dg.Geo.LocationStatus = 1
+			}
+			events := engine.Evaluate(dg)
+			got := make([]model.GeofenceEventType, 0)
+			for _, ev := range events {
+				got = append(got, ev.Type)
+				require.Equal(t, f.ID, ev.FenceID)
+				require.Equal(t, tt.wantDur, ev.Duration)
+			}
+			require.ElementsMatch(t, tt.want, got)
+		})
+	}
+	require.Len(t, storage.GetGeofenceEventCache().ListEvents(phone), 3)
+	require.Empty(t, engine.DeviceStates(phone))
+
+	// 解除绑定后不再产生事件
+	require.Nil(t, store.SetAssignments(phone, nil))
+	for i := 0; i < 2; i++ {
+		dg := &model.DeviceGeo{Phone: phone, Geo: &model.GeoMeta{LocationStatus: 1}, Location: &model.Location{Latitude: 30, Longitude: 120}}
+		require.Empty(t, engine.Evaluate(dg))
+	}
+
+	// 重新加载后围栏和绑定关系不变
+	require.Nil(t, store.SetAssignments(phone, []string{f.ID}))
+	require.Nil(t, store.SetFenceDir(dir))
+	got, err := store.GetFence(f.ID)
+	require.Nil(t, err)
+	require.Equal(t, f.Name, got.Name)
+	require.Equal(t, []string{f.ID}, store.GetAssignments(phone))
+}
@@ -0,0 +1,125 @@
+package geofence
+
+import (
+	"math"
+
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+const earthRadius = 6371008.8 // 地球平均半径，单位米
+
+// 以终端位置为原点的局部平面坐标，单位米，x向东，y向北
+type vec struct {
+	x, y float64
+}
+
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// 经度差，跨180度经线时取较短的一侧
+func lngDelta(lng, lng0 float64) float64 {
+	d := lng - lng0
+	if d > 180 {
+		d -= 360
+	} else if d < -180 {
+		d += 360
+	}
+	return d
+}
+
+// 等距投影，围栏尺度在数百公里以内时误差可以忽略
+func project(lat0, lng0 float64, p *storage.FencePoint) vec {
+	return vec{
+		x: rad(lngDelta(p.Longitude, lng0)) * earthRadius * math.Cos(rad(lat0)),
+		y: rad(p.Latitude-lat0) * earthRadius,
+	}
+}
+
+// 大圆距离，单位米
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := rad(lat2 - lat1)
+	dLng := rad(lngDelta(lng2, lng1))
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// 原点到线段ab的距离
+func segmentDistance(a, b vec) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(a.x*dx+a.y*dy)/l))
+	}
+	return math.Hypot(a.x+t*dx, a.y+t*dy)
+}
+
+// signedDistance 位置到围栏边界的有向距离，单位米，在围栏内为负数
+func signedDistance(f *storage.Fence, lat, lng float64) float64 {
+	switch f.Type {
+	case storage.FenceCircle:
+		return haversine(lat, lng, f.Center.Latitude, f.Center.Longitude) - f.Radius
+	case storage.FencePolygon:
+		pts := make([]vec, 0, len(f.Points))
+		for _, p := range f.Points {
+			pts = append(pts, project(lat, lng, p))
+		}
+		inside := false
+		d := math.Inf(1)
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			d = math.Min(d, segmentDistance(a, b))
+			// 射线法，向x正方向发出射线
+			if (a.y > 0) != (b.y > 0) && a.x+(0-a.y)*(b.x-a.x)/(b.y-a.y) > 0 {
+				inside = !inside
+			}
+		}
+		if inside {
+			return -d
+		}
+		return d
+	case storage.FenceCorridor:
+		d := math.Inf(1)
+		prev := project(lat, lng, f.Points[0])
+		for _, p := range f.Points[1:] {
+			cur := project(lat, lng, p)
+			d = math.Min(d, segmentDistance(prev, cur))
+			prev = cur
+		}
+		return d - f.Width/2
+	}
+	return math.Inf(1)
+}
+
+// 外接矩形，单位度
+type bbox struct {
+	minLat, minLng, maxLat, maxLng float64
+}
+
+func (b *bbox) contains(lat, lng float64) bool {
+	return lat >= b.minLat && lat <= b.maxLat && lng >= b.minLng && lng <= b.maxLng
+}
+
+// 围栏的外接矩形，向外扩展margin米，用于空间索引
+func fenceBBox(f *storage.Fence, margin float64) *bbox {
+	pts := f.Points
+	switch f.Type {
+	case storage.FenceCircle:
+		pts = []*storage.FencePoint{f.Center}
+		margin += f.Radius
+	case storage.FenceCorridor:
+		margin += f.Width / 2
+	}
+	b := &bbox{minLat: 90, minLng: 180, maxLat: -90, maxLng: -180}
+	for _, p := range pts {
+		b.minLat, b.maxLat = math.Min(b.minLat, p.Latitude), math.Max(b.maxLat, p.Latitude)
+		b.minLng, b.maxLng = math.Min(b.minLng, p.Longitude), math.Max(b.maxLng, p.Longitude)
+	}
+	dLat := margin / earthRadius * 180 / math.Pi
+	// 按离赤道较远的一侧计算经度方向的扩展，保证覆盖
+	cos := math.Max(math.Cos(rad(math.Min(90, math.Max(math.Abs(b.minLat), math.Abs(b.maxLat))+dLat))), 0.01)
+	dLng := dLat / cos
+	b.minLat, b.maxLat = math.Max(-90, b.minLat-dLat), math.Min(90, b.maxLat+dLat)
+	b.minLng, b.maxLng = b.minLng-dLng, b.maxLng+dLng
+	return b
+}
@@ -0,0 +1,95 @@
+package geofence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestSignedDistance(t *testing.T) {
+	square := []*storage.FencePoint{
+		{Latitude: 30.00, Longitude: 120.00},
+		{Latitude: 30.00, Longitude: 120.01},
+		{Latitude: 30.01, Longitude: 120.01},
+		{Latitude: 30.01, Longitude: 120.00},
+	}
+	tests := []struct {
+		name     string
+		fence    *storage.Fence
+		lat, lng float64
+		want     float64 // 期望的有向距离，单位米
+		delta    float64
+	}{
+		{
+			name:  "case1: circle inside",
+			fence: &storage.Fence{Type: storage.FenceCircle, Center: &storage.FencePoint{Latitude: 30, Longitude: 120}, Radius: 500},
+			lat:   30.001,
+			lng:   120,
+			want:  111.2 - 500,
+			delta: 1,
+		},
+		{
+			name:  "case2: polygon inside",
+			fence: &storage.Fence{Type: storage.FencePolygon, Points: square},
+			lat:   30.005,
+			lng:   120.009,
+			want:  -96.4,
+			delta: 1,
+		},
+		{
+			name:  "case3: polygon outside",
+			fence: &storage.Fence{Type: storage.FencePolygon, Points: square},
+			lat:   29.999,
+			lng:   120.005,
+			want:  111.2,
+			delta: 1,
+		},
+		{
+			name:  "case4: corridor",
+			fence: &storage.Fence{Type: storage.FenceCorridor, Points: square[:2], Width: 100},
+			lat:   30.0003,
+			lng:   120.005,
+			want:  33.4 - 50,
+			delta: 1,
+		},
+		{
+			name:  "case5: circle across antimeridian",
+			fence: &storage.Fence{Type: storage.FenceCircle, Center: &storage.FencePoint{Latitude: 0, Longitude: 179.9995}, Radius: 200},
+			lat:   0,
+			lng:   -179.9995,
+			want:  111.2 - 200,
+			delta: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.want, signedDistance(tt.fence, tt.lat, tt.lng), tt.delta)
+		})
+	}
+}
+
+func TestGridIndex_query(t *testing.T) {
+	fences := []*storage.Fence{
+		{ID: "small", Type: storage.FenceCircle, Center: &storage.FencePoint{Latitude: 30, Longitude: 120}, Radius: 100},
+		{ID: "large", Type: storage.FencePolygon, Points: []*storage.FencePoint{
+			{Latitude: 20, Longitude: 100}, {Latitude: 20, Longitude: 130}, {Latitude: 40, Longitude: 130},
+		}},
+		{ID: "wrap", Type: storage.FenceCircle, Center: &storage.FencePoint{Latitude: 0, Longitude: 180}, Radius: 1000},
+	}
+	idx := newGridIndex(fences, 20)
+	require.Len(t, idx.large, 2)
+
+	ids := func(fences []*storage.Fence) []string {
+		res := make([]string, 0)
+		for _, f := range fences {
+			res = append(res, f.ID)
+		}
+		return res
+	}
+	// 缓冲区内的位置也能查到
+	require.ElementsMatch(t, []string{"small", "large", "wrap"}, ids(idx.query(30.001, 120)))
+	require.ElementsMatch(t, []string{"large", "wrap"}, ids(idx.query(30.01, 120)))
+	require.ElementsMatch(t, []string{"wrap"}, ids(idx.query(50, 0)))
+}
@@ -0,0 +1,78 @@
+package geofence
+
+import (
+	"math"
+
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+const (
+	cellSize         = 0.05 // 网格边长，单位度，约5公里
+	maxCellsPerFence = 4096 // 超过该网格数的大围栏不放入网格，每次都检查外接矩形
+)
+
+type cellKey struct {
+	row, col int32
+}
+
+func cellOf(lat, lng float64) cellKey {
+	return cellKey{row: int32(math.Floor((lat + 90) / cellSize)), col: int32(math.Floor((lng + 180) / cellSize))}
+}
+
+type indexEntry struct {
+	fence *storage.Fence
+	box   *bbox
+	wrap  bool // 跨180度经线，外接矩形无效，每次都计算距离
+}
+
+// gridIndex 均匀网格空间索引，围栏按外接矩形放入覆盖的网格，查询时只检查位置所在网格内的围栏
+type gridIndex struct {
+	fences map[string]*storage.Fence
+	cells  map[cellKey][]*indexEntry
+	large  []*indexEntry
+}
+
+// 外接矩形按margin扩展，保证在缓冲区内的位置也能查到围栏
+func newGridIndex(fences []*storage.Fence, margin float64) *gridIndex {
+	idx := &gridIndex{
+		fences: make(map[string]*storage.Fence, len(fences)),
+		cells:  make(map[cellKey][]*indexEntry),
+	}
+	for _, f := range fences {
+		idx.fences[f.ID] = f
+		e := &indexEntry{fence: f, box: fenceBBox(f, margin)}
+		if e.box.minLng < -180 || e.box.maxLng > 180 || e.box.maxLng-e.box.minLng > 180 {
+			e.wrap = true
+			idx.large = append(idx.large, e)
+			continue
+		}
+		lo, hi := cellOf(e.box.minLat, e.box.minLng), cellOf(e.box.maxLat, e.box.maxLng)
+		if int64(hi.row-lo.row+1)*int64(hi.col-lo.col+1) > maxCellsPerFence {
+			idx.large = append(idx.large, e)
+			continue
+		}
+		for row := lo.row; row <= hi.row; row++ {
+			for col := lo.col; col <= hi.col; col++ {
+				k := cellKey{row: row, col: col}
+				idx.cells[k] = append(idx.cells[k], e)
+			}
+		}
+	}
+	return idx
+}
+
+// 外接矩形包含该位置的围栏
+func (idx *gridIndex) query(lat, lng float64) []*storage.Fence {
+	res := make([]*storage.Fence, 0)
+	for _, e := range idx.cells[cellOf(lat, lng)] {
+		if e.box.contains(lat, lng) {
+			res = append(res, e.fence)
+		}
+	}
+	for _, e := range idx.large {
+		if e.wrap || e.box.contains(lat, lng) {
+			res = append(res, e.fence)
+		}
+	}
+	return res
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 终端区域和路线
+func registerArea(router gin.IRouter, serv server.Server) {
+	cache := storage.GetDeviceCache()
+
+	// 终端已安装的区域和路线，type可选circle、rectangle、polygon、route
+	router.GET("/device/:phone/areas", func(c *gin.Context) {
+		typ := uint8(0)
+		if name := c.Query("type"); name != "" {
+			t, err := model.ParseAreaType(name)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+				return
+			}
+			typ = t
+		}
+		c.JSON(http.StatusOK, storage.GetAreaCatalog().ListAreas(c.Param("phone"), typ))
+	})
+
+	router.GET("/device/:phone/areas/:type/:id", func(c *gin.Context) {
+		typ, err := model.ParseAreaType(c.Param("type"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		area, err := storage.GetAreaCatalog().GetArea(c.Param("phone"), typ, uint32(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, area)
+	})
+
+	// 追加区域
+	router.POST("/device/:phone/areas/:type", func(c *gin.Context) {
+		setAreas(c, serv, model.AreaActionAppend)
+	})
+
+	// 更新区域，action为update时先删除终端上该类型的全部区域，为modify时按ID修改
+	router.PUT("/device/:phone/areas/:type", func(c *gin.Context) {
+		setAreas(c, serv, model.AreaActionUpdate)
+	})
+
+	// 删除区域，ids为空时删除该类型的全部区域
+	router.DELETE("/device/:phone/areas/:type", func(c *gin.Context) {
+		typ, err := model.ParseAreaType(c.Param("type"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		ids, err := parseAreaIDs(c.Query("ids"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		sendAreas(c, serv, typ, func(genHeader func(uint16) *model.MsgHeader) ([]model.JT808Msg, error) {
+			return model.NewAreaDelMsgs(typ, ids, genHeader)
+		})
+	})
+
+	// 查询终端上的区域数据，仅2019版本终端支持
+	router.GET("/device/:phone/areas/:type/query", func(c *gin.Context) {
+		phone := c.Param("phone")
+		typ, err := model.ParseAreaType(c.Param("type"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		ids, err := parseAreaIDs(c.Query("ids"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		if device.VersionDesc != model.Version2019 {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "Fail to query areas, only supported by 2019 version"})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		msg := &model.Msg8608{
+			Header:    model.GenMsgHeader(device, 0x8608, session.GetNextSerialNum()),
+			QueryType: typ,
+			IDs:       ids,
+		}
+		rsp, err := sendAndWait(serv, device.Phone, msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rsp.(*model.Msg0608))
+	})
+}
+
+// 设置区域，请求体为{"action":"update|modify","areas":[...]}，追加时忽略action
+func setAreas(c *gin.Context, serv server.Server, action uint8) {
+	typ, err := model.ParseAreaType(c.Param("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	req := struct {
+		Action string          `json:"action"`
+		Areas  json.RawMessage `json:"areas" binding:"required"`
+	}{}
+	if err = c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	if action != model.AreaActionAppend {
+		switch req.Action {
+		case "", "update":
+			action = model.AreaActionUpdate
+		case "modify":
+			action = model.AreaActionModify
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"err": fmt.Sprintf("Fail to set areas, invalid action=%s", req.Action)})
+			return
+		}
+	}
+	areas, err := model.UnmarshalAreas(typ, req.Areas)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	sendAreas(c, serv, typ, func(genHeader func(uint16) *model.MsgHeader) ([]model.JT808Msg, error) {
+		return model.NewAreaSetMsgs(typ, action, areas, genHeader)
+	})
+}
+
+// 依次下发区域消息，终端应答成功后更新区域目录，遇到失败时不再下发后续消息
+func sendAreas(c *gin.Context, serv server.Server, typ uint8, build func(func(uint16) *model.MsgHeader) ([]model.JT808Msg, error)) {
+	phone := c.Param("phone")
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	msgs, err := build(func(msgID uint16) *model.MsgHeader {
+		return model.GenMsgHeader(device, msgID, session.GetNextSerialNum())
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+
+	catalog := storage.GetAreaCatalog()
+	results := make([]gin.H, 0, len(msgs))
+	for _, msg := range msgs {
+		rsp, err := sendAndWait(serv, device.Phone, msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error(), "results": results, "areas": catalog.ListAreas(phone, typ)})
+			return
+		}
+		ack := rsp.(*model.Msg0001)
+		results = append(results, gin.H{
+			"msgId":  fmt.Sprintf("0x%04x", msg.GetHeader().MsgID),
+			"result": ack.Result,
+			"desc":   ack.Result2Str(),
+		})
+		if model.ResultCode(ack.Result) != model.ResultSuccess {
+			break
+		}
+		catalog.Apply(device.Phone, msg)
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results, "areas": catalog.ListAreas(phone, typ)})
+}
+
+// 解析逗号分隔的区域ID列表
+func parseAreaIDs(s string) ([]uint32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+	ids := make([]uint32, 0, len(fields))
+	for _, field := range fields {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to parse area id, id=%s", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 终端查询、参数设置和控制
+func registerDevice(router gin.IRouter, serv server.Server) {
+	cache := storage.GetDeviceCache()
+	geoCache := storage.GetGeoCache()
+
+	router.GET("/device", func(c *gin.Context) {
+		c.JSON(http.StatusOK, cache.ListDevice())
+	})
+
+	router.GET("/device/:phone/geo", func(c *gin.Context) {
+		phone := c.Param("phone")
+
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+
+		res := make(map[string]any)
+		err = mapstructure.Decode(device, &res)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		gis, err := geoCache.GetGeoLatestByPhone(phone)
+		if err != nil {
+			return
+		}
+		res["gis"] = gis
+
+		c.JSON(http.StatusOK, res)
+	})
+
+	router.GET("/device/:phone/params", func(c *gin.Context) {
+		phone := c.Param("phone")
+		// ids为空时查询全部参数(0x8104)，否则查询指定参数(0x8106)
+		ids, err := parseParamIDs(c.Query("ids"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		var msg model.JT808Msg
+		if len(ids) == 0 {
+			msg = &model.Msg8104{Header: model.GenMsgHeader(device, 0x8104, session.GetNextSerialNum())}
+		} else {
+			msg = &model.Msg8106{Header: model.GenMsgHeader(device, 0x8106, session.GetNextSerialNum()), ParamIDs: ids}
+		}
+
+		rsp, err := sendAndWait(serv, device.Phone, msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rsp.(*model.Msg0104).Parameters)
+	})
+
+	router.PUT("/device/:phone/params", func(c *gin.Context) {
+		phone := c.Param("phone")
+		params := model.DeviceParams{}
+		if err := c.ShouldBind(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		header := model.GenMsgHeader(device, 0x8103, session.GetNextSerialNum())
+		msg := model.Msg8103{
+			Header:     header,
+			Parameters: &params,
+		}
+		serv.Send(session.ID, &msg)
+	})
+
+	router.POST("/device/:phone/rsa", func(c *gin.Context) {
+		phone := c.Param("phone")
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		header := model.GenMsgHeader(device, 0x8A00, session.GetNextSerialNum())
+		serv.Send(session.ID, protocol.GenMsg8A00(header))
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	router.GET("/device/:phone/location", func(c *gin.Context) {
+		phone := c.Param("phone")
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		msg := model.Msg8201{Header: model.GenMsgHeader(device, 0x8201, session.GetNextSerialNum())}
+		rsp, err := sendAndWait(serv, device.Phone, &msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+			return
+		}
+		dg := &model.DeviceGeo{}
+		if err = dg.Decode(device.Phone, rsp.(*model.Msg0201).Location); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, dg)
+	})
+
+	// 开始临时位置跟踪，interval为0时停止
+	router.PUT("/device/:phone/tracking", func(c *gin.Context) {
+		phone := c.Param("phone")
+		msg := model.Msg8202{}
+		if err := c.ShouldBind(&msg); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		sendTracking(c, serv, phone, &msg)
+	})
+
+	router.DELETE("/device/:phone/tracking", func(c *gin.Context) {
+		sendTracking(c, serv, c.Param("phone"), &model.Msg8202{})
+	})
+
+	// 终端控制，command为2时按server参数连接指定服务器
+	router.POST("/device/:phone/control", func(c *gin.Context) {
+		phone := c.Param("phone")
+		req := struct {
+			Command model.TerminalCommand      `json:"command"`
+			Server  *model.ConnectServerParams `json:"server"`
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		msg, err := model.NewMsg8105(req.Command, req.Server)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		msg.Header = model.GenMsgHeader(device, 0x8105, session.GetNextSerialNum())
+		rsp, err := sendAndWait(serv, device.Phone, msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+			return
+		}
+		ack := rsp.(*model.Msg0001)
+		c.JSON(http.StatusOK, gin.H{
+			"command": model.TerminalCommand2Str(req.Command),
+			"result":  ack.Result,
+			"desc":    ack.Result2Str(),
+		})
+	})
+
+	router.GET("/device/:phone/params/v2", func(c *gin.Context) {
+		phone := c.Param("phone")
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+
+		header := model.GenMsgHeader(device, 0x8104, session.GetNextSerialNum())
+		msg := model.Msg8104{
+			Header: header,
+		}
+
+		serv.SendV2(device.Phone, &msg, func(m any) error {
+			rsp := m.(*model.Msg0104)
+			settings, err := json.Marshal(rsp.Parameters)
+
+			log.Debug().
+				Msgf("执行0x8104消息返回结果：%s => code:%v, res:%s", rsp.GetHeader().PhoneNumber, err, settings)
+
+			return nil
+		})
+	})
+
+	router.PUT("/device/:phone/params/v2", func(c *gin.Context) {
+		phone := c.Param("phone")
+		params := model.DeviceParams{}
+		if err := c.ShouldBind(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		header := model.GenMsgHeader(device, 0x8103, session.GetNextSerialNum())
+		msg := model.Msg8103{
+			Header:     header,
+			Parameters: &params,
+		}
+
+		serv.SendV2(device.Phone, &msg, func(m any) error {
+			rsp := m.(*model.Msg0001)
+			log.Debug().
+				Msgf("执行0x8103消息返回结果：%s", rsp.Result2Str())
+			return nil
+		})
+	})
+}
+
+// 下发临时位置跟踪控制，同步返回终端的通用应答结果
+func sendTracking(c *gin.Context, serv server.Server, phone string, msg *model.Msg8202) {
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	msg.Header = model.GenMsgHeader(device, 0x8202, session.GetNextSerialNum())
+	rsp, err := sendAndWait(serv, device.Phone, msg)
+	if err != nil {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+		return
+	}
+	ack := rsp.(*model.Msg0001)
+	c.JSON(http.StatusOK, gin.H{"result": ack.Result, "desc": ack.Result2Str()})
+}
+
+// 解析逗号分隔的参数ID列表，支持0x前缀的十六进制
+func parseParamIDs(s string) ([]uint32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+	if len(fields) > math.MaxUint8 {
+		return nil, errors.Errorf("Fail to parse param ids, too many ids, count=%d", len(fields))
+	}
+	ids := make([]uint32, 0, len(fields))
+	for _, field := range fields {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 0, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to parse param id, id=%s", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/geofence"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 平台侧电子围栏和终端绑定
+func registerGeofence(router gin.IRouter) {
+	// 平台侧电子围栏，type为circle、polygon或corridor
+	router.POST("/geofence", func(c *gin.Context) {
+		saveFence(c, "")
+	})
+
+	router.PUT("/geofence/:id", func(c *gin.Context) {
+		saveFence(c, c.Param("id"))
+	})
+
+	router.GET("/geofence", func(c *gin.Context) {
+		c.JSON(http.StatusOK, storage.GetFenceStore().ListFences())
+	})
+
+	router.GET("/geofence/:id", func(c *gin.Context) {
+		fence, err := storage.GetFenceStore().GetFence(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, fence)
+	})
+
+	router.DELETE("/geofence/:id", func(c *gin.Context) {
+		err := storage.GetFenceStore().DelFence(c.Param("id"))
+		if errors.Is(err, storage.ErrFenceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	// 终端绑定的围栏和当前所在的围栏
+	router.GET("/device/:phone/geofence", func(c *gin.Context) {
+		phone := c.Param("phone")
+		c.JSON(http.StatusOK, gin.H{
+			"fenceIds": storage.GetFenceStore().GetAssignments(phone),
+			"inside":   geofence.GetEngine().DeviceStates(phone),
+		})
+	})
+
+	// 设置终端绑定的围栏，覆盖原有绑定，fenceIds为空时解除全部绑定
+	router.PUT("/device/:phone/geofence", func(c *gin.Context) {
+		phone := c.Param("phone")
+		req := struct {
+			FenceIDs []string `json:"fenceIds"`
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		if err := storage.GetFenceStore().SetAssignments(phone, req.FenceIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"fenceIds": storage.GetFenceStore().GetAssignments(phone)})
+	})
+
+	router.GET("/device/:phone/geofence/events", func(c *gin.Context) {
+		c.JSON(http.StatusOK, storage.GetGeofenceEventCache().ListEvents(c.Param("phone")))
+	})
+}
+
+// 新增或修改平台侧围栏，修改时id为路径参数
+func saveFence(c *gin.Context, id string) {
+	fence := &storage.Fence{}
+	if err := c.ShouldBind(fence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	store := storage.GetFenceStore()
+	if id != "" {
+		if _, err := store.GetFence(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+	}
+	fence.ID = id
+	fence, err := store.SaveFence(fence)
+	if errors.Is(err, storage.ErrInvalidFence) {
+		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fence)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+)
+
+// 同步等待终端应答的最长时间
+const responseTimeout = 10 * time.Second
+
+// Register 按业务注册http接口
+func Register(router gin.IRouter, serv server.Server) {
+	registerSession(router)
+	registerDevice(router, serv)
+	registerArea(router, serv)
+	registerMedia(router, serv)
+	registerTransparent(router, serv)
+	registerMessage(router, serv)
+	registerUpgrade(router)
+	registerGeofence(router)
+}
+
+// 发送消息并同步等待终端应答，超时后取消等待
+func sendAndWait(serv server.Server, phone string, msg model.JT808Msg) (any, error) {
+	rspCh := make(chan any, 1)
+	err := serv.SendV2(phone, msg, func(m any) error {
+		rspCh <- m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case rsp := <-rspCh:
+		return rsp, nil
+	case <-time.After(responseTimeout):
+		protocol.NewSender().Remove(&protocol.SenderKey{
+			Phone:        phone,
+			MsgId:        msg.GetHeader().MsgID,
+			SerialNumber: msg.GetHeader().SerialNumber,
+		})
+		return nil, errors.Errorf("Fail to wait for device response, msgId=0x%04x, timeout=%v", msg.GetHeader().MsgID, responseTimeout)
+	}
+}
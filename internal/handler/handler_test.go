@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	Register(router, nil)
+
+	routes := make(map[string]bool)
+	for _, r := range router.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	require.Len(t, routes, 40)
+	for _, want := range []string{
+		"GET /session",
+		"GET /device/:phone/params",
+		"PUT /device/:phone/areas/:type",
+		"GET /device/:phone/media/:id",
+		"POST /message/text",
+		"PUT /upgrade/campaign/:id/status",
+		"PUT /device/:phone/geofence",
+	} {
+		require.True(t, routes[want], want)
+	}
+}
+
+func TestParseParamIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []uint32
+		wantErr bool
+	}{
+		{name: "case1: empty", s: "", want: nil},
+		{name: "case2: hex and decimal", s: "0x0001, 19", want: []uint32{0x0001, 19}},
+		{name: "case3: invalid", s: "0x0001,abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseParamIDs(tt.s)
+			if tt.wantErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 拍摄和多媒体文件
+func registerMedia(router gin.IRouter, serv server.Server) {
+	cache := storage.GetDeviceCache()
+
+	router.POST("/device/:phone/camera/shoot", func(c *gin.Context) {
+		phone := c.Param("phone")
+		msg := model.Msg8801{}
+		if err := c.ShouldBind(&msg); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		msg.Header = model.GenMsgHeader(device, 0x8801, session.GetNextSerialNum())
+		rsp, err := sendAndWait(serv, device.Phone, &msg)
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"err": err.Error()})
+			return
+		}
+		out := rsp.(*model.Msg0805)
+		// 多媒体ID与终端随后上传的0x0801数据对应
+		c.JSON(http.StatusOK, gin.H{
+			"result":   out.Result,
+			"desc":     model.Msg0805Result2Str(out.Result),
+			"mediaIds": out.MediaIDs,
+		})
+	})
+
+	router.GET("/device/:phone/media", func(c *gin.Context) {
+		phone := c.Param("phone")
+		res, err := storage.GetMediaStore().ListMedia(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+
+	router.GET("/device/:phone/media/:id", func(c *gin.Context) {
+		phone := c.Param("phone")
+		mediaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		index, path, err := storage.GetMediaStore().GetMediaFile(phone, uint32(mediaID))
+		if errors.Is(err, storage.ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.FileAttachment(path, index.FileName)
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+// 文本信息下发
+func registerMessage(router gin.IRouter, serv server.Server) {
+	// 向一个或多个终端下发文本信息，返回每个终端的通用应答结果
+	router.POST("/message/text", func(c *gin.Context) {
+		req := struct {
+			Phones []string `json:"phones"`
+			Text   string   `json:"text"`
+			model.TextOptions
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		if len(req.Phones) == 0 || req.Text == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"err": "Fail to send text, phones and text are required"})
+			return
+		}
+
+		type textResult struct {
+			Result uint8  `json:"result"`
+			Desc   string `json:"desc"`
+			Err    string `json:"err,omitempty"`
+		}
+		results := make(map[string]*textResult, len(req.Phones))
+		mutex := &sync.Mutex{}
+		wg := &sync.WaitGroup{}
+		for _, phone := range req.Phones {
+			phone := phone
+			wg.Add(1)
+			routines.GoSafe(func() {
+				defer wg.Done()
+				res := &textResult{}
+				rsp, err := sendText(serv, phone, req.Text, &req.TextOptions)
+				if err != nil {
+					res.Err = err.Error()
+				} else {
+					res.Result = rsp.Result
+					res.Desc = rsp.Result2Str()
+				}
+				mutex.Lock()
+				results[phone] = res
+				mutex.Unlock()
+			})
+		}
+		wg.Wait()
+		c.JSON(http.StatusOK, results)
+	})
+}
+
+// 按终端协议版本生成文本信息并等待通用应答
+func sendText(serv server.Server, phone, text string, opts *model.TextOptions) (*model.Msg0001, error) {
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	msg := &model.Msg8300{
+		Header:   model.GenMsgHeader(device, 0x8300, session.GetNextSerialNum()),
+		Flag:     opts.Flag(device.VersionDesc),
+		TextType: opts.TextType,
+		Text:     text,
+	}
+	if msg.TextType == 0 {
+		msg.TextType = model.TextTypeNotice
+	}
+	rsp, err := sendAndWait(serv, device.Phone, msg)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.(*model.Msg0001), nil
+}
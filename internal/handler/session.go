@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 连接、准入和分包统计
+func registerSession(router gin.IRouter) {
+	router.GET("/session", func(c *gin.Context) {
+		res := make([]gin.H, 0)
+		for _, s := range storage.ListSession() {
+			res = append(res, gin.H{
+				"id":         s.ID,
+				"proto":      s.GetTransProto(),
+				"writeQueue": s.WriteQueueStats(),
+			})
+		}
+		c.JSON(http.StatusOK, res)
+	})
+
+	router.GET("/session/admission", func(c *gin.Context) {
+		total, rejected := server.AdmissionStats()
+		c.JSON(http.StatusOK, gin.H{"sessions": total, "rejected": rejected})
+	})
+
+	router.GET("/segment/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, storage.GetSegmentStats())
+	})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 数据透传
+func registerTransparent(router gin.IRouter, serv server.Server) {
+	cache := storage.GetDeviceCache()
+
+	router.POST("/device/:phone/transparent", func(c *gin.Context) {
+		phone := c.Param("phone")
+		req := struct {
+			MsgType uint8  `json:"msgType"`
+			Data    string `json:"data"` // 十六进制字符串
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		data, err := hex.DecodeString(req.Data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		session, err := storage.GetSession(device.SessionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		header := model.GenMsgHeader(device, 0x8900, session.GetNextSerialNum())
+		msg := model.Msg8900{
+			Header:  header,
+			MsgType: req.MsgType,
+			Data:    data,
+		}
+		serv.Send(session.ID, &msg)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	router.GET("/device/:phone/transparent", func(c *gin.Context) {
+		phone := c.Param("phone")
+		c.JSON(http.StatusOK, storage.GetTransparentCache().ListEvents(phone))
+	})
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 升级包和升级任务
+func registerUpgrade(router gin.IRouter) {
+	// 上传升级包，multipart表单：file、upgradeType、manufacturerId、version
+	router.POST("/upgrade/firmware", func(c *gin.Context) {
+		req := struct {
+			UpgradeType    uint8  `form:"upgradeType"`
+			ManufacturerID string `form:"manufacturerId" binding:"max=11"`
+			Version        string `form:"version" binding:"required,max=255"`
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		fw, err := storage.GetFirmwareStore().SaveFirmware(&storage.Firmware{
+			Name:           fh.Filename,
+			UpgradeType:    req.UpgradeType,
+			ManufacturerID: req.ManufacturerID,
+			Version:        req.Version,
+		}, data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, fw)
+	})
+
+	router.GET("/upgrade/firmware", func(c *gin.Context) {
+		fws, err := storage.GetFirmwareStore().ListFirmware()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, fws)
+	})
+
+	// 创建升级任务，phones为空时按softwareVersion选择当前缓存的终端
+	router.POST("/upgrade/campaign", func(c *gin.Context) {
+		req := struct {
+			FirmwareID      string   `json:"firmwareId" binding:"required"`
+			Phones          []string `json:"phones"`
+			SoftwareVersion string   `json:"softwareVersion"`
+			StageSize       int      `json:"stageSize"`
+			Concurrency     int      `json:"concurrency"`
+			MaxFailures     int      `json:"maxFailures"`
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		fw, err := storage.GetFirmwareStore().GetFirmware(req.FirmwareID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		phones := req.Phones
+		if len(phones) == 0 && req.SoftwareVersion != "" {
+			phones = storage.SelectDevicesByVersion(req.SoftwareVersion)
+		}
+		campaign, err := storage.GetCampaignCache().CreateCampaign(&storage.Campaign{
+			FirmwareID:  fw.ID,
+			UpgradeType: fw.UpgradeType,
+			Version:     fw.Version,
+			StageSize:   req.StageSize,
+			Concurrency: req.Concurrency,
+			MaxFailures: req.MaxFailures,
+		}, phones)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, campaign)
+	})
+
+	router.GET("/upgrade/campaign", func(c *gin.Context) {
+		c.JSON(http.StatusOK, storage.GetCampaignCache().ListCampaigns())
+	})
+
+	router.GET("/upgrade/campaign/:id", func(c *gin.Context) {
+		campaign, err := storage.GetCampaignCache().GetCampaign(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"campaign": campaign, "summary": campaign.Summary()})
+	})
+
+	// 暂停(paused)、恢复(running)或取消(canceled)升级任务
+	router.PUT("/upgrade/campaign/:id/status", func(c *gin.Context) {
+		req := struct {
+			Status storage.CampaignStatus `json:"status" binding:"required"`
+		}{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		campaign, err := storage.GetCampaignCache().SetCampaignStatus(c.Param("id"), req.Status)
+		if errors.Is(err, storage.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"err": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, campaign)
+	})
+}
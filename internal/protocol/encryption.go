@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	jtrsa "github.com/fakeyanss/jt808-server-go/internal/codec/rsa"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+const platformKeyBits = jtrsa.ModulusLen * 8
+
+var (
+	ErrEncryptionRequired = errors.New("Encryption is required for this device")
+	ErrInvalidPrivateKey  = errors.New("Fail to parse rsa private key")
+)
+
+// 不加密的消息，密钥交换完成前需要明文收发
+var plainMsgIDs = map[uint16]bool{
+	0x0001: true, // 终端通用应答
+	0x0002: true, // 心跳
+	0x0003: true, // 注销
+	0x0100: true, // 注册
+	0x0102: true, // 鉴权
+	0x0A00: true, // 终端RSA公钥
+	0x8100: true, // 注册应答
+	0x8A00: true, // 平台RSA公钥
+}
+
+// 消息体RSA加解密
+type encryption struct {
+	key       *rsa.PrivateKey     // 平台RSA私钥
+	mandatory map[string]struct{} // 必须加密的终端手机号
+	mutex     *sync.RWMutex
+}
+
+var encryptionSingleton *encryption
+var encryptionInitOnce sync.Once
+
+// 未配置平台私钥时，启动时生成一个
+func getEncryption() *encryption {
+	encryptionInitOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, platformKeyBits)
+		if err != nil {
+			log.Error().Err(err).Msg("Fail to generate platform rsa key")
+		}
+		encryptionSingleton = &encryption{
+			key:       key,
+			mandatory: make(map[string]struct{}),
+			mutex:     &sync.RWMutex{},
+		}
+	})
+	return encryptionSingleton
+}
+
+// SetEncryptionOptions 设置平台私钥和必须加密的终端。privateKeyFile为空时使用启动时生成的密钥
+func SetEncryptionOptions(privateKeyFile string, mandatoryPhones []string) error {
+	e := getEncryption()
+	var key *rsa.PrivateKey
+	if privateKeyFile != "" {
+		var err error
+		key, err = loadPrivateKey(privateKeyFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if key != nil {
+		e.key = key
+	}
+	e.mandatory = make(map[string]struct{}, len(mandatoryPhones))
+	for _, phone := range mandatoryPhones {
+		e.mandatory[phone] = struct{}{}
+	}
+	return nil
+}
+
+// 加载PEM格式的PKCS#1或PKCS#8私钥，模数必须为1024位
+func loadPrivateKey(file string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to read rsa private key file")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, _ = k.(*rsa.PrivateKey)
+	}
+	if key == nil || key.Size() != jtrsa.ModulusLen {
+		return nil, ErrInvalidPrivateKey
+	}
+	return key, nil
+}
+
+func (e *encryption) publicKey() *rsa.PublicKey {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return &e.key.PublicKey
+}
+
+func (e *encryption) isMandatory(phone string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	_, ok := e.mandatory[phone]
+	return ok
+}
+
+// 解密上行消息体。必须加密的终端发送明文消息时返回ErrEncryptionRequired
+func (e *encryption) decryptBody(header *model.MsgHeader, body []byte) ([]byte, error) {
+	if header.Attr.EncryptionDesc != model.EncryptionRSA {
+		if e.isMandatory(header.PhoneNumber) && !plainMsgIDs[header.MsgID] {
+			return nil, errors.Wrapf(ErrEncryptionRequired, "phone=%s, msgId=0x%04x", header.PhoneNumber, header.MsgID)
+		}
+		return body, nil
+	}
+
+	e.mutex.RLock()
+	key := e.key
+	e.mutex.RUnlock()
+	return jtrsa.Decrypt(key, body)
+}
+
+// 终端上报过公钥时加密下行消息体，并设置消息体属性中的加密标识
+func (e *encryption) encryptBody(header *model.MsgHeader, body []byte) ([]byte, error) {
+	// 回复消息可能复用了上行消息的消息头，先清除加密标识
+	header.Attr.Encryption = uint8(model.EncryptionNone)
+	header.Attr.EncryptionDesc = model.EncryptionNone
+	if plainMsgIDs[header.MsgID] || len(body) == 0 {
+		return body, nil
+	}
+	pub, err := storage.GetKeyCache().GetDeviceKey(header.PhoneNumber)
+	if err != nil {
+		// 密钥交换前的通用应答只能明文发送，其他下发指令必须加密
+		if e.isMandatory(header.PhoneNumber) && header.MsgID != 0x8001 {
+			return nil, errors.Wrapf(ErrEncryptionRequired, "Fail to find device rsa key, phone=%s", header.PhoneNumber)
+		}
+		return body, nil
+	}
+
+	cipher, err := jtrsa.Encrypt(pub, body)
+	if err != nil {
+		return nil, err
+	}
+	header.Attr.Encryption = uint8(model.EncryptionRSA)
+	header.Attr.EncryptionDesc = model.EncryptionRSA
+	return cipher, nil
+}
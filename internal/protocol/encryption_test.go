@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	jtrsa "github.com/fakeyanss/jt808-server-go/internal/codec/rsa"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestJT808PacketCodec_encryption(t *testing.T) {
+	const phone = "013800000003"
+	devKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+	storage.GetKeyCache().StoreDeviceKey(phone, &devKey.PublicKey)
+	defer storage.GetKeyCache().DelDeviceKey(phone)
+
+	pc := NewJT808PacketCodec()
+	genMsg := func(msgID uint16) *model.Msg8003 {
+		return &model.Msg8003{
+			Header: &model.MsgHeader{
+				MsgID:        msgID,
+				Attr:         &model.MsgBodyAttr{VersionDesc: model.Version2013},
+				PhoneNumber:  phone,
+				SerialNumber: 1,
+			},
+			OriginalSerialNumber: 9,
+			RetransmitIDs:        []uint16{1, 2},
+		}
+	}
+
+	// 下行消息使用终端公钥加密
+	frames, err := pc.Encode(genMsg(0x8003), nil)
+	require.Nil(t, err)
+	pkt, err := pc.verify(pc.unescape(frames[0]))
+	require.Nil(t, err)
+	header := &model.MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	require.Equal(t, model.EncryptionRSA, header.Attr.EncryptionDesc)
+	require.Equal(t, jtrsa.ModulusLen, int(header.Attr.BodyLength))
+	body, err := jtrsa.Decrypt(devKey, pkt[header.Idx:])
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x00, 0x09, 0x02, 0x00, 0x01, 0x00, 0x02}, body)
+
+	// 上行加密消息使用平台私钥解密
+	plain := genMsg(0x0005)
+	plainPkt, err := plain.Encode()
+	require.Nil(t, err)
+	cipher, err := jtrsa.Encrypt(getEncryption().publicKey(), plainPkt[len(plainPkt)-7:])
+	require.Nil(t, err)
+	plain.Header.Attr.Encryption = uint8(model.EncryptionRSA)
+	plain.Header.Attr.BodyLength = uint16(len(cipher))
+	headerPkt, err := plain.Header.Encode()
+	require.Nil(t, err)
+	pd, err := pc.Decode(pc.escape(pc.genVerifier(append(headerPkt, cipher...))))
+	require.Nil(t, err)
+	require.Equal(t, body, pd.Body)
+
+	// 必须加密的终端不能发送明文消息
+	require.Nil(t, SetEncryptionOptions("", []string{phone}))
+	defer func() { _ = SetEncryptionOptions("", nil) }()
+	storage.GetKeyCache().DelDeviceKey(phone)
+	_, err = pc.Encode(genMsg(0x8003), nil)
+	require.ErrorIs(t, err, ErrEncryptionRequired)
+	_, err = pc.Decode(pc.escape(pc.genVerifier(plainPkt)))
+	require.ErrorIs(t, err, ErrEncryptionRequired)
+}
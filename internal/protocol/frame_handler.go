@@ -17,6 +17,8 @@ import (
 const (
 	// 标识位[2] + 消息头[21] + 消息体[1023 * 2(转义预留)]  + 校验码[1] + 标识位[2]
 	MaxFrameLen = 2 + 21 + 1023*2 + 1 + 2
+
+	initScanBufLen = 4096 // 扫描缓冲区初始长度
 )
 
 var (
@@ -31,7 +33,7 @@ type FrameHandler interface {
 }
 
 type JT808FrameHandler struct {
-	rbuf *bufio.Reader
+	scanner *bufio.Scanner // 按0x7e标识位切分数据流，处理粘包和半包
 
 	// wbuf *bufio.Writer // 发送消息应该立即发出，不能使用缓存writer
 	writer io.Writer
@@ -39,28 +41,81 @@ type JT808FrameHandler struct {
 
 func NewJT808FrameHandler(conn net.Conn) *JT808FrameHandler {
 	return &JT808FrameHandler{
-		rbuf:   bufio.NewReader(conn),
-		writer: conn,
+		scanner: newFrameScanner(conn, MaxFrameLen),
+		writer:  conn,
+	}
+}
+
+// 创建frame扫描器。缓冲区需要大于最大帧长，才能判断出超长帧并丢弃
+func newFrameScanner(r io.Reader, maxFrameLen int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, initScanBufLen), 2*maxFrameLen)
+	scanner.Split(splitFrameFunc(maxFrameLen))
+	return scanner
+}
+
+// 按0x7e...0x7e切分完整帧
+//
+//   - 起始标识位之前的数据视为垃圾数据，直接丢弃以重新同步
+//   - 连续两个0x7e，前一个视为上一帧的结束标识位残留，丢弃
+//   - 帧长度超过maxFrameLen，丢弃整帧
+//   - 没有找到结束标识位，缓存已读数据，等待下一次读取
+func splitFrameFunc(maxFrameLen int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		start := bytes.IndexByte(data, boundaryMark)
+		if start < 0 {
+			log.Debug().Int("discard_len", len(data)).Msg("Discard bytes without frame boundary.")
+			return len(data), nil, nil
+		}
+
+		end := bytes.IndexByte(data[start+1:], boundaryMark)
+		if end < 0 {
+			if len(data)-start > maxFrameLen || atEOF {
+				log.Debug().Int("discard_len", len(data)-start).Msg("Discard oversize or incomplete frame.")
+				return len(data), nil, nil
+			}
+			return start, nil, nil // 半包，等待读取更多数据
+		}
+		end += start + 1
+
+		if end == start+1 {
+			return end, nil, nil
+		}
+
+		if end+1-start > maxFrameLen {
+			log.Debug().Int("discard_len", end+1-start).Msg("Discard oversize frame.")
+			return end + 1, nil, nil
+		}
+
+		return end + 1, data[start : end+1], nil
 	}
 }
 
 func (fh *JT808FrameHandler) Recv(ctx context.Context) (FramePayload, error) {
-	buf := make([]byte, MaxFrameLen)
-	_, err := fh.rbuf.Read(buf)
-	if err != nil {
+	if !fh.scanner.Scan() {
+		err := fh.scanner.Err()
+		if err == nil {
+			err = io.EOF
+		}
 		return nil, errors.Wrap(err, "Fail to read stream to framePayload")
 	}
-	// 移除末尾多余的0
-	buf = bytes.TrimRight(buf, "\x00")
 
-	if len(buf) == 0 {
+	// scanner会复用底层缓冲区，需要拷贝出来
+	token := fh.scanner.Bytes()
+	if len(token) == 0 {
 		return nil, ErrFrameReadEmpty
 	}
+	buf := make([]byte, len(token))
+	copy(buf, token)
 
 	if log.Logger.GetLevel() == zerolog.DebugLevel {
 		var sessionID string
-		session := ctx.Value(model.SessionCtxKey{}).(*model.Session)
-		if session != nil {
+		session, ok := ctx.Value(model.SessionCtxKey{}).(*model.Session)
+		if ok && session != nil {
 			sessionID = session.ID // client端不设置session
 		}
 		// for debug
@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+func TestJT808FrameHandler_Recv(t *testing.T) {
+	frame1 := hex.Str2Byte("7E0200001C2234567890150000000000000002080301CD779E0728C032003C0000008F230125145158FB7E")
+	frame2 := hex.Str2Byte("7e000140050100000000017299841738ffff007b01c803b57e")
+	frame3 := hex.Str2Byte("7e0002000001234567890100000000017e") // 消息体末尾的0x00需要保留
+
+	oversize := append([]byte{0x7e}, bytes.Repeat([]byte{0x01}, 64)...)
+	oversize = append(oversize, 0x7e)
+
+	type args struct {
+		reader      io.Reader
+		maxFrameLen int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []FramePayload
+	}{
+		{
+			name: "case1: sticky packets in one read",
+			args: args{
+				reader:      bytes.NewReader(bytes.Join([][]byte{frame1, frame2, frame3}, nil)),
+				maxFrameLen: MaxFrameLen,
+			},
+			want: []FramePayload{frame1, frame2, frame3},
+		},
+		{
+			name: "case2: half packets across reads",
+			args: args{
+				reader:      iotest.OneByteReader(bytes.NewReader(bytes.Join([][]byte{frame1, frame2}, nil))),
+				maxFrameLen: MaxFrameLen,
+			},
+			want: []FramePayload{frame1, frame2},
+		},
+		{
+			name: "case3: resync after garbage bytes",
+			args: args{
+				reader:      bytes.NewReader(bytes.Join([][]byte{{0x01, 0x02}, frame1, {0x00, 0x00, 0x7e}, frame2, {0x03}}, nil)),
+				maxFrameLen: MaxFrameLen,
+			},
+			want: []FramePayload{frame1, frame2},
+		},
+		{
+			name: "case4: drop oversize frame",
+			args: args{
+				reader:      bytes.NewReader(bytes.Join([][]byte{oversize, frame2, frame3}, nil)),
+				maxFrameLen: 32,
+			},
+			want: []FramePayload{frame2, frame3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := &JT808FrameHandler{scanner: newFrameScanner(tt.args.reader, tt.args.maxFrameLen)}
+			got := []FramePayload{}
+			for {
+				frame, err := fh.Recv(context.Background())
+				if err != nil {
+					require.ErrorIs(t, err, io.EOF)
+					break
+				}
+				got = append(got, frame)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
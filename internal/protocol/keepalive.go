@@ -43,6 +43,11 @@ func (t *KeepaliveTimer) Cancel(devicePhone string) {
 	t.cron.Cancel(devicePhone)
 }
 
+// Stop 停止保活检查
+func (t *KeepaliveTimer) Stop() {
+	t.cron.Stop()
+}
+
 func (t *KeepaliveTimer) Jobs() []*gron.Entry {
 	return t.cron.Entries()
 }
@@ -0,0 +1,544 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/gbk"
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+var ErrInvalidArea = errors.New("Invalid area params")
+
+// 区域类型，与0x8608查询类型取值一致
+const (
+	AreaTypeCircle    uint8 = 1 // 圆形
+	AreaTypeRectangle uint8 = 2 // 矩形
+	AreaTypePolygon   uint8 = 3 // 多边形
+	AreaTypeRoute     uint8 = 4 // 路线
+)
+
+var areaTypeNames = map[uint8]string{
+	AreaTypeCircle:    "circle",
+	AreaTypeRectangle: "rectangle",
+	AreaTypePolygon:   "polygon",
+	AreaTypeRoute:     "route",
+}
+
+func AreaTypeName(t uint8) string {
+	if name, ok := areaTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseAreaType 按名称解析区域类型，如circle
+func ParseAreaType(name string) (uint8, error) {
+	for t, n := range areaTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, errors.Wrapf(ErrInvalidArea, "type=%s", name)
+}
+
+// 设置区域的设置属性，仅圆形和矩形区域使用
+const (
+	AreaActionUpdate uint8 = 0 // 更新区域，终端删除该类型的全部区域后保存本次下发的区域
+	AreaActionAppend uint8 = 1 // 追加区域
+	AreaActionModify uint8 = 2 // 修改区域
+)
+
+// 区域和路线属性
+const (
+	AreaAttrByTime             uint16 = 1 << 0  // bit0, 根据时间
+	AreaAttrSpeedLimit         uint16 = 1 << 1  // bit1, 限速，路线为保留位
+	AreaAttrEnterAlarmDriver   uint16 = 1 << 2  // bit2, 进区域报警给驾驶员
+	AreaAttrEnterAlarmPlatform uint16 = 1 << 3  // bit3, 进区域报警给平台
+	AreaAttrExitAlarmDriver    uint16 = 1 << 4  // bit4, 出区域报警给驾驶员
+	AreaAttrExitAlarmPlatform  uint16 = 1 << 5  // bit5, 出区域报警给平台
+	AreaAttrSouthLatitude      uint16 = 1 << 6  // bit6, 0:北纬;1:南纬
+	AreaAttrWestLongitude      uint16 = 1 << 7  // bit7, 0:东经;1:西经
+	AreaAttrDoorForbidden      uint16 = 1 << 8  // bit8, 0:允许开门;1:禁止开门
+	AreaAttrCommClosed         uint16 = 1 << 14 // bit14, 0:进区域开启通信模块;1:进区域关闭通信模块
+	AreaAttrCollectGNSS        uint16 = 1 << 15 // bit15, 0:进区域不采集GNSS详细定位数据;1:进区域采集
+)
+
+// 路段属性
+const (
+	RouteSegmentAttrDriveTime     uint8 = 1 << 0 // bit0, 行驶时间
+	RouteSegmentAttrSpeedLimit    uint8 = 1 << 1 // bit1, 限速
+	RouteSegmentAttrSouthLatitude uint8 = 1 << 2 // bit2, 0:北纬;1:南纬
+	RouteSegmentAttrWestLongitude uint8 = 1 << 3 // bit3, 0:东经;1:西经
+)
+
+const maxAreaDeleteCount = 125 // 删除区域时单条消息最多的区域数
+
+// AreaLimit 区域的时间和限速设置，区域属性对应位为0时没有该字段
+type AreaLimit struct {
+	StartTime         string `json:"startTime,omitempty"`         // 起始时间，YYMMDDhhmmss，区域属性bit0为1时有
+	EndTime           string `json:"endTime,omitempty"`           // 结束时间，YYMMDDhhmmss，区域属性bit0为1时有
+	MaxSpeed          uint16 `json:"maxSpeed,omitempty"`          // 最高速度，km/h，区域属性bit1为1时有
+	OverspeedDuration uint8  `json:"overspeedDuration,omitempty"` // 超速持续时间，单位秒，区域属性bit1为1时有
+}
+
+func (l *AreaLimit) decodeTime(r *hex.Reader, attr uint16) {
+	if attr&AreaAttrByTime == 0 {
+		return
+	}
+	l.StartTime = r.ReadBCD("startTime", 6)
+	l.EndTime = r.ReadBCD("endTime", 6)
+}
+
+func (l *AreaLimit) encodeTime(pkt []byte, attr uint16) []byte {
+	if attr&AreaAttrByTime == 0 {
+		return pkt
+	}
+	pkt = hex.WriteFixedBCD(pkt, l.StartTime, 6)
+	return hex.WriteFixedBCD(pkt, l.EndTime, 6)
+}
+
+func (l *AreaLimit) decodeSpeed(r *hex.Reader, attr uint16) {
+	if attr&AreaAttrSpeedLimit == 0 {
+		return
+	}
+	l.MaxSpeed = r.ReadWord("maxSpeed")
+	l.OverspeedDuration = r.ReadUint8("overspeedDuration")
+}
+
+func (l *AreaLimit) encodeSpeed(pkt []byte, attr uint16) []byte {
+	if attr&AreaAttrSpeedLimit == 0 {
+		return pkt
+	}
+	pkt = hex.WriteWord(pkt, l.MaxSpeed)
+	return hex.WriteByte(pkt, l.OverspeedDuration)
+}
+
+// 夜间最高速度，2019版本区域属性bit1为1时有
+func decodeNightSpeed(r *hex.Reader, attr uint16, ver VersionType) uint16 {
+	if ver != Version2019 || attr&AreaAttrSpeedLimit == 0 {
+		return 0
+	}
+	return r.ReadWord("nightMaxSpeed")
+}
+
+func encodeNightSpeed(pkt []byte, nightSpeed uint16, attr uint16, ver VersionType) []byte {
+	if ver != Version2019 || attr&AreaAttrSpeedLimit == 0 {
+		return pkt
+	}
+	return hex.WriteWord(pkt, nightSpeed)
+}
+
+// 区域名称，2019版本有，WORD长度+GBK编码
+func decodeAreaName(r *hex.Reader, ver VersionType) string {
+	if ver != Version2019 {
+		return ""
+	}
+	n := r.ReadWord("nameLen")
+	return r.ReadGBK("name", int(n))
+}
+
+func encodeAreaName(pkt []byte, name string, ver VersionType) ([]byte, error) {
+	if ver != Version2019 {
+		return pkt, nil
+	}
+	b, err := gbk.UTF82GBK([]byte(name))
+	if err != nil {
+		return nil, errors.Wrapf(ErrEncodeMsg, "Fail to encode area name to gbk, name=%s", name)
+	}
+	pkt = hex.WriteWord(pkt, uint16(len(b)))
+	return hex.WriteBytes(pkt, b), nil
+}
+
+// AreaPoint 多边形顶点，经纬度单位为百万分之一度
+type AreaPoint struct {
+	Latitude  uint32 `json:"latitude"`
+	Longitude uint32 `json:"longitude"`
+}
+
+// CircleArea 圆形区域项
+type CircleArea struct {
+	ID        uint32 `json:"id"`
+	Attr      uint16 `json:"attr"`      // 区域属性
+	Latitude  uint32 `json:"latitude"`  // 中心点纬度
+	Longitude uint32 `json:"longitude"` // 中心点经度
+	Radius    uint32 `json:"radius"`    // 半径，单位米
+	AreaLimit
+	NightMaxSpeed uint16 `json:"nightMaxSpeed,omitempty"` // 夜间最高速度，2019版本
+	Name          string `json:"name,omitempty"`          // 区域名称，2019版本
+}
+
+func (a *CircleArea) decode(r *hex.Reader, ver VersionType) {
+	a.ID = r.ReadDoubleWord("areaId")
+	a.Attr = r.ReadWord("areaAttr")
+	a.Latitude = r.ReadDoubleWord("latitude")
+	a.Longitude = r.ReadDoubleWord("longitude")
+	a.Radius = r.ReadDoubleWord("radius")
+	a.decodeTime(r, a.Attr)
+	a.decodeSpeed(r, a.Attr)
+	a.NightMaxSpeed = decodeNightSpeed(r, a.Attr, ver)
+	a.Name = decodeAreaName(r, ver)
+}
+
+func (a *CircleArea) encode(pkt []byte, ver VersionType) ([]byte, error) {
+	pkt = hex.WriteDoubleWord(pkt, a.ID)
+	pkt = hex.WriteWord(pkt, a.Attr)
+	pkt = hex.WriteDoubleWord(pkt, a.Latitude)
+	pkt = hex.WriteDoubleWord(pkt, a.Longitude)
+	pkt = hex.WriteDoubleWord(pkt, a.Radius)
+	pkt = a.encodeTime(pkt, a.Attr)
+	pkt = a.encodeSpeed(pkt, a.Attr)
+	pkt = encodeNightSpeed(pkt, a.NightMaxSpeed, a.Attr, ver)
+	return encodeAreaName(pkt, a.Name, ver)
+}
+
+// RectangleArea 矩形区域项
+type RectangleArea struct {
+	ID                   uint32 `json:"id"`
+	Attr                 uint16 `json:"attr"`                 // 区域属性
+	TopLeftLatitude      uint32 `json:"topLeftLatitude"`      // 左上点纬度
+	TopLeftLongitude     uint32 `json:"topLeftLongitude"`     // 左上点经度
+	BottomRightLatitude  uint32 `json:"bottomRightLatitude"`  // 右下点纬度
+	BottomRightLongitude uint32 `json:"bottomRightLongitude"` // 右下点经度
+	AreaLimit
+	NightMaxSpeed uint16 `json:"nightMaxSpeed,omitempty"` // 夜间最高速度，2019版本
+	Name          string `json:"name,omitempty"`          // 区域名称，2019版本
+}
+
+func (a *RectangleArea) decode(r *hex.Reader, ver VersionType) {
+	a.ID = r.ReadDoubleWord("areaId")
+	a.Attr = r.ReadWord("areaAttr")
+	a.TopLeftLatitude = r.ReadDoubleWord("topLeftLatitude")
+	a.TopLeftLongitude = r.ReadDoubleWord("topLeftLongitude")
+	a.BottomRightLatitude = r.ReadDoubleWord("bottomRightLatitude")
+	a.BottomRightLongitude = r.ReadDoubleWord("bottomRightLongitude")
+	a.decodeTime(r, a.Attr)
+	a.decodeSpeed(r, a.Attr)
+	a.NightMaxSpeed = decodeNightSpeed(r, a.Attr, ver)
+	a.Name = decodeAreaName(r, ver)
+}
+
+func (a *RectangleArea) encode(pkt []byte, ver VersionType) ([]byte, error) {
+	pkt = hex.WriteDoubleWord(pkt, a.ID)
+	pkt = hex.WriteWord(pkt, a.Attr)
+	pkt = hex.WriteDoubleWord(pkt, a.TopLeftLatitude)
+	pkt = hex.WriteDoubleWord(pkt, a.TopLeftLongitude)
+	pkt = hex.WriteDoubleWord(pkt, a.BottomRightLatitude)
+	pkt = hex.WriteDoubleWord(pkt, a.BottomRightLongitude)
+	pkt = a.encodeTime(pkt, a.Attr)
+	pkt = a.encodeSpeed(pkt, a.Attr)
+	pkt = encodeNightSpeed(pkt, a.NightMaxSpeed, a.Attr, ver)
+	return encodeAreaName(pkt, a.Name, ver)
+}
+
+// PolygonArea 多边形区域，夜间最高速度和名称位于顶点之后
+type PolygonArea struct {
+	ID   uint32 `json:"id"`
+	Attr uint16 `json:"attr"` // 区域属性
+	AreaLimit
+	Vertices      []*AreaPoint `json:"vertices"`                // 顶点
+	NightMaxSpeed uint16       `json:"nightMaxSpeed,omitempty"` // 夜间最高速度，2019版本
+	Name          string       `json:"name,omitempty"`          // 区域名称，2019版本
+}
+
+func (a *PolygonArea) decode(r *hex.Reader, ver VersionType) {
+	a.ID = r.ReadDoubleWord("areaId")
+	a.Attr = r.ReadWord("areaAttr")
+	a.decodeTime(r, a.Attr)
+	a.decodeSpeed(r, a.Attr)
+	cnt := int(r.ReadWord("vertexCnt"))
+	if r.Require("vertices", 8*cnt) {
+		a.Vertices = make([]*AreaPoint, 0, cnt)
+		for i := 0; i < cnt; i++ {
+			a.Vertices = append(a.Vertices, &AreaPoint{
+				Latitude:  r.ReadDoubleWord("latitude"),
+				Longitude: r.ReadDoubleWord("longitude"),
+			})
+		}
+	}
+	a.NightMaxSpeed = decodeNightSpeed(r, a.Attr, ver)
+	a.Name = decodeAreaName(r, ver)
+}
+
+func (a *PolygonArea) encode(pkt []byte, ver VersionType) ([]byte, error) {
+	pkt = hex.WriteDoubleWord(pkt, a.ID)
+	pkt = hex.WriteWord(pkt, a.Attr)
+	pkt = a.encodeTime(pkt, a.Attr)
+	pkt = a.encodeSpeed(pkt, a.Attr)
+	pkt = hex.WriteWord(pkt, uint16(len(a.Vertices)))
+	for _, v := range a.Vertices {
+		pkt = hex.WriteDoubleWord(pkt, v.Latitude)
+		pkt = hex.WriteDoubleWord(pkt, v.Longitude)
+	}
+	pkt = encodeNightSpeed(pkt, a.NightMaxSpeed, a.Attr, ver)
+	return encodeAreaName(pkt, a.Name, ver)
+}
+
+// RoutePoint 路线拐点，路段属性对应位为0时没有行驶时间和限速字段
+type RoutePoint struct {
+	PointID           uint32 `json:"pointId"`                     // 拐点ID
+	SegmentID         uint32 `json:"segmentId"`                   // 路段ID
+	Latitude          uint32 `json:"latitude"`                    // 拐点纬度
+	Longitude         uint32 `json:"longitude"`                   // 拐点经度
+	Width             uint8  `json:"width"`                       // 路段宽度，单位米
+	Attr              uint8  `json:"attr"`                        // 路段属性
+	MaxDriveTime      uint16 `json:"maxDriveTime,omitempty"`      // 路段行驶过长阈值，单位秒，bit0为1时有
+	MinDriveTime      uint16 `json:"minDriveTime,omitempty"`      // 路段行驶不足阈值，单位秒，bit0为1时有
+	MaxSpeed          uint16 `json:"maxSpeed,omitempty"`          // 路段最高速度，km/h，bit1为1时有
+	OverspeedDuration uint8  `json:"overspeedDuration,omitempty"` // 路段超速持续时间，单位秒，bit1为1时有
+	NightMaxSpeed     uint16 `json:"nightMaxSpeed,omitempty"`     // 路段夜间最高速度，2019版本bit1为1时有
+}
+
+func (p *RoutePoint) decode(r *hex.Reader, ver VersionType) {
+	p.PointID = r.ReadDoubleWord("pointId")
+	p.SegmentID = r.ReadDoubleWord("segmentId")
+	p.Latitude = r.ReadDoubleWord("latitude")
+	p.Longitude = r.ReadDoubleWord("longitude")
+	p.Width = r.ReadUint8("width")
+	p.Attr = r.ReadUint8("segmentAttr")
+	if p.Attr&RouteSegmentAttrDriveTime != 0 {
+		p.MaxDriveTime = r.ReadWord("maxDriveTime")
+		p.MinDriveTime = r.ReadWord("minDriveTime")
+	}
+	if p.Attr&RouteSegmentAttrSpeedLimit != 0 {
+		p.MaxSpeed = r.ReadWord("maxSpeed")
+		p.OverspeedDuration = r.ReadUint8("overspeedDuration")
+		if ver == Version2019 {
+			p.NightMaxSpeed = r.ReadWord("nightMaxSpeed")
+		}
+	}
+}
+
+func (p *RoutePoint) encode(pkt []byte, ver VersionType) []byte {
+	pkt = hex.WriteDoubleWord(pkt, p.PointID)
+	pkt = hex.WriteDoubleWord(pkt, p.SegmentID)
+	pkt = hex.WriteDoubleWord(pkt, p.Latitude)
+	pkt = hex.WriteDoubleWord(pkt, p.Longitude)
+	pkt = hex.WriteByte(pkt, p.Width)
+	pkt = hex.WriteByte(pkt, p.Attr)
+	if p.Attr&RouteSegmentAttrDriveTime != 0 {
+		pkt = hex.WriteWord(pkt, p.MaxDriveTime)
+		pkt = hex.WriteWord(pkt, p.MinDriveTime)
+	}
+	if p.Attr&RouteSegmentAttrSpeedLimit != 0 {
+		pkt = hex.WriteWord(pkt, p.MaxSpeed)
+		pkt = hex.WriteByte(pkt, p.OverspeedDuration)
+		if ver == Version2019 {
+			pkt = hex.WriteWord(pkt, p.NightMaxSpeed)
+		}
+	}
+	return pkt
+}
+
+// RouteArea 路线
+type RouteArea struct {
+	ID        uint32        `json:"id"`
+	Attr      uint16        `json:"attr"`                // 路线属性，bit0根据时间，bit2-5进出路线报警
+	StartTime string        `json:"startTime,omitempty"` // 起始时间，YYMMDDhhmmss，路线属性bit0为1时有
+	EndTime   string        `json:"endTime,omitempty"`   // 结束时间，YYMMDDhhmmss，路线属性bit0为1时有
+	Points    []*RoutePoint `json:"points"`              // 拐点
+	Name      string        `json:"name,omitempty"`      // 路线名称，2019版本
+}
+
+func (a *RouteArea) decode(r *hex.Reader, ver VersionType) {
+	a.ID = r.ReadDoubleWord("routeId")
+	a.Attr = r.ReadWord("routeAttr")
+	if a.Attr&AreaAttrByTime != 0 {
+		a.StartTime = r.ReadBCD("startTime", 6)
+		a.EndTime = r.ReadBCD("endTime", 6)
+	}
+	cnt := int(r.ReadWord("pointCnt"))
+	if r.Require("points", 18*cnt) { // 拐点最少18字节
+		a.Points = make([]*RoutePoint, 0, cnt)
+		for i := 0; i < cnt; i++ {
+			p := &RoutePoint{}
+			p.decode(r, ver)
+			a.Points = append(a.Points, p)
+		}
+	}
+	a.Name = decodeAreaName(r, ver)
+}
+
+func (a *RouteArea) encode(pkt []byte, ver VersionType) ([]byte, error) {
+	pkt = hex.WriteDoubleWord(pkt, a.ID)
+	pkt = hex.WriteWord(pkt, a.Attr)
+	if a.Attr&AreaAttrByTime != 0 {
+		pkt = hex.WriteFixedBCD(pkt, a.StartTime, 6)
+		pkt = hex.WriteFixedBCD(pkt, a.EndTime, 6)
+	}
+	pkt = hex.WriteWord(pkt, uint16(len(a.Points)))
+	for _, p := range a.Points {
+		pkt = p.encode(pkt, ver)
+	}
+	return encodeAreaName(pkt, a.Name, ver)
+}
+
+// 删除区域或路线的消息体，数量为0时删除该类型的全部区域
+func decodeAreaIDs(r *hex.Reader) []uint32 {
+	cnt := int(r.ReadUint8("areaCnt"))
+	if !r.Require("areaIds", 4*cnt) {
+		return nil
+	}
+	ids := make([]uint32, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		ids = append(ids, r.ReadDoubleWord("areaId"))
+	}
+	return ids
+}
+
+func encodeAreaIDs(pkt []byte, ids []uint32) ([]byte, error) {
+	if len(ids) > maxAreaDeleteCount {
+		return nil, errors.Wrapf(ErrEncodeMsg, "Too many areas to delete, count=%d", len(ids))
+	}
+	pkt = hex.WriteByte(pkt, uint8(len(ids)))
+	for _, id := range ids {
+		pkt = hex.WriteDoubleWord(pkt, id)
+	}
+	return pkt, nil
+}
+
+// Area 圆形、矩形、多边形区域和路线的公共接口
+type Area interface {
+	AreaType() uint8
+	AreaID() uint32
+	AreaName() string
+}
+
+func (a *CircleArea) AreaType() uint8     { return AreaTypeCircle }
+func (a *CircleArea) AreaID() uint32      { return a.ID }
+func (a *CircleArea) AreaName() string    { return a.Name }
+func (a *RectangleArea) AreaType() uint8  { return AreaTypeRectangle }
+func (a *RectangleArea) AreaID() uint32   { return a.ID }
+func (a *RectangleArea) AreaName() string { return a.Name }
+func (a *PolygonArea) AreaType() uint8    { return AreaTypePolygon }
+func (a *PolygonArea) AreaID() uint32     { return a.ID }
+func (a *PolygonArea) AreaName() string   { return a.Name }
+func (a *RouteArea) AreaType() uint8      { return AreaTypeRoute }
+func (a *RouteArea) AreaID() uint32       { return a.ID }
+func (a *RouteArea) AreaName() string     { return a.Name }
+
+// UnmarshalAreas 按区域类型解析json数组
+func UnmarshalAreas(typ uint8, data []byte) ([]Area, error) {
+	var areas []Area
+	var err error
+	switch typ {
+	case AreaTypeCircle:
+		var items []*CircleArea
+		err = json.Unmarshal(data, &items)
+		for _, a := range items {
+			areas = append(areas, a)
+		}
+	case AreaTypeRectangle:
+		var items []*RectangleArea
+		err = json.Unmarshal(data, &items)
+		for _, a := range items {
+			areas = append(areas, a)
+		}
+	case AreaTypePolygon:
+		var items []*PolygonArea
+		err = json.Unmarshal(data, &items)
+		for _, a := range items {
+			areas = append(areas, a)
+		}
+	case AreaTypeRoute:
+		var items []*RouteArea
+		err = json.Unmarshal(data, &items)
+		for _, a := range items {
+			areas = append(areas, a)
+		}
+	default:
+		return nil, errors.Wrapf(ErrInvalidArea, "type=%d", typ)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to unmarshal areas")
+	}
+	return areas, nil
+}
+
+// NewAreaSetMsgs 生成设置区域的消息，genHeader按消息ID生成消息头。
+//
+// 圆形和矩形区域一条消息设置全部区域，多边形和路线每条消息一个区域，更新时先删除该类型的全部区域
+func NewAreaSetMsgs(typ, action uint8, areas []Area, genHeader func(msgID uint16) *MsgHeader) ([]JT808Msg, error) {
+	if action > AreaActionModify {
+		return nil, errors.Wrapf(ErrInvalidArea, "action=%d", action)
+	}
+	for _, a := range areas {
+		if a.AreaType() != typ {
+			return nil, errors.Wrapf(ErrInvalidArea, "type=%d, area type=%d", typ, a.AreaType())
+		}
+	}
+	msgs := make([]JT808Msg, 0)
+	switch typ {
+	case AreaTypeCircle, AreaTypeRectangle:
+		if len(areas) == 0 || len(areas) > math.MaxUint8 {
+			return nil, errors.Wrapf(ErrInvalidArea, "area count=%d", len(areas))
+		}
+		if typ == AreaTypeCircle {
+			m := &Msg8600{Header: genHeader(0x8600), Action: action}
+			for _, a := range areas {
+				m.Areas = append(m.Areas, a.(*CircleArea))
+			}
+			msgs = append(msgs, m)
+		} else {
+			m := &Msg8602{Header: genHeader(0x8602), Action: action}
+			for _, a := range areas {
+				m.Areas = append(m.Areas, a.(*RectangleArea))
+			}
+			msgs = append(msgs, m)
+		}
+	case AreaTypePolygon:
+		if action == AreaActionUpdate {
+			msgs = append(msgs, &Msg8605{Header: genHeader(0x8605)})
+		}
+		for _, a := range areas {
+			msgs = append(msgs, &Msg8604{Header: genHeader(0x8604), Area: a.(*PolygonArea)})
+		}
+	case AreaTypeRoute:
+		if action == AreaActionUpdate {
+			msgs = append(msgs, &Msg8607{Header: genHeader(0x8607)})
+		}
+		for _, a := range areas {
+			msgs = append(msgs, &Msg8606{Header: genHeader(0x8606), Route: a.(*RouteArea)})
+		}
+	default:
+		return nil, errors.Wrapf(ErrInvalidArea, "type=%d", typ)
+	}
+	return msgs, nil
+}
+
+var areaDelMsgIDs = map[uint8]uint16{
+	AreaTypeCircle:    0x8601,
+	AreaTypeRectangle: 0x8603,
+	AreaTypePolygon:   0x8605,
+	AreaTypeRoute:     0x8607,
+}
+
+// NewAreaDelMsgs 生成删除区域的消息，ids为空时删除该类型的全部区域，超过125个时拆分为多条消息
+func NewAreaDelMsgs(typ uint8, ids []uint32, genHeader func(msgID uint16) *MsgHeader) ([]JT808Msg, error) {
+	msgID, ok := areaDelMsgIDs[typ]
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidArea, "type=%d", typ)
+	}
+	msgs := make([]JT808Msg, 0)
+	for start := 0; start == 0 || start < len(ids); start += maxAreaDeleteCount {
+		end := start + maxAreaDeleteCount
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+		header := genHeader(msgID)
+		switch typ {
+		case AreaTypeCircle:
+			msgs = append(msgs, &Msg8601{Header: header, IDs: chunk})
+		case AreaTypeRectangle:
+			msgs = append(msgs, &Msg8603{Header: header, IDs: chunk})
+		case AreaTypePolygon:
+			msgs = append(msgs, &Msg8605{Header: header, IDs: chunk})
+		case AreaTypeRoute:
+			msgs = append(msgs, &Msg8607{Header: header, IDs: chunk})
+		}
+	}
+	return msgs, nil
+}
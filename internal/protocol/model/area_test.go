@@ -0,0 +1,173 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// 编码后重新解码，比较消息体
+func encodeDecode(t *testing.T, msg JT808Msg, got JT808Msg) {
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+}
+
+func TestMsg8600_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    *MsgBodyAttr
+		phone   string
+		area    *CircleArea
+		wantLen int // 消息体长度
+	}{
+		{
+			name:    "case1: 2013 without time and speed",
+			attr:    &MsgBodyAttr{VersionDesc: Version2013},
+			phone:   "013800000001",
+			area:    &CircleArea{ID: 1, Attr: AreaAttrEnterAlarmPlatform, Latitude: 30000000, Longitude: 120000000, Radius: 500},
+			wantLen: 2 + 18,
+		},
+		{
+			name:  "case2: 2013 with time and speed",
+			attr:  &MsgBodyAttr{VersionDesc: Version2013},
+			phone: "013800000001",
+			area: &CircleArea{
+				ID: 2, Attr: AreaAttrByTime | AreaAttrSpeedLimit, Latitude: 30000000, Longitude: 120000000, Radius: 500,
+				AreaLimit: AreaLimit{StartTime: "230101080000", EndTime: "231231180000", MaxSpeed: 60, OverspeedDuration: 10},
+			},
+			wantLen: 2 + 18 + 12 + 3,
+		},
+		{
+			name:  "case3: 2019 with night speed and gbk name",
+			attr:  &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019},
+			phone: "00000000013800000001",
+			area: &CircleArea{
+				ID: 3, Attr: AreaAttrSpeedLimit | AreaAttrSouthLatitude, Latitude: 30000000, Longitude: 120000000, Radius: 500,
+				AreaLimit: AreaLimit{MaxSpeed: 80, OverspeedDuration: 5}, NightMaxSpeed: 64, Name: "仓库",
+			},
+			wantLen: 2 + 18 + 3 + 2 + 2 + 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8600{
+				Header: &MsgHeader{MsgID: 0x8600, Attr: tt.attr, PhoneNumber: tt.phone, SerialNumber: 1},
+				Action: AreaActionAppend,
+				Areas:  []*CircleArea{tt.area},
+			}
+			got := &Msg8600{}
+			encodeDecode(t, msg, got)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+			got.Header = msg.Header
+			require.Equal(t, msg, got)
+		})
+	}
+}
+
+func TestMsg8606_EncodeDecode(t *testing.T) {
+	msg := &Msg8606{
+		Header: &MsgHeader{MsgID: 0x8606, Attr: &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019}, PhoneNumber: "00000000013800000001"},
+		Route: &RouteArea{
+			ID:        9,
+			Attr:      AreaAttrByTime | AreaAttrExitAlarmPlatform,
+			StartTime: "230101000000",
+			EndTime:   "231231235959",
+			Points: []*RoutePoint{
+				{PointID: 1, SegmentID: 1, Latitude: 30000000, Longitude: 120000000, Width: 50},
+				{
+					PointID: 2, SegmentID: 2, Latitude: 30001000, Longitude: 120001000, Width: 50,
+					Attr:         RouteSegmentAttrDriveTime | RouteSegmentAttrSpeedLimit,
+					MaxDriveTime: 600, MinDriveTime: 60, MaxSpeed: 80, OverspeedDuration: 10, NightMaxSpeed: 60,
+				},
+			},
+			Name: "送货路线",
+		},
+	}
+	got := &Msg8606{}
+	encodeDecode(t, msg, got)
+	got.Header = msg.Header
+	require.Equal(t, msg, got)
+}
+
+func TestMsg8601_EncodeDecode(t *testing.T) {
+	header := &MsgHeader{MsgID: 0x8601, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"}
+	msg := &Msg8601{Header: header, IDs: []uint32{1, 2, 3}}
+	got := &Msg8601{}
+	encodeDecode(t, msg, got)
+	got.Header = msg.Header
+	require.Equal(t, msg, got)
+
+	// 超过125个区域时无法编码
+	_, err := (&Msg8601{Header: header, IDs: make([]uint32, 126)}).Encode()
+	require.ErrorIs(t, err, ErrEncodeMsg)
+}
+
+func TestMsg0608_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *Msg0608
+	}{
+		{
+			name: "case1: rectangles",
+			msg: &Msg0608{
+				QueryType: AreaTypeRectangle,
+				Rectangles: []*RectangleArea{
+					{ID: 1, TopLeftLatitude: 30001000, TopLeftLongitude: 120000000, BottomRightLatitude: 30000000, BottomRightLongitude: 120001000, Name: "A"},
+					{ID: 2, Attr: AreaAttrSpeedLimit, AreaLimit: AreaLimit{MaxSpeed: 40, OverspeedDuration: 3}, NightMaxSpeed: 30, Name: "B"},
+				},
+			},
+		},
+		{
+			name: "case2: polygons",
+			msg: &Msg0608{
+				QueryType: AreaTypePolygon,
+				Polygons: []*PolygonArea{
+					{ID: 5, Vertices: []*AreaPoint{{Latitude: 1, Longitude: 2}, {Latitude: 3, Longitude: 4}, {Latitude: 5, Longitude: 6}}, Name: "多边形"},
+				},
+			},
+		},
+		{
+			name: "case3: nothing found",
+			msg:  &Msg0608{QueryType: AreaTypeRoute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.Header = &MsgHeader{MsgID: 0x0608, Attr: &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019}, PhoneNumber: "00000000013800000001"}
+			got := &Msg0608{}
+			encodeDecode(t, tt.msg, got)
+			got.Header = tt.msg.Header
+			require.Equal(t, tt.msg, got)
+		})
+	}
+}
+
+func TestNewAreaMsgs(t *testing.T) {
+	serial := uint16(0)
+	genHeader := func(msgID uint16) *MsgHeader {
+		serial++
+		return &MsgHeader{MsgID: msgID, SerialNumber: serial}
+	}
+	areas, err := UnmarshalAreas(AreaTypePolygon, []byte(`[{"id":1,"vertices":[{"latitude":1,"longitude":2}]},{"id":2,"vertices":[]}]`))
+	require.Nil(t, err)
+
+	// 更新多边形时先删除全部多边形区域
+	msgs, err := NewAreaSetMsgs(AreaTypePolygon, AreaActionUpdate, areas, genHeader)
+	require.Nil(t, err)
+	require.Len(t, msgs, 3)
+	require.Empty(t, msgs[0].(*Msg8605).IDs)
+	require.Equal(t, uint32(2), msgs[2].(*Msg8604).Area.ID)
+
+	_, err = NewAreaSetMsgs(AreaTypeCircle, AreaActionAppend, areas, genHeader)
+	require.ErrorIs(t, err, ErrInvalidArea)
+
+	// 删除超过125个区域时拆分
+	msgs, err = NewAreaDelMsgs(AreaTypeRoute, make([]uint32, 130), genHeader)
+	require.Nil(t, err)
+	require.Len(t, msgs, 2)
+	require.Len(t, msgs[1].(*Msg8607).IDs, 5)
+}
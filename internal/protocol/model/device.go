@@ -29,6 +29,7 @@ type Device struct {
 	Keepalive    time.Duration       `json:"keepalive"`   // 保活时长
 	LastComTime  time.Time           `json:"lastComTime"` // 最近一次交互时间
 	Status       DeviceStatus        `json:"status"`
+	CertIdentity string              `json:"certIdentity"` // 注册时绑定的客户端证书CN，双向TLS认证时有值
 
 	// 设备信息
 	VersionDesc     VersionType `json:"versionDesc"`     // jt808协议版本描述, 区分 2011 / 2013 / 2019
@@ -39,6 +40,10 @@ type Device struct {
 }
 
 func NewDevice(in *Msg0100, session *Session) *Device {
+	var certIdentity string
+	if session.PeerIdentity != nil {
+		certIdentity = session.PeerIdentity.CommonName
+	}
 
 	return &Device{
 		ID:              in.DeviceID,
@@ -50,6 +55,7 @@ func NewDevice(in *Msg0100, session *Session) *Device {
 		Keepalive:       time.Minute * 1,
 		LastComTime:     time.Now(),
 		Status:          DeviceStatusOffline,
+		CertIdentity:    certIdentity,
 		VersionDesc:     in.Header.Attr.VersionDesc,
 		ProtocolVersion: in.Header.ProtocolVersion,
 	}
@@ -68,11 +74,12 @@ func (d *Device) ShouldClear() bool {
 
 // 终端设备地理位置状态相关信息
 type DeviceGeo struct {
-	Phone    string    `json:"phone"`
-	Geo      *GeoMeta  `json:"gis"`
-	Location *Location `json:"location"`
-	Drive    *Drive    `json:"drive"`
-	Time     time.Time `json:"time"`
+	Phone     string    `json:"phone"`
+	Geo       *GeoMeta  `json:"gis"`
+	Location  *Location `json:"location"`
+	Drive     *Drive    `json:"drive"`
+	Time      time.Time `json:"time"`
+	BlindZone bool      `json:"blindZone"` // 是否为0x0704盲区补报的位置
 }
 
 func (dg *DeviceGeo) Decode(phone string, m *Msg0200) error {
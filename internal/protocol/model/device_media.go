@@ -17,15 +17,15 @@ type DeviceMediaQuery struct {
 	StorageType    uint8      `json:"storageType"`    // 存储器类型。0：所有存储器；1：主存储器；2：灾备存储器
 }
 
-func (q *DeviceMediaQuery) Decode(pkt []byte, idx *int) {
-	q.LogicChannelID = hex.ReadByte(pkt, idx)
-	q.StartTime = hex.ReadTime(pkt, idx)
-	q.EndTime = hex.ReadTime(pkt, idx)
-	q.AlarmSign = hex.ReadDoubleWord(pkt, idx)
-	q.AlarmSignExt = hex.ReadDoubleWord(pkt, idx)
-	q.MediaType = hex.ReadByte(pkt, idx)
-	q.StreamType = hex.ReadByte(pkt, idx)
-	q.StorageType = hex.ReadByte(pkt, idx)
+func (q *DeviceMediaQuery) Decode(r *hex.Reader) {
+	q.LogicChannelID = r.ReadUint8("logicChannelId")
+	q.StartTime = r.ReadTime("startTime")
+	q.EndTime = r.ReadTime("endTime")
+	q.AlarmSign = r.ReadDoubleWord("alarmSign")
+	q.AlarmSignExt = r.ReadDoubleWord("alarmSignExt")
+	q.MediaType = r.ReadUint8("mediaType")
+	q.StreamType = r.ReadUint8("streamType")
+	q.StorageType = r.ReadUint8("storageType")
 }
 
 func (q *DeviceMediaQuery) Encode() (pkt []byte) {
@@ -45,10 +45,9 @@ type DeviceMedia struct {
 	Size uint32 // 文件大小，单位Byte
 }
 
-func (m *DeviceMedia) Decode(pkt []byte) {
-	idx := 0
-	m.DeviceMediaQuery.Decode(pkt, &idx)
-	m.Size = hex.ReadDoubleWord(pkt, &idx)
+func (m *DeviceMedia) Decode(r *hex.Reader) {
+	m.DeviceMediaQuery.Decode(r)
+	m.Size = r.ReadDoubleWord("size")
 }
 
 func (m *DeviceMedia) Encode() (pkt []byte) {
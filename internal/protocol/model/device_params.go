@@ -23,13 +23,12 @@ type DeviceParams struct {
 	Params      []*ParamData `json:"settings"` // 参数项列表
 }
 
-func (p *DeviceParams) Decode(phone string, cnt uint8, pkt []byte) error {
+func (p *DeviceParams) Decode(phone string, cnt uint8, r *hex.Reader) error {
 	p.DevicePhone = phone
 	p.ParamCnt = cnt
-	idx := 0
 	for i := 0; i < int(cnt); i++ {
 		param := &ParamData{}
-		err := param.Decode(pkt, &idx)
+		err := param.Decode(r)
 		if err != nil {
 			return err
 		}
@@ -76,6 +75,22 @@ func (p *DeviceParams) Update(newParams *DeviceParams) {
 	p.ParamCnt = uint8(len(mergeParams))
 }
 
+// Select 按参数ID筛选参数项，按ids顺序返回，不存在的ID跳过
+func (p *DeviceParams) Select(ids []uint32) *DeviceParams {
+	paramMap := make(map[uint32]*ParamData)
+	for _, param := range p.Params {
+		paramMap[param.ParamID] = param
+	}
+	selected := &DeviceParams{DevicePhone: p.DevicePhone}
+	for _, id := range ids {
+		if param, ok := paramMap[id]; ok {
+			selected.Params = append(selected.Params, param)
+		}
+	}
+	selected.ParamCnt = uint8(len(selected.Params))
+	return selected
+}
+
 type ParamData struct {
 	ParamID  uint32 `json:"id"`     // 参数ID
 	ParamLen uint8  `json:"length"` // 参数长度
@@ -85,14 +100,26 @@ type ParamData struct {
 	ParamDesc  string `json:"desc"`  // 参数说明
 }
 
-func (p *ParamData) Decode(pkt []byte, idx *int) error {
-	p.ParamID = hex.ReadDoubleWord(pkt, idx)
-	p.ParamLen = hex.ReadByte(pkt, idx)
+func (p *ParamData) Decode(r *hex.Reader) error {
+	p.ParamID = r.ReadDoubleWord("paramId")
+	p.ParamLen = r.ReadUint8("paramLen")
+	value := r.ReadBytes("paramValue", int(p.ParamLen))
+	if r.Err() != nil {
+		return r.Err()
+	}
 	fn, ok := argTable[p.ParamID]
 	if !ok {
+		// 未定义的参数(如旧版本终端的自定义参数)按原始字节读取，避免后续参数错位
 		log.Warn().Str("ParamID", fmt.Sprintf("0x%04x", p.ParamID)).Err(ErrParamIDNotSupportted).Msg("skip it")
+		fn = &paramFn{decode: decodeBytes, encode: encodeBytes}
+	}
+	// 按参数长度截取后再解析，参数值长度与定义不一致时不影响后续参数
+	vr := hex.NewReader(value)
+	p.ParamValue = fn.decode(vr, int(p.ParamLen))
+	if vr.Err() != nil {
+		r.Fail(fmt.Sprintf("param 0x%04x", p.ParamID), int(p.ParamLen))
+		return r.Err()
 	}
-	p.ParamValue = fn.decode(pkt, idx, int(p.ParamLen))
 	return nil
 }
 
@@ -112,7 +139,7 @@ func (p *ParamData) Encode() (pkt []byte, err error) {
 }
 
 type paramFn struct {
-	decode func([]byte, *int, int) any
+	decode func(*hex.Reader, int) any
 	encode func(any) (pkt []byte)
 }
 
@@ -161,7 +188,7 @@ func writeDoubleWordAny(pkt []byte, num any) []byte {
 }
 
 // []byte -> struct
-func decodeDsm(b []byte, idx *int, paramLen int) any {
+func decodeDsm(r *hex.Reader, paramLen int) any {
 	dsm := &SettingsAiDSM{}
 
 	// 遍历结构体
@@ -174,13 +201,13 @@ func decodeDsm(b []byte, idx *int, paramLen int) any {
 		}
 		switch field.Type().Size() {
 		case 1:
-			field.Set(reflect.ValueOf(hex.ReadByte(b, idx)))
+			field.Set(reflect.ValueOf(r.ReadUint8(t.Field(k).Name)))
 			break
 		case 2:
-			field.Set(reflect.ValueOf(hex.ReadWord(b, idx)))
+			field.Set(reflect.ValueOf(r.ReadWord(t.Field(k).Name)))
 			break
 		case 4:
-			field.Set(reflect.ValueOf(hex.ReadDoubleWord(b, idx)))
+			field.Set(reflect.ValueOf(r.ReadDoubleWord(t.Field(k).Name)))
 			break
 		}
 	}
@@ -221,17 +248,17 @@ func encodeDsm(a any) (pkt []byte) {
 }
 
 var (
-	decodeByte       = func(b []byte, idx *int, paramLen int) any { return hex.ReadByte(b, idx) }
+	decodeByte       = func(r *hex.Reader, paramLen int) any { return r.ReadUint8("value") }
 	encodeByte       = func(a any) (pkt []byte) { return writeByteAny(pkt, a) }
-	decodeWord       = func(b []byte, idx *int, paramLen int) any { return hex.ReadWord(b, idx) }
+	decodeWord       = func(r *hex.Reader, paramLen int) any { return r.ReadWord("value") }
 	encodeWord       = func(a any) (pkt []byte) { return writeWordAny(pkt, a) }
-	decodeDoubleWord = func(b []byte, idx *int, paramLen int) any { return hex.ReadDoubleWord(b, idx) }
+	decodeDoubleWord = func(r *hex.Reader, paramLen int) any { return r.ReadDoubleWord("value") }
 	encodeDoubleWord = func(a any) (pkt []byte) { return writeDoubleWordAny(pkt, a) }
-	decodeBytes      = func(b []byte, idx *int, paramLen int) any { return hex.ReadBCD(b, idx, paramLen) } // transform bytes to string
-	encodeBytes      = func(a any) (pkt []byte) { return hex.WriteBCD(pkt, a.(string)) }                   // transform string to bytes
-	decodeString     = func(b []byte, idx *int, paramLen int) any { return hex.ReadString(b, idx, paramLen) }
+	decodeBytes      = func(r *hex.Reader, paramLen int) any { return r.ReadBCD("value", paramLen) } // transform bytes to string
+	encodeBytes      = func(a any) (pkt []byte) { return hex.WriteBCD(pkt, a.(string)) }             // transform string to bytes
+	decodeString     = func(r *hex.Reader, paramLen int) any { return r.ReadString("value", paramLen) }
 	encodeString     = func(a any) (pkt []byte) { return hex.WriteString(pkt, a.(string)) }
-	decodeGBK        = func(b []byte, idx *int, paramLen int) any { return hex.ReadGBK(b, idx, paramLen) }
+	decodeGBK        = func(r *hex.Reader, paramLen int) any { return r.ReadGBK("value", paramLen) }
 	encodeGBK        = func(a any) (pkt []byte) { return hex.WriteGBK(pkt, a.(string)) }
 )
 
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
 )
 
 func TestDeviceArgs_Decode(t *testing.T) {
@@ -32,7 +34,7 @@ func TestDeviceArgs_Decode(t *testing.T) {
 				Params:   tt.fields.Args,
 			}
 			got := &DeviceParams{}
-			err := got.Decode("", tt.args.cnt, tt.args.pkt)
+			err := got.Decode("", tt.args.cnt, hex.NewReader(tt.args.pkt))
 			require.Equal(t, tt.wantErr, err != nil, err)
 			require.Equal(t, want, got)
 		})
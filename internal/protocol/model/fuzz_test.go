@@ -0,0 +1,62 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 解码任意输入都不能panic，失败时返回ErrDecodeHeader或ErrDecodeMsg
+func fuzzDecode(t *testing.T, data []byte, msg JT808Msg) {
+	header := &MsgHeader{}
+	if err := header.Decode(data); err != nil {
+		if !errors.Is(err, ErrDecodeHeader) {
+			t.Fatalf("unexpected header error: %v", err)
+		}
+		return
+	}
+	if msg == nil {
+		return
+	}
+	body := data[header.Idx:]
+	header.Attr.BodyLength = uint16(len(body))
+	if err := msg.Decode(&PacketData{Header: header, Body: body}); err != nil && !errors.Is(err, ErrDecodeMsg) {
+		t.Fatalf("unexpected msg error: %v", err)
+	}
+}
+
+func FuzzMsgHeader_Decode(f *testing.F) {
+	f.Add(hex.Str2Byte("0100400001123456789012345678900001"))
+	f.Add(hex.Str2Byte("01022000013912345678000200030001"))
+	f.Add([]byte{0x01, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, nil)
+	})
+}
+
+func FuzzMsg0100_Decode(f *testing.F) {
+	f.Add(hex.Str2Byte("010000210139123456780001002c012c37303131314a543830382d31314130303030303102d4c1423132333435"))
+	f.Add(hex.Str2Byte("0100405401123456789012345678900001001f007366616b6579616e" +
+		"7373000066616b6579616e73732e6769746875622e696f0000000000000000000000313233" +
+		"34414243440000000000000000000000000000000000000000000001bea9413132333435"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, &Msg0100{})
+	})
+}
+
+func FuzzMsg0200_Decode(f *testing.F) {
+	f.Add(hex.Str2Byte("0200002a013912345678000300000000000000030157faf806cc628900140258005a2301011200000104000030390202012c03020262"))
+	f.Add(hex.Str2Byte("0200001c01391234567800030000000000000003"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, &Msg0200{})
+	})
+}
+
+func FuzzMsg0104_Decode(f *testing.F) {
+	f.Add(hex.Str2Byte("0104001a013912345678000400050200000001040000003c00000013093132372e302e302e31"))
+	f.Add(hex.Str2Byte("01040010013912345678000400050200000001020001"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzDecode(t, data, &Msg0104{})
+	})
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type GeofenceEventType string
+
+const (
+	GeofenceEnter GeofenceEventType = "enter" // 进入围栏
+	GeofenceExit  GeofenceEventType = "exit"  // 离开围栏
+	GeofenceDwell GeofenceEventType = "dwell" // 在围栏内停留超过设定时长
+)
+
+// GeofenceEvent 平台根据终端位置判断产生的围栏事件
+type GeofenceEvent struct {
+	Phone     string            `json:"phone"`
+	FenceID   string            `json:"fenceId"`
+	FenceName string            `json:"fenceName"`
+	Type      GeofenceEventType `json:"type"`
+	Latitude  float64           `json:"latitude"`  // 触发事件的位置，南纬为负数
+	Longitude float64           `json:"longitude"` // 触发事件的位置，西经为负数
+	Duration  int64             `json:"duration"`  // 离开和停留事件为在围栏内的时长，单位秒
+	Time      time.Time         `json:"time"`      // 触发事件的位置上报时间
+}
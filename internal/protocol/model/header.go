@@ -25,42 +25,38 @@ type MsgHeader struct {
 
 // 将[]byte解码成消息头结构体
 func (h *MsgHeader) Decode(pkt []byte) error {
-	var idx int
+	r := hex.NewReader(pkt)
 
-	h.MsgID = hex.ReadWord(pkt, &idx) // 消息id [0,2)位
-
-	// fmt.Printf("Recv RawMsgID: 0x%04x\n", h.MsgID)
+	h.MsgID = r.ReadWord("msgId") // 消息id [0,2)位
 
 	h.Attr = &MsgBodyAttr{}
-	err := h.Attr.Decode(hex.ReadWord(pkt, &idx)) // 消息体属性 [2,4)位
+	err := h.Attr.Decode(r.ReadWord("attr")) // 消息体属性 [2,4)位
 	if err != nil {
 		return ErrDecodeHeader
 	}
 
 	if h.Attr.VersionDesc == Version2019 {
-		h.ProtocolVersion = hex.ReadByte(pkt, &idx) // 2019版本，协议版本号 第4位
+		h.ProtocolVersion = r.ReadUint8("protocolVersion") // 2019版本，协议版本号 第4位
 	}
 
-	// 2013版本，phoneNumber [5,11)位 长度6位；2019版本，phoneNumber [5,15)位 长度10位。
-	if h.Attr.VersionDesc == Version2019 {
-		h.PhoneNumber = hex.ReadBCD(pkt, &idx, 10)
-	} else if h.Attr.VersionDesc == Version2013 {
-		h.PhoneNumber = hex.ReadBCD(pkt, &idx, 6)
+	// 2011/2013版本，phoneNumber [4,10)位 长度6位；2019版本，phoneNumber [5,15)位 长度10位。
+	if h.Attr.VersionDesc == Version2019 || h.Attr.VersionDesc == Version2013 || h.Attr.VersionDesc == Version2011 {
+		h.PhoneNumber = r.ReadBCD("phoneNumber", phoneLen(h.Attr.VersionDesc))
 	} else {
 		return ErrDecodeHeader
 	}
 
-	h.SerialNumber = hex.ReadWord(pkt, &idx)
+	h.SerialNumber = r.ReadWord("serialNumber")
 
 	if h.Attr.PacketFragmentedDesc {
 		h.Frag = &MsgFragmentation{}
-		err = h.Frag.Decode(pkt, &idx) // 消息包封装项
-		if err != nil {
-			return ErrDecodeHeader
-		}
+		_ = h.Frag.Decode(r) // 消息包封装项，错误在下面统一检查
 	}
 
-	h.Idx = idx
+	if err := r.Err(); err != nil {
+		return &DecodeError{Kind: ErrDecodeHeader, MsgID: h.MsgID, Err: err}
+	}
+	h.Idx = r.Idx()
 
 	return nil
 }
@@ -72,8 +68,8 @@ func (h *MsgHeader) Encode() (pkt []byte, err error) {
 	if h.Attr.VersionDesc == Version2019 {
 		pkt = hex.WriteByte(pkt, h.ProtocolVersion) // 协议版本号
 	}
-	pkt = hex.WriteBCD(pkt, h.PhoneNumber)   // 手机号
-	pkt = hex.WriteWord(pkt, h.SerialNumber) // 消息流水号
+	pkt = hex.WriteFixedBCD(pkt, h.PhoneNumber, phoneLen(h.Attr.VersionDesc)) // 手机号
+	pkt = hex.WriteWord(pkt, h.SerialNumber)                                  // 消息流水号
 	if h.Frag != nil {
 		pkt = append(pkt, h.Frag.Encode()...) // 消息包封装项
 	}
@@ -131,7 +127,7 @@ type MsgBodyAttr struct {
 	BodyLength       uint16 `json:"bodyLength"`       // 消息体长度
 	Encryption       uint8  `json:"encryption"`       // 加密类型
 	PacketFragmented uint8  `json:"packetFragmented"` // 分包标识，1：长消息，有分包；2：无分包
-	VersionSign      uint8  `json:"versionSign"`      // 版本标识，1：2019版本；0：2013版本
+	VersionSign      uint8  `json:"versionSign"`      // 版本标识，1：2019版本；0：2011/2013版本
 	Extra            uint8  `json:"extra"`            // 预留一个bit位的保留字段
 
 	EncryptionDesc       EncryptionType       `json:"encryptionDesc"`       // 加密类型描述
@@ -160,7 +156,9 @@ func (attr *MsgBodyAttr) Decode(bitNum uint16) error {
 		attr.PacketFragmentedDesc = PacketFragmentedFalse
 	}
 
-	attr.VersionSign = uint8(bitNum & versionSignBit >> 14) // 版本标识 14位
+	// 版本标识 14位。2011版本该位为保留位，消息头与2013版本相同，
+	// 先按2013版本解析，再根据注册消息长度或终端缓存识别2011版本
+	attr.VersionSign = uint8(bitNum & versionSignBit >> 14)
 	if attr.VersionSign == 1 {
 		attr.VersionDesc = Version2019
 	} else {
@@ -173,7 +171,7 @@ func (attr *MsgBodyAttr) Decode(bitNum uint16) error {
 
 func (attr *MsgBodyAttr) Encode() uint16 {
 	var bitNum uint16
-	bitNum += attr.BodyLength                     // 消息体长度
+	bitNum += attr.BodyLength & bodyLengthBit     // 消息体长度
 	bitNum += uint16(attr.Encryption) << 10       // 加密方式
 	bitNum += uint16(attr.PacketFragmented) << 13 // 分包
 	bitNum += uint16(attr.VersionSign) << 14      // 版本标识
@@ -187,10 +185,10 @@ type MsgFragmentation struct {
 	Index uint16 `json:"index"` // 包序号，从1开始
 }
 
-func (frag *MsgFragmentation) Decode(pkt []byte, idx *int) error {
-	frag.Total = hex.ReadWord(pkt, idx)
-	frag.Index = hex.ReadWord(pkt, idx)
-	return nil
+func (frag *MsgFragmentation) Decode(r *hex.Reader) error {
+	frag.Total = r.ReadWord("fragTotal")
+	frag.Index = r.ReadWord("fragIndex")
+	return r.Err()
 }
 
 func (frag *MsgFragmentation) Encode() []byte {
@@ -207,18 +205,38 @@ func versionDecode(ver VersionType) uint8 {
 	return 0
 }
 
-func GenMsgHeader(d *Device, msgID, serialNumber uint16) *MsgHeader {
+// 消息头中终端手机号的BCD长度，2019版本10字节，2011/2013版本6字节
+func phoneLen(ver VersionType) int {
+	if ver == Version2019 {
+		return 10
+	}
+	return 6
+}
+
+func genHeader(ver VersionType, protocolVersion uint8, phone string, msgID, serialNumber uint16) *MsgHeader {
 	return &MsgHeader{
 		MsgID: msgID,
 		Attr: &MsgBodyAttr{
 			Encryption:       uint8(EncryptionNone),
 			PacketFragmented: 0,
-			VersionSign:      versionDecode(d.VersionDesc),
+			VersionSign:      versionDecode(ver),
 			Extra:            0,
-			VersionDesc:      d.VersionDesc,
+			VersionDesc:      ver,
 		},
-		ProtocolVersion: d.ProtocolVersion,
-		PhoneNumber:     d.Phone,
+		ProtocolVersion: protocolVersion,
+		PhoneNumber:     phone,
 		SerialNumber:    serialNumber,
 	}
 }
+
+// GenMsgHeader 按终端注册时协商的协议版本生成下发消息的消息头
+func GenMsgHeader(d *Device, msgID, serialNumber uint16) *MsgHeader {
+	return genHeader(d.VersionDesc, d.ProtocolVersion, d.Phone, msgID, serialNumber)
+}
+
+// GenReplyHeader 按收到的消息头生成应答消息头，沿用对方的协议版本和手机号。
+//
+// 生成新的消息头而不是复用收到的消息头，流水号由发送时的会话重新分配
+func GenReplyHeader(in *MsgHeader, msgID uint16) *MsgHeader {
+	return genHeader(in.Attr.VersionDesc, in.ProtocolVersion, in.PhoneNumber, msgID, in.SerialNumber)
+}
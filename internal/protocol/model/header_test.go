@@ -147,7 +147,6 @@ func TestMsgFragmentation_Decode(t *testing.T) {
 	}
 	type args struct {
 		pkt []byte
-		idx *int
 	}
 	tests := []struct {
 		name    string
@@ -155,17 +154,19 @@ func TestMsgFragmentation_Decode(t *testing.T) {
 		args    args
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{name: "case1: decode frag", fields: fields{Total: 3, Index: 1}, args: args{pkt: hex.Str2Byte("00030001")}},
+		{name: "case2: short frag", args: args{pkt: hex.Str2Byte("0003")}, wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			frag := &MsgFragmentation{
-				Total: tt.fields.Total,
-				Index: tt.fields.Index,
-			}
-			if err := frag.Decode(tt.args.pkt, tt.args.idx); (err != nil) != tt.wantErr {
+			frag := &MsgFragmentation{}
+			if err := frag.Decode(hex.NewReader(tt.args.pkt)); (err != nil) != tt.wantErr {
 				t.Errorf("MsgFragmentation.Decode() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr {
+				require.Equal(t, tt.fields.Total, frag.Total)
+				require.Equal(t, tt.fields.Index, frag.Index)
+			}
 		})
 	}
 }
@@ -194,3 +195,38 @@ func TestMsgFragmentation_Encode(t *testing.T) {
 		})
 	}
 }
+
+func TestGenReplyHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *MsgHeader
+		want []byte
+	}{
+		{
+			name: "case1: 2019 reply",
+			in:   genMsgHeader(0x0200),
+			want: hex.Str2Byte("8001400001123456789012345678900001"),
+		},
+		{
+			name: "case2: 2013 reply with short phone",
+			in: &MsgHeader{
+				MsgID:        0x0200,
+				Attr:         &MsgBodyAttr{VersionDesc: Version2013, Encryption: uint8(EncryptionRSA), EncryptionDesc: EncryptionRSA},
+				PhoneNumber:  "13912345678",
+				SerialNumber: 1,
+			},
+			want: hex.Str2Byte("80010000013912345678" + "0001"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := GenReplyHeader(tt.in, 0x8001)
+			got, err := h.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got)
+			// 不修改收到的消息头
+			require.Equal(t, uint16(0x0200), tt.in.MsgID)
+			require.NotSame(t, tt.in.Attr, h.Attr)
+		})
+	}
+}
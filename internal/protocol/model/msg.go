@@ -1,7 +1,11 @@
 package model
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
 )
 
 const (
@@ -17,6 +21,46 @@ var (
 	ErrGenOutgoingMsg = errors.New("Fail to generate outgoing msg")
 )
 
+// DecodeError 解码失败的错误，可以通过errors.Is判断ErrDecodeMsg/ErrDecodeHeader，
+// 通过errors.As获取hex.ReadError中的字段名和偏移
+type DecodeError struct {
+	Kind  error  // ErrDecodeMsg或ErrDecodeHeader
+	MsgID uint16 // 消息ID
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v, msgId=0x%04x: %v", e.Kind, e.MsgID, e.Err)
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// 检查消息体读取是否出错
+func decodeErr(msgID uint16, r *hex.Reader) error {
+	if err := r.Err(); err != nil {
+		return &DecodeError{Kind: ErrDecodeMsg, MsgID: msgID, Err: err}
+	}
+	return nil
+}
+
+// 读取n个WORD，数量超过剩余长度时直接记录错误，避免按非法的数量分配内存
+func readWords(r *hex.Reader, field string, n int) []uint16 {
+	if !r.Require(field, 2*n) {
+		return nil
+	}
+	words := make([]uint16, 0, n)
+	for i := 0; i < n; i++ {
+		words = append(words, r.ReadWord(field))
+	}
+	return words
+}
+
 type JT808Msg interface {
 	Decode(*PacketData) error            // Packet -> JT808Msg
 	Encode() (pkt []byte, err error)     // JT808Msg -> Packet
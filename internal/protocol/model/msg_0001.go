@@ -34,12 +34,12 @@ func (m *Msg0001) Result2Str() string {
 
 func (m *Msg0001) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
+	r := hex.NewReader(packet.Body)
 
-	m.AnswerSerialNumber = hex.ReadWord(pkt, &idx)
-	m.AnswerMessageID = hex.ReadWord(pkt, &idx)
-	m.Result = hex.ReadByte(pkt, &idx)
-	return nil
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.AnswerMessageID = r.ReadWord("answerMessageId")
+	m.Result = r.ReadUint8("result")
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg0001) Encode() (pkt []byte, err error) {
@@ -61,7 +61,6 @@ func (m *Msg0001) GenOutgoing(incoming JT808Msg) error {
 	m.AnswerMessageID = header.MsgID
 	m.Result = 0
 
-	m.Header = header
-	m.Header.MsgID = 0x0001
+	m.Header = GenReplyHeader(header, 0x0001)
 	return nil
 }
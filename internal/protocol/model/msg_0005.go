@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 终端补传分包请求，请求平台重新下发指定的分包(2019版本)
+type Msg0005 struct {
+	Header               *MsgHeader `json:"header"`
+	OriginalSerialNumber uint16     `json:"originalSerialNumber"` // 对应要求补传的原始消息第一包的流水号
+	RetransmitCnt        uint16     `json:"retransmitCnt"`        // 重传包总数
+	RetransmitIDs        []uint16   `json:"retransmitIds"`        // 重传包序号列表
+}
+
+func (m *Msg0005) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.OriginalSerialNumber = r.ReadWord("originalSerialNumber")
+	m.RetransmitCnt = r.ReadWord("retransmitCnt")
+	m.RetransmitIDs = readWords(r, "retransmitIds", int(m.RetransmitCnt))
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0005) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteWord(pkt, m.OriginalSerialNumber)
+	pkt = hex.WriteWord(pkt, uint16(len(m.RetransmitIDs)))
+	for _, id := range m.RetransmitIDs {
+		pkt = hex.WriteWord(pkt, id)
+	}
+
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0005) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0005) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
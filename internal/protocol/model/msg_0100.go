@@ -28,33 +28,32 @@ type Msg0100 struct {
 
 func (m *Msg0100) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.ProvinceID = hex.ReadWord(pkt, &idx)
-	m.CityID = hex.ReadWord(pkt, &idx)
+	r := hex.NewReader(packet.Body)
+	m.ProvinceID = r.ReadWord("provinceId")
+	m.CityID = r.ReadWord("cityId")
 
-	ver := &m.Header.Attr.VersionDesc
+	// 2011与2013版本的消息头相同，版本由解码时按终端缓存或配置确定
+	ver := m.Header.Attr.VersionDesc
 	var manuLen, modeLen, idLen int
-	if *ver == Version2019 {
+	if ver == Version2019 {
 		manuLen, modeLen, idLen = 11, 30, 30
-	} else if *ver == Version2013 {
-		manuLen, idLen = 5, 7
-		remainLen := int(m.Header.Attr.BodyLength) - idx
-		if remainLen > 5+20+7+1 { // 厂商+型号+ID+车牌颜色，2013版本至少33位
-			modeLen = 20
-		} else {
-			modeLen = 8
-			ver = &[]VersionType{Version2011}[0]
-		}
+	} else if ver == Version2013 {
+		manuLen, modeLen, idLen = 5, 20, 7
+	} else if ver == Version2011 {
+		manuLen, modeLen, idLen = 5, 8, 7
 	} else {
 		return ErrDecodeMsg
 	}
 	cutset := "\x00"
-	m.ManufacturerID = strings.TrimRight(hex.ReadString(pkt, &idx, manuLen), cutset)
-	m.DeviceMode = strings.TrimRight(hex.ReadString(pkt, &idx, modeLen), cutset)
-	m.DeviceID = strings.TrimRight(hex.ReadString(pkt, &idx, idLen), cutset)
-
-	m.PlateColor = hex.ReadByte(pkt, &idx)
-	m.PlateNumber = hex.ReadGBK(pkt, &idx, int(m.Header.Attr.BodyLength)-idx)
+	m.ManufacturerID = strings.TrimRight(r.ReadString("manufacturerId", manuLen), cutset)
+	m.DeviceMode = strings.TrimRight(r.ReadString("deviceMode", modeLen), cutset)
+	m.DeviceID = strings.TrimRight(r.ReadString("deviceId", idLen), cutset)
+
+	m.PlateColor = r.ReadUint8("plateColor")
+	m.PlateNumber = r.ReadGBK("plateNumber", r.Len())
+	if err := decodeErr(m.Header.MsgID, r); err != nil {
+		return err
+	}
 	m.LocationDesc = region.Parse(fmt.Sprintf("%02d%04d", m.ProvinceID, m.CityID)).Name
 
 	return nil
@@ -61,3 +61,18 @@ func TestMsg0100_Encode(t *testing.T) {
 		})
 	}
 }
+
+func TestMsg0100_DecodeTruncated(t *testing.T) {
+	// 2011版本注册消息截断在终端ID中间
+	pkt := hex.Str2Byte("010000140139123456780001002c012c37303131314a543830382d313141303030")
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	header.Attr.VersionDesc = Version2011
+	err := (&Msg0100{}).Decode(&PacketData{Header: header, Body: pkt[header.Idx:]})
+	require.ErrorIs(t, err, ErrDecodeMsg)
+	require.ErrorIs(t, err, hex.ErrShortRead)
+	var readErr *hex.ReadError
+	require.ErrorAs(t, err, &readErr)
+	require.Equal(t, "deviceId", readErr.Field)
+	require.Equal(t, 17, readErr.Offset)
+}
@@ -9,7 +9,7 @@ import (
 // 终端鉴权
 type Msg0102 struct {
 	Header          *MsgHeader `json:"header"`
-	AuthCodeLen     uint8      `json:"authCodeLen"`     // 鉴权码长度，byte，2019版本有
+	AuthCodeLen     uint8      `json:"authCodeLen"`     // 鉴权码长度，byte，2019版本有，2011/2013版本消息体只有鉴权码
 	AuthCode        string     `json:"authCode"`        // 鉴权码，string
 	IMEI            string     `json:"imei"`            // 终端IMEI，byte(15)，2019版本有
 	SoftwareVersion string     `json:"softwareVersion"` // 软件版本号，byte(20)，2019版本有
@@ -17,17 +17,17 @@ type Msg0102 struct {
 
 func (m *Msg0102) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
+	r := hex.NewReader(packet.Body)
 	ver := m.Header.Attr.VersionDesc
-	if ver == Version2013 {
-		m.AuthCode = hex.ReadString(pkt, &idx, int(m.Header.Attr.BodyLength)-idx)
+	if ver == Version2013 || ver == Version2011 {
+		m.AuthCode = r.ReadString("authCode", r.Len())
 	} else if ver == Version2019 {
-		m.AuthCodeLen = hex.ReadByte(pkt, &idx)
-		m.AuthCode = hex.ReadString(pkt, &idx, int(m.AuthCodeLen))
-		m.IMEI = hex.ReadString(pkt, &idx, 15)
-		m.SoftwareVersion = strings.TrimRight(hex.ReadString(pkt, &idx, 20), "\x00")
+		m.AuthCodeLen = r.ReadUint8("authCodeLen")
+		m.AuthCode = r.ReadString("authCode", int(m.AuthCodeLen))
+		m.IMEI = r.ReadString("imei", 15)
+		m.SoftwareVersion = strings.TrimRight(r.ReadString("softwareVersion", 20), "\x00")
 	}
-	return nil
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg0102) Encode() (pkt []byte, err error) {
@@ -69,8 +69,7 @@ func (m *Msg0102) GenOutgoing(incoming JT808Msg) error {
 	if !ok {
 		return ErrGenOutgoingMsg
 	}
-	m.Header = in.Header
-	m.Header.MsgID = 0x0102
+	m.Header = GenReplyHeader(in.Header, 0x0102)
 	m.AuthCode = in.AuthCode // 鉴权码就是8100返回的鉴权码
 	m.AuthCodeLen = uint8(len(m.AuthCode))
 
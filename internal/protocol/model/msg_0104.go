@@ -18,16 +18,12 @@ type Msg0104 struct {
 
 func (m *Msg0104) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.AnswerSerialNumber = hex.ReadWord(pkt, &idx)
-	m.AnswerParamCnt = hex.ReadByte(pkt, &idx)
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.AnswerParamCnt = r.ReadUint8("answerParamCnt")
 	m.Parameters = &DeviceParams{}
-	err := m.Parameters.Decode(m.Header.PhoneNumber, m.AnswerParamCnt, pkt[idx:])
-	if err != nil {
-		log.Error().Err(err).Str("device", m.Header.PhoneNumber).Msg("Fail to decode device params")
-		return ErrDecodeMsg
-	}
-	return nil
+	m.Parameters.Decode(m.Header.PhoneNumber, m.AnswerParamCnt, r)
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg0104) Encode() (pkt []byte, err error) {
@@ -49,13 +45,15 @@ func (m *Msg0104) GetHeader() *MsgHeader {
 }
 
 func (m *Msg0104) GenOutgoing(incoming JT808Msg) error {
-	in, ok := incoming.(*Msg8104)
-	if !ok {
+	// 查询终端参数和查询指定终端参数均以0x0104应答
+	switch incoming.(type) {
+	case *Msg8104, *Msg8106:
+	default:
 		return ErrGenOutgoingMsg
 	}
-	m.AnswerSerialNumber = in.Header.SerialNumber
-	m.Header = in.Header
-	m.Header.MsgID = 0x0104
+	in := incoming.GetHeader()
+	m.AnswerSerialNumber = in.SerialNumber
+	m.Header = GenReplyHeader(in, 0x0104)
 
 	return nil
 }
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 升级结果
+const (
+	UpgradeResultSuccess  uint8 = 0 // 成功
+	UpgradeResultFail     uint8 = 1 // 失败
+	UpgradeResultCanceled uint8 = 2 // 取消
+)
+
+func Msg0108Result2Str(res uint8) string {
+	switch res {
+	case UpgradeResultSuccess:
+		return "success"
+	case UpgradeResultFail:
+		return "failed"
+	case UpgradeResultCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// 0x0108 《8.18 终端升级结果通知》
+//
+// 终端升级完成并重新连接后发送，平台以通用应答回复
+type Msg0108 struct {
+	Header      *MsgHeader `json:"header"`
+	UpgradeType uint8      `json:"upgradeType"` // 升级类型，0:终端;12:道路运输证IC卡读卡器;52:北斗卫星定位模块
+	Result      uint8      `json:"result"`      // 升级结果，0:成功;1:失败;2:取消
+}
+
+func (m *Msg0108) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.UpgradeType = r.ReadUint8("upgradeType")
+	m.Result = r.ReadUint8("result")
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0108) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.UpgradeType)
+	pkt = hex.WriteByte(pkt, m.Result)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0108) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0108) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
@@ -231,27 +231,27 @@ func extraAiDSMDecode(data []byte) any {
 		return nil
 	}
 
-	idx := 0
+	r := hex.NewReader(data)
 	alarm := &AlarmMsg{
-		ID:    hex.ReadDoubleWord(data, &idx),
-		State: hex.ReadByte(data, &idx),
+		ID:    r.ReadDoubleWord("id"),
+		State: r.ReadUint8("state"),
 		//SeralNumber: "xxx",
 	}
 
 	dsm := &AlarmMsgDSM{
-		Type:         hex.ReadByte(data, &idx),
-		Level:        hex.ReadByte(data, &idx),
-		FatigueLevel: hex.ReadByte(data, &idx),
-		Reserve:      hex.ReadBytes(data, &idx, 4),
-		Speed:        hex.ReadByte(data, &idx),
-		Altitude:     hex.ReadWord(data, &idx),
-		Latitude:     hex.ReadDoubleWord(data, &idx),
-		Longitude:    hex.ReadDoubleWord(data, &idx),
-		Time:         hex.ReadBCD(data, &idx, 6),
-		//CarState:     hex.ReadByte(data, &idx),
+		Type:         r.ReadUint8("type"),
+		Level:        r.ReadUint8("level"),
+		FatigueLevel: r.ReadUint8("fatigueLevel"),
+		Reserve:      r.ReadBytes("reserve", 4),
+		Speed:        r.ReadUint8("speed"),
+		Altitude:     r.ReadWord("altitude"),
+		Latitude:     r.ReadDoubleWord("latitude"),
+		Longitude:    r.ReadDoubleWord("longitude"),
+		Time:         r.ReadBCD("time", 6),
+		//CarState:     r.ReadUint8("carState"),
 	}
 
-	state := hex.ReadWord(data, &idx)
+	state := r.ReadWord("carState")
 	dsm.CarState.ACC = cond((state&bv(0)) != 0, ON, OFF).(bool)
 	dsm.CarState.LeftLight = cond((state&bv(1)) != 0, ON, OFF).(bool)
 	dsm.CarState.RightLight = cond((state&bv(2)) != 0, ON, OFF).(bool)
@@ -261,7 +261,10 @@ func extraAiDSMDecode(data []byte) any {
 	dsm.CarState.Locate = cond((state&bv(10)) != 0, ON, OFF).(bool)
 
 	alarm.Detail = dsm
-	alarm.SeralNumber = hex.Byte2Str(hex.ReadBytes(data, &idx, 16))
+	alarm.SeralNumber = hex.Byte2Str(r.ReadBytes("serialNumber", 16))
+	if r.Err() != nil {
+		return nil
+	}
 
 	return alarm
 }
@@ -272,20 +275,23 @@ func extraAiDSMEncode(data []byte) any {
 
 func (m *Msg0200) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.AlarmSign = hex.ReadDoubleWord(pkt, &idx)
-	m.StatusSign = hex.ReadDoubleWord(pkt, &idx)
-	m.Latitude = hex.ReadDoubleWord(pkt, &idx)
-	m.Longitude = hex.ReadDoubleWord(pkt, &idx)
-	m.Altitude = hex.ReadWord(pkt, &idx)
-	m.Speed = hex.ReadWord(pkt, &idx)
-	m.Direction = hex.ReadWord(pkt, &idx)
-	m.Time = hex.ReadBCD(pkt, &idx, 6)
-
-	for idx < len(pkt) {
-		id := hex.ReadByte(pkt, &idx)
-		length := hex.ReadByte(pkt, &idx)
-		if id == 0 || (idx+int(length)) > int(m.Header.Attr.BodyLength) {
+	r := hex.NewReader(packet.Body)
+	m.AlarmSign = r.ReadDoubleWord("alarmSign")
+	m.StatusSign = r.ReadDoubleWord("statusSign")
+	m.Latitude = r.ReadDoubleWord("latitude")
+	m.Longitude = r.ReadDoubleWord("longitude")
+	m.Altitude = r.ReadWord("altitude")
+	m.Speed = r.ReadWord("speed")
+	m.Direction = r.ReadWord("direction")
+	m.Time = r.ReadBCD("time", 6)
+	if err := decodeErr(m.Header.MsgID, r); err != nil {
+		return err
+	}
+
+	for r.Len() >= 2 {
+		id := r.ReadUint8("extraId")
+		length := r.ReadUint8("extraLength")
+		if id == 0 || int(length) > r.Len() {
 			// id 无效
 			// 或者 数据已经越界，不要再继续解码了
 			break
@@ -293,12 +299,11 @@ func (m *Msg0200) Decode(packet *PacketData) error {
 		extra := Msg0200Extra{
 			Id:     id,
 			Length: length,
-			//Value: hex.ReadBytes(pkt, &idx, int(length)),
 		}
+		data := r.ReadBytes("extraValue", int(length))
 		fn := extraDecodeFunctions[id]
 		if fn != nil {
 			// 通过回调解析
-			data := hex.ReadBytes(pkt, &idx, int(length))
 			value := fn(data)
 			if value == nil {
 				// 当value为空，说明传入的参数不合法
@@ -314,14 +319,18 @@ func (m *Msg0200) Decode(packet *PacketData) error {
 		} else {
 			// 没有处理回调，
 			// 所以直接拿hex-buf
-			extra.Value = hex.ReadBytes(pkt, &idx, int(length))
+			extra.Value = data
 		}
 		m.Extra = append(m.Extra, extra)
 	}
-	return nil
+	return decodeErr(m.Header.MsgID, r)
 }
 
-func (m *Msg0200) Encode() (pkt []byte, err error) {
+// 位置基本信息的长度，不含附加信息
+const locationBasicLen = 28
+
+// 写入位置基本信息，其他消息中内嵌的位置信息也使用此格式
+func (m *Msg0200) encodeBasic(pkt []byte) []byte {
 	pkt = hex.WriteDoubleWord(pkt, m.AlarmSign)
 	pkt = hex.WriteDoubleWord(pkt, m.StatusSign)
 	pkt = hex.WriteDoubleWord(pkt, m.Latitude)
@@ -329,7 +338,13 @@ func (m *Msg0200) Encode() (pkt []byte, err error) {
 	pkt = hex.WriteWord(pkt, m.Altitude)
 	pkt = hex.WriteWord(pkt, m.Speed)
 	pkt = hex.WriteWord(pkt, m.Direction)
-	pkt = hex.WriteBCD(pkt, m.Time)
+	pkt = hex.WriteFixedBCD(pkt, m.Time, 6)
+	return pkt
+}
+
+// 写入位置基本信息和附加信息，批量上传等消息中内嵌的位置汇报也使用此格式
+func (m *Msg0200) encodeBody(pkt []byte) []byte {
+	pkt = m.encodeBasic(pkt)
 
 	for i := 0; i < len(m.Extra); i++ {
 		extra := m.Extra[i]
@@ -337,7 +352,11 @@ func (m *Msg0200) Encode() (pkt []byte, err error) {
 		pkt = hex.WriteByte(pkt, extra.Length)
 		pkt = hex.WriteBytes(pkt, extra.Value.([]byte))
 	}
+	return pkt
+}
 
+func (m *Msg0200) Encode() (pkt []byte, err error) {
+	pkt = m.encodeBody(pkt)
 	pkt, err = writeHeader(m, pkt)
 	return pkt, err
 }
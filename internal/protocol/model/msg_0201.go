@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x0201 《8.30 位置信息查询应答》
+type Msg0201 struct {
+	Header             *MsgHeader `json:"header"`
+	AnswerSerialNumber uint16     `json:"answerSerialNumber"` // 应答流水号，对应0x8201消息的流水号
+	Location           *Msg0200   `json:"location"`           // 位置信息汇报，格式同0x0200消息体
+}
+
+func (m *Msg0201) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	body := r.ReadBytes("location", r.Len())
+	if err := decodeErr(m.Header.MsgID, r); err != nil {
+		return err
+	}
+	m.Location = &Msg0200{}
+	return m.Location.Decode(&PacketData{Header: m.Header, Body: body})
+}
+
+func (m *Msg0201) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteWord(pkt, m.AnswerSerialNumber)
+	location := m.Location
+	if location == nil {
+		location = &Msg0200{}
+	}
+	pkt = location.encodeBody(pkt)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0201) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0201) GenOutgoing(incoming JT808Msg) error {
+	in, ok := incoming.(*Msg8201)
+	if !ok {
+		return ErrGenOutgoingMsg
+	}
+	m.AnswerSerialNumber = in.Header.SerialNumber
+	m.Header = GenReplyHeader(in.Header, 0x0201)
+	return nil
+}
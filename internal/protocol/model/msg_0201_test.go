@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg0201_EncodeDecode(t *testing.T) {
+	in := &Msg8201{Header: &MsgHeader{MsgID: 0x8201, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001", SerialNumber: 21}}
+	msg := &Msg0201{}
+	require.Nil(t, msg.GenOutgoing(in))
+	msg.Location = &Msg0200{
+		StatusSign: 3,
+		Latitude:   22543096,
+		Longitude:  114057865,
+		Time:       "230101120000",
+		Extra:      []Msg0200Extra{{Id: 0x01, Length: 4, Value: []byte{0x00, 0x00, 0x30, 0x39}}},
+	}
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+	require.Equal(t, 2+28+6, int(msg.Header.Attr.BodyLength))
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg0201{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	require.Equal(t, uint16(21), got.AnswerSerialNumber)
+	require.Equal(t, uint32(22543096), got.Location.Latitude)
+	require.Equal(t, "230101120000", got.Location.Time)
+	require.Len(t, got.Location.Extra, 1)
+
+	// 位置信息不完整
+	err = got.Decode(&PacketData{Header: header, Body: pkt[header.Idx : header.Idx+10]})
+	require.ErrorIs(t, err, ErrDecodeMsg)
+}
+
+func TestMsg8202_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval uint16
+		duration uint32
+		wantLen  int // 消息体长度
+	}{
+		{name: "case1: start tracking", interval: 5, duration: 300, wantLen: 6},
+		{name: "case2: stop tracking", interval: 0, wantLen: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8202{
+				Header:        &MsgHeader{MsgID: 0x8202, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+				Interval:      tt.interval,
+				ValidDuration: tt.duration,
+			}
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8202{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, tt.interval, got.Interval)
+			require.Equal(t, tt.duration, got.ValidDuration)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x0608 《8.53 查询区域或线路数据应答》
+//
+// 2019版本新增，数据项格式同对应设置消息的消息体，圆形和矩形区域每个数据项只含一个区域
+type Msg0608 struct {
+	Header     *MsgHeader       `json:"header"`
+	QueryType  uint8            `json:"queryType"`            // 查询类型，1:圆形;2:矩形;3:多边形;4:路线
+	Count      uint32           `json:"count"`                // 查询到的区域或线路数量
+	Circles    []*CircleArea    `json:"circles,omitempty"`    // 圆形区域
+	Rectangles []*RectangleArea `json:"rectangles,omitempty"` // 矩形区域
+	Polygons   []*PolygonArea   `json:"polygons,omitempty"`   // 多边形区域
+	Routes     []*RouteArea     `json:"routes,omitempty"`     // 路线
+}
+
+// 按顺序读取设置消息体，圆形和矩形消息体带设置属性和区域个数
+func (m *Msg0608) decodeItems(r *hex.Reader, ver VersionType) {
+	for n := 0; n < int(m.Count) && r.Len() > 0 && r.Err() == nil; {
+		switch m.QueryType {
+		case AreaTypeCircle, AreaTypeRectangle:
+			r.ReadUint8("action")
+			cnt := int(r.ReadUint8("areaCnt"))
+			for i := 0; i < cnt && r.Err() == nil; i++ {
+				if m.QueryType == AreaTypeCircle {
+					a := &CircleArea{}
+					a.decode(r, ver)
+					m.Circles = append(m.Circles, a)
+				} else {
+					a := &RectangleArea{}
+					a.decode(r, ver)
+					m.Rectangles = append(m.Rectangles, a)
+				}
+			}
+			n += cnt
+		case AreaTypePolygon:
+			a := &PolygonArea{}
+			a.decode(r, ver)
+			m.Polygons = append(m.Polygons, a)
+			n++
+		case AreaTypeRoute:
+			a := &RouteArea{}
+			a.decode(r, ver)
+			m.Routes = append(m.Routes, a)
+			n++
+		default:
+			return
+		}
+	}
+}
+
+func (m *Msg0608) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.QueryType = r.ReadUint8("queryType")
+	m.Count = r.ReadDoubleWord("count")
+	m.decodeItems(r, m.Header.Attr.VersionDesc)
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0608) Encode() (pkt []byte, err error) {
+	ver := m.Header.Attr.VersionDesc
+	pkt = hex.WriteByte(pkt, m.QueryType)
+	switch m.QueryType {
+	case AreaTypeCircle:
+		m.Count = uint32(len(m.Circles))
+		pkt = hex.WriteDoubleWord(pkt, m.Count)
+		for _, a := range m.Circles {
+			pkt = hex.WriteBytes(pkt, []byte{AreaActionUpdate, 1})
+			if pkt, err = a.encode(pkt, ver); err != nil {
+				return nil, err
+			}
+		}
+	case AreaTypeRectangle:
+		m.Count = uint32(len(m.Rectangles))
+		pkt = hex.WriteDoubleWord(pkt, m.Count)
+		for _, a := range m.Rectangles {
+			pkt = hex.WriteBytes(pkt, []byte{AreaActionUpdate, 1})
+			if pkt, err = a.encode(pkt, ver); err != nil {
+				return nil, err
+			}
+		}
+	case AreaTypePolygon:
+		m.Count = uint32(len(m.Polygons))
+		pkt = hex.WriteDoubleWord(pkt, m.Count)
+		for _, a := range m.Polygons {
+			if pkt, err = a.encode(pkt, ver); err != nil {
+				return nil, err
+			}
+		}
+	case AreaTypeRoute:
+		m.Count = uint32(len(m.Routes))
+		pkt = hex.WriteDoubleWord(pkt, m.Count)
+		for _, a := range m.Routes {
+			if pkt, err = a.encode(pkt, ver); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.Wrapf(ErrInvalidArea, "queryType=%d", m.QueryType)
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0608) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0608) GenOutgoing(incoming JT808Msg) error {
+	in, ok := incoming.(*Msg8608)
+	if !ok {
+		return ErrGenOutgoingMsg
+	}
+	m.Header = GenReplyHeader(in.Header, 0x0608)
+	m.QueryType = in.QueryType
+	return nil
+}
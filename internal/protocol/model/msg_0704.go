@@ -0,0 +1,63 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 位置数据类型
+const (
+	LocationBatchNormal    uint8 = 0 // 正常位置批量汇报
+	LocationBatchBlindZone uint8 = 1 // 盲区补报
+)
+
+// 0x0704 《8.39 定位数据批量上传》
+//
+// 终端在无网络覆盖期间缓存的位置数据，恢复连接后批量上传
+type Msg0704 struct {
+	Header    *MsgHeader `json:"header"`
+	Count     uint16     `json:"count"`     // 数据项个数
+	Type      uint8      `json:"type"`      // 位置数据类型，0:正常位置批量汇报;1:盲区补报
+	Locations []*Msg0200 `json:"locations"` // 位置汇报数据项，格式同0x0200消息体
+}
+
+func (m *Msg0704) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Count = r.ReadWord("count")
+	m.Type = r.ReadUint8("type")
+	m.Locations = make([]*Msg0200, 0)
+	for i := 0; i < int(m.Count) && r.Err() == nil; i++ {
+		length := r.ReadWord("locationLength")
+		body := r.ReadBytes("location", int(length))
+		if r.Err() != nil {
+			break
+		}
+		loc := &Msg0200{}
+		if err := loc.Decode(&PacketData{Header: m.Header, Body: body}); err != nil {
+			return err
+		}
+		m.Locations = append(m.Locations, loc)
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0704) Encode() (pkt []byte, err error) {
+	m.Count = uint16(len(m.Locations))
+	pkt = hex.WriteWord(pkt, m.Count)
+	pkt = hex.WriteByte(pkt, m.Type)
+	for _, loc := range m.Locations {
+		body := loc.encodeBody(nil)
+		pkt = hex.WriteWord(pkt, uint16(len(body)))
+		pkt = hex.WriteBytes(pkt, body)
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0704) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0704) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
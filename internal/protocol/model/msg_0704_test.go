@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg0704_EncodeDecode(t *testing.T) {
+	msg := &Msg0704{
+		Header: &MsgHeader{MsgID: 0x0704, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+		Type:   LocationBatchBlindZone,
+		Locations: []*Msg0200{
+			{Latitude: 22543096, Longitude: 114057865, Time: "230101120500"},
+			{
+				Latitude:  22543100,
+				Longitude: 114057870,
+				Time:      "230101120000",
+				Extra:     []Msg0200Extra{{Id: 0x01, Length: 4, Value: []byte{0x00, 0x00, 0x30, 0x39}}},
+			},
+		},
+	}
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+	require.Equal(t, 2+1+(2+28)+(2+28+6), int(msg.Header.Attr.BodyLength))
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg0704{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	require.Equal(t, uint16(2), got.Count)
+	require.Equal(t, LocationBatchBlindZone, got.Type)
+	require.Len(t, got.Locations, 2)
+	require.Equal(t, "230101120500", got.Locations[0].Time)
+	require.Equal(t, uint32(22543100), got.Locations[1].Latitude)
+	require.Len(t, got.Locations[1].Extra, 1)
+
+	// 数据项个数大于实际数据
+	body := pkt[header.Idx:]
+	truncated := append([]byte{0x00, 0x03}, body[2:]...)
+	err = got.Decode(&PacketData{Header: header, Body: truncated})
+	require.ErrorIs(t, err, ErrDecodeMsg)
+}
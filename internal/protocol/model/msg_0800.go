@@ -1,16 +1,38 @@
 package model
 
-// todo: 多媒体事件消息上传
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 多媒体事件信息上传
+//
+// 终端因特定事件拍摄或录制多媒体数据后上传，平台以通用应答回复
 type Msg0800 struct {
-	Header *MsgHeader `json:"header"`
+	Header              *MsgHeader `json:"header"`
+	MultiMediaID        uint32     `json:"multiMediaId"`
+	MultiMediaType      uint8      `json:"multiMediaType"`      // 多媒体类型。0:图像;1:音频;2:视频;
+	MultiMediaContainer uint8      `json:"multiMediaContainer"` // 多媒体格式编码。0:JPEG;1:TIF;2:MP3;3:WAV;4:WMV; 其他保留
+	EventID             uint8      `json:"eventId"`             // 事件项编码
+	LogicChannelID      uint8      `json:"logicChannelId"`      // 逻辑通道ID
 }
 
 func (m *Msg0800) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.MultiMediaID = r.ReadDoubleWord("multiMediaId")
+	m.MultiMediaType = r.ReadUint8("multiMediaType")
+	m.MultiMediaContainer = r.ReadUint8("multiMediaContainer")
+	m.EventID = r.ReadUint8("eventId")
+	m.LogicChannelID = r.ReadUint8("logicChannelId")
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg0800) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteDoubleWord(pkt, m.MultiMediaID)
+	pkt = hex.WriteByte(pkt, m.MultiMediaType)
+	pkt = hex.WriteByte(pkt, m.MultiMediaContainer)
+	pkt = hex.WriteByte(pkt, m.EventID)
+	pkt = hex.WriteByte(pkt, m.LogicChannelID)
 	pkt, err = writeHeader(m, pkt)
 	return pkt, err
 }
@@ -19,6 +41,6 @@ func (m *Msg0800) GetHeader() *MsgHeader {
 	return m.Header
 }
 
-func (m *Msg0800) GenOutgoing(incoming JT808Msg) error {
+func (m *Msg0800) GenOutgoing(_ JT808Msg) error {
 	return nil
 }
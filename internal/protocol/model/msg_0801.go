@@ -1,7 +1,37 @@
 package model
 
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 多媒体类型
+const (
+	MediaTypeImage uint8 = 0
+	MediaTypeAudio uint8 = 1
+	MediaTypeVideo uint8 = 2
+)
+
+// MediaFormatExt 多媒体格式编码对应的文件扩展名
+func MediaFormatExt(format uint8) string {
+	switch format {
+	case 0:
+		return "jpg"
+	case 1:
+		return "tif"
+	case 2:
+		return "mp3"
+	case 3:
+		return "wav"
+	case 4:
+		return "wmv"
+	}
+	return "bin"
+}
+
 // 多媒体数据上传
 // 与JT1078合用时，此消息只上传图片数据
+//
+// 多媒体数据较大时分包上传，分包组装完成后再解析
 type Msg0801 struct {
 	Header              *MsgHeader `json:"header"`
 	MultiMediaID        uint32     `json:"multiMediaId"`
@@ -9,16 +39,43 @@ type Msg0801 struct {
 	MultiMediaContainer uint8      `json:"multiMediaContainer"` // 多媒体格式编码。0:JPEG;1:TIF;2:MP3;3:WAV;4:WMV; 其他保留
 	EventID             uint8      `json:"eventId"`             // 事件项编码。0:平台下发指令;1:定时动作;2:抢劫报警触 发;3:碰撞侧翻报警触发;其他保留
 	LogicChannelID      uint8      `json:"logicChannelId"`      // 逻辑通道ID
-	GeoAlarmBody        []byte     `json:"geoAlarmBody"`        // 位置信息汇报消息体
-	FragmentData        []byte     `json:"fragmentData"`        // 多媒体数据包
+	Location            *Msg0200   `json:"location"`            // 位置信息汇报消息体，只包含位置基本信息
+	FragmentData        []byte     `json:"-"`                   // 多媒体数据包
 }
 
 func (m *Msg0801) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.MultiMediaID = r.ReadDoubleWord("multiMediaId")
+	m.MultiMediaType = r.ReadUint8("multiMediaType")
+	m.MultiMediaContainer = r.ReadUint8("multiMediaContainer")
+	m.EventID = r.ReadUint8("eventId")
+	m.LogicChannelID = r.ReadUint8("logicChannelId")
+	geo := r.ReadBytes("location", locationBasicLen)
+	if err := decodeErr(m.Header.MsgID, r); err != nil {
+		return err
+	}
+	m.Location = &Msg0200{}
+	if err := m.Location.Decode(&PacketData{Header: m.Header, Body: geo}); err != nil {
+		return err
+	}
+	m.FragmentData = r.ReadBytes("fragmentData", r.Len())
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg0801) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteDoubleWord(pkt, m.MultiMediaID)
+	pkt = hex.WriteByte(pkt, m.MultiMediaType)
+	pkt = hex.WriteByte(pkt, m.MultiMediaContainer)
+	pkt = hex.WriteByte(pkt, m.EventID)
+	pkt = hex.WriteByte(pkt, m.LogicChannelID)
+	location := m.Location
+	if location == nil {
+		location = &Msg0200{}
+	}
+	pkt = location.encodeBasic(pkt)
+	pkt = hex.WriteBytes(pkt, m.FragmentData)
+
 	pkt, err = writeHeader(m, pkt)
 	return pkt, err
 }
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg0801_EncodeDecode(t *testing.T) {
+	msg := &Msg0801{
+		Header:              &MsgHeader{MsgID: 0x0801, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001", SerialNumber: 9},
+		MultiMediaID:        0x00030001,
+		MultiMediaType:      MediaTypeImage,
+		MultiMediaContainer: 0,
+		EventID:             0,
+		LogicChannelID:      1,
+		Location: &Msg0200{
+			StatusSign: 3,
+			Latitude:   22543096,
+			Longitude:  114057865,
+			Speed:      600,
+			Time:       "230101120000",
+		},
+		FragmentData: []byte{0xff, 0xd8, 0xff, 0xe0, 0xff, 0xd9},
+	}
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+	require.Equal(t, 4+4+locationBasicLen+6, int(msg.Header.Attr.BodyLength))
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg0801{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	require.Equal(t, uint32(0x00030001), got.MultiMediaID)
+	require.Equal(t, uint8(1), got.LogicChannelID)
+	require.Equal(t, uint32(22543096), got.Location.Latitude)
+	require.Equal(t, uint32(114057865), got.Location.Longitude)
+	require.Equal(t, "230101120000", got.Location.Time)
+	require.Empty(t, got.Location.Extra)
+	require.Equal(t, msg.FragmentData, got.FragmentData)
+
+	// 位置信息不完整
+	err = got.Decode(&PacketData{Header: header, Body: pkt[header.Idx : header.Idx+20]})
+	require.ErrorIs(t, err, ErrDecodeMsg)
+}
+
+func TestMsg8800_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing []uint16
+	}{
+		{name: "case1: all received"},
+		{name: "case2: retransmit", missing: []uint16{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Msg0801{
+				Header:       &MsgHeader{MsgID: 0x0801, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+				MultiMediaID: 7,
+			}
+			msg := &Msg8800{}
+			require.Nil(t, msg.GenOutgoing(in))
+			msg.RetransmitIDs = tt.missing
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, 4+1+2*len(tt.missing), int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8800{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, uint32(7), got.MultiMediaID)
+			require.Equal(t, uint8(len(tt.missing)), got.RetransmitCount)
+			require.ElementsMatch(t, tt.missing, got.RetransmitIDs)
+		})
+	}
+}
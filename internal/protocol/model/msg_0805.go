@@ -0,0 +1,72 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x0805 《8.54 摄像头立即拍摄命令应答》
+type Msg0805 struct {
+	Header             *MsgHeader `json:"header"`
+	AnswerSerialNumber uint16     `json:"answerSerialNumber"` // 应答流水号，对应0x8801消息的流水号
+	Result             uint8      `json:"result"`             // 结果，0成功，1失败，2通道不支持
+	MediaCount         uint16     `json:"mediaCount"`         // 多媒体ID个数，结果为0时有效
+	MediaIDs           []uint32   `json:"mediaIds"`           // 多媒体ID列表，结果为0时有效
+}
+
+func Msg0805Result2Str(res uint8) string {
+	switch res {
+	case 0:
+		return "success"
+	case 1:
+		return "failed"
+	case 2:
+		return "channel unsupported"
+	}
+	return "unknown"
+}
+
+func (m *Msg0805) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.Result = r.ReadUint8("result")
+	// 拍摄失败时终端可能不携带ID列表
+	if m.Result == 0 && r.Len() > 0 {
+		m.MediaCount = r.ReadWord("mediaCount")
+		if r.Require("mediaIds", int(m.MediaCount)*4) {
+			m.MediaIDs = make([]uint32, 0, m.MediaCount)
+			for i := 0; i < int(m.MediaCount); i++ {
+				m.MediaIDs = append(m.MediaIDs, r.ReadDoubleWord("mediaId"))
+			}
+		}
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0805) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteWord(pkt, m.AnswerSerialNumber)
+	pkt = hex.WriteByte(pkt, m.Result)
+	if m.Result == 0 {
+		m.MediaCount = uint16(len(m.MediaIDs))
+		pkt = hex.WriteWord(pkt, m.MediaCount)
+		for _, id := range m.MediaIDs {
+			pkt = hex.WriteDoubleWord(pkt, id)
+		}
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0805) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0805) GenOutgoing(incoming JT808Msg) error {
+	in, ok := incoming.(*Msg8801)
+	if !ok {
+		return ErrGenOutgoingMsg
+	}
+	m.AnswerSerialNumber = in.Header.SerialNumber
+	m.Header = GenReplyHeader(in.Header, 0x0805)
+	return nil
+}
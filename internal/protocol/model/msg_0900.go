@@ -1,3 +1,74 @@
 package model
 
+import (
+	"time"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 透传消息类型
+const (
+	TransparentGNSS       uint8 = 0x00 // GNSS模块详细定位数据
+	TransparentICCard     uint8 = 0x0B // 道路运输证IC卡信息
+	TransparentSerial1    uint8 = 0x41 // 串口1透传
+	TransparentSerial2    uint8 = 0x42 // 串口2透传
+	TransparentUserDefMin uint8 = 0xF0 // 用户自定义透传，0xF0-0xFF
+)
+
+// TransparentTypeName 透传消息类型名称
+func TransparentTypeName(msgType uint8) string {
+	switch {
+	case msgType == TransparentGNSS:
+		return "gnss"
+	case msgType == TransparentICCard:
+		return "icCard"
+	case msgType == TransparentSerial1:
+		return "serial1"
+	case msgType == TransparentSerial2:
+		return "serial2"
+	case msgType >= TransparentUserDefMin:
+		return "userDefined"
+	}
+	return "unknown"
+}
+
 // 0x0900 《8.61 数据上行透传》
+type Msg0900 struct {
+	Header  *MsgHeader `json:"header"`
+	MsgType uint8      `json:"msgType"` // 透传消息类型
+	Data    []byte     `json:"data"`    // 透传消息内容
+}
+
+func (m *Msg0900) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.MsgType = r.ReadUint8("msgType")
+	m.Data = r.ReadBytes("data", r.Len())
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0900) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.MsgType)
+	pkt = hex.WriteBytes(pkt, m.Data)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0900) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0900) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
+
+// 上行透传数据解析后的事件
+type TransparentEvent struct {
+	Phone      string    `json:"phone"`
+	MsgType    uint8     `json:"msgType"`
+	TypeName   string    `json:"typeName"`
+	Payload    any       `json:"payload,omitempty"` // 注册的解析器输出的结构化数据
+	Raw        string    `json:"raw"`               // 原始数据hex
+	Error      string    `json:"error,omitempty"`   // 解析失败的原因
+	ReceivedAt time.Time `json:"receivedAt"`
+}
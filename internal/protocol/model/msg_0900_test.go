@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg0900_EncodeDecode(t *testing.T) {
+	up := &Msg0900{
+		Header:  &MsgHeader{MsgID: 0x0900, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+		MsgType: TransparentSerial1,
+		Data:    []byte("hello"),
+	}
+	pkt, err := up.Encode()
+	require.Nil(t, err)
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg0900{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	require.Equal(t, TransparentSerial1, got.MsgType)
+	require.Equal(t, []byte("hello"), got.Data)
+
+	down := &Msg8900{
+		Header:  &MsgHeader{MsgID: 0x8900, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+		MsgType: 0xF1,
+		Data:    []byte{0x01, 0x02},
+	}
+	pkt, err = down.Encode()
+	require.Nil(t, err)
+	require.Nil(t, header.Decode(pkt))
+	got8900 := &Msg8900{}
+	require.Nil(t, got8900.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	require.Equal(t, uint8(0xF1), got8900.MsgType)
+	require.Equal(t, []byte{0x01, 0x02}, got8900.Data)
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"crypto/rsa"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+	jtrsa "github.com/fakeyanss/jt808-server-go/internal/codec/rsa"
+)
+
+// 终端RSA公钥，平台收到后回复平台RSA公钥
+type Msg0A00 struct {
+	Header *MsgHeader `json:"header"`
+	E      uint32     `json:"e"` // RSA公钥{e,n}中的e
+	N      []byte     `json:"n"` // RSA公钥{e,n}中的n，128字节
+}
+
+func (m *Msg0A00) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.E = r.ReadDoubleWord("e")
+	m.N = r.ReadBytes("n", jtrsa.ModulusLen)
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg0A00) Encode() (pkt []byte, err error) {
+	if len(m.N) != jtrsa.ModulusLen {
+		return nil, ErrEncodeMsg
+	}
+	pkt = hex.WriteDoubleWord(pkt, m.E)
+	pkt = hex.WriteBytes(pkt, m.N)
+
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg0A00) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg0A00) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
+
+// PublicKey 消息中的RSA公钥
+func (m *Msg0A00) PublicKey() *rsa.PublicKey {
+	return jtrsa.PublicKey(m.E, m.N)
+}
+
+// SetPublicKey 设置消息中的RSA公钥
+func (m *Msg0A00) SetPublicKey(pub *rsa.PublicKey) {
+	m.E = uint32(pub.E)
+	m.N = jtrsa.Modulus(pub)
+}
@@ -16,11 +16,11 @@ type Msg1205 struct {
 
 func (m *Msg1205) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.AnswerSerialNumber = hex.ReadWord(pkt, &idx)
-	m.MediaCount = hex.ReadDoubleWord(pkt, &idx)
-	m.DeviceMedia.Decode(pkt[idx:])
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.MediaCount = r.ReadDoubleWord("mediaCount")
+	m.DeviceMedia.Decode(r)
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg1205) Encode() (pkt []byte, err error) {
@@ -42,8 +42,7 @@ func (m *Msg1205) GenOutgoing(incoming JT808Msg) error {
 		return ErrGenOutgoingMsg
 	}
 	m.AnswerSerialNumber = in.Header.SerialNumber
-	m.Header = in.Header
-	m.Header.MsgID = 0x1205
+	m.Header = GenReplyHeader(in.Header, 0x1205)
 
 	return nil
 }
@@ -23,10 +23,10 @@ type Msg8001 struct {
 
 func (m *Msg8001) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.AnswerSerialNumber = hex.ReadWord(pkt, &idx)
-	m.AnswerMessageID = hex.ReadWord(pkt, &idx)
-	m.Result = ResultCode(hex.ReadByte(pkt, &idx))
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.AnswerMessageID = r.ReadWord("answerMessageId")
+	m.Result = ResultCode(r.ReadUint8("result"))
 
 	return nil
 }
@@ -50,8 +50,7 @@ func (m *Msg8001) GenOutgoing(incoming JT808Msg) error {
 	m.AnswerMessageID = header.MsgID
 	m.Result = 0
 
-	m.Header = header
-	m.Header.MsgID = 0x8001
+	m.Header = GenReplyHeader(header, 0x8001)
 
 	return nil
 }
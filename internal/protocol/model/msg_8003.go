@@ -0,0 +1,53 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 服务器补传分包请求，请求终端重新上传指定的分包
+type Msg8003 struct {
+	Header               *MsgHeader `json:"header"`
+	OriginalSerialNumber uint16     `json:"originalSerialNumber"` // 对应要求补传的原始消息第一包的流水号
+	RetransmitCnt        uint16     `json:"retransmitCnt"`        // 重传包总数，2013版本为BYTE，2019版本为WORD
+	RetransmitIDs        []uint16   `json:"retransmitIds"`        // 重传包序号列表
+}
+
+func (m *Msg8003) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.OriginalSerialNumber = r.ReadWord("originalSerialNumber")
+	if m.Header.Attr.VersionDesc == Version2019 {
+		m.RetransmitCnt = r.ReadWord("retransmitCnt")
+	} else {
+		m.RetransmitCnt = uint16(r.ReadUint8("retransmitCnt"))
+	}
+	m.RetransmitIDs = readWords(r, "retransmitIds", int(m.RetransmitCnt))
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8003) Encode() (pkt []byte, err error) {
+	m.RetransmitCnt = uint16(len(m.RetransmitIDs))
+	pkt = hex.WriteWord(pkt, m.OriginalSerialNumber)
+	if m.Header.Attr.VersionDesc == Version2019 {
+		pkt = hex.WriteWord(pkt, m.RetransmitCnt)
+	} else {
+		if m.RetransmitCnt > 0xFF {
+			return nil, ErrEncodeMsg
+		}
+		pkt = hex.WriteByte(pkt, uint8(m.RetransmitCnt))
+	}
+	for _, id := range m.RetransmitIDs {
+		pkt = hex.WriteWord(pkt, id)
+	}
+
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8003) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8003) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg8003_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    *MsgBodyAttr
+		phone   string
+		wantLen int // 消息体长度
+	}{
+		{
+			name:    "case1: 2013 count is BYTE",
+			attr:    &MsgBodyAttr{VersionDesc: Version2013},
+			phone:   "013800000001",
+			wantLen: 2 + 1 + 2*2,
+		},
+		{
+			name:    "case2: 2019 count is WORD",
+			attr:    &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019},
+			phone:   "00000000013800000001",
+			wantLen: 2 + 2 + 2*2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8003{
+				Header:               &MsgHeader{MsgID: 0x8003, Attr: tt.attr, PhoneNumber: tt.phone},
+				OriginalSerialNumber: 7,
+				RetransmitIDs:        []uint16{2, 5},
+			}
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8003{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, uint16(7), got.OriginalSerialNumber)
+			require.Equal(t, []uint16{2, 5}, got.RetransmitIDs)
+		})
+	}
+}
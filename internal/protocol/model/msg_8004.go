@@ -14,9 +14,9 @@ type Msg8004 struct {
 
 func (m *Msg8004) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.ServerTime = hex.ReadTime(pkt, &idx)
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.ServerTime = r.ReadTime("serverTime")
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg8004) Encode() (pkt []byte, err error) {
@@ -35,8 +35,7 @@ func (m *Msg8004) GenOutgoing(incoming JT808Msg) error {
 	now := time.Now()
 	m.ServerTime = &now
 
-	m.Header = in.Header
-	m.Header.MsgID = MsgID8004
+	m.Header = GenReplyHeader(in.Header, MsgID8004)
 
 	return nil
 }
@@ -24,11 +24,11 @@ type Msg8100 struct {
 
 func (m *Msg8100) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.AnswerSerialNumber = hex.ReadWord(pkt, &idx)
-	m.Result = ResultCodeType(hex.ReadByte(pkt, &idx))
-	m.AuthCode = hex.ReadString(pkt, &idx, int(m.Header.Attr.BodyLength)-idx)
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.AnswerSerialNumber = r.ReadWord("answerSerialNumber")
+	m.Result = ResultCodeType(r.ReadUint8("result"))
+	m.AuthCode = r.ReadString("authCode", r.Len())
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg8100) Encode() (pkt []byte, err error) {
@@ -53,8 +53,7 @@ func (m *Msg8100) GenOutgoing(incoming JT808Msg) error {
 	m.Result = 0
 	m.AuthCode = "AuthCode" // 初始值，在后续处理中根据id重写
 
-	m.Header = in.Header
-	m.Header.MsgID = 0x8100
+	m.Header = GenReplyHeader(in.Header, 0x8100)
 
 	return nil
 }
@@ -16,15 +16,11 @@ type Msg8103 struct {
 // client接收到消息，解析为结构体
 func (m *Msg8103) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.ParamCnt = hex.ReadByte(pkt, &idx)
+	r := hex.NewReader(packet.Body)
+	m.ParamCnt = r.ReadUint8("paramCnt")
 	m.Parameters = &DeviceParams{}
-	err := m.Parameters.Decode(m.Header.PhoneNumber, m.ParamCnt, pkt[idx:])
-	if err != nil {
-		log.Error().Err(err).Str("device", m.Header.PhoneNumber).Msg("Fail to decode device params")
-		return ErrDecodeMsg
-	}
-	return nil
+	m.Parameters.Decode(m.Header.PhoneNumber, m.ParamCnt, r)
+	return decodeErr(m.Header.MsgID, r)
 }
 
 // server端发送8103消息，编码为字节数组
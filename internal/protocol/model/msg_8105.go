@@ -0,0 +1,244 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/gbk"
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+var ErrInvalidControlParams = errors.New("Invalid terminal control params")
+
+type TerminalCommand uint8
+
+// 终端控制命令字
+const (
+	TerminalCommandUpgrade       TerminalCommand = 1 // 无线升级
+	TerminalCommandConnectServer TerminalCommand = 2 // 控制终端连接指定服务器
+	TerminalCommandPowerOff      TerminalCommand = 3 // 终端关机
+	TerminalCommandReset         TerminalCommand = 4 // 终端复位
+	TerminalCommandFactoryReset  TerminalCommand = 5 // 终端恢复出厂设置
+	TerminalCommandCloseDataComm TerminalCommand = 6 // 关闭数据通信
+	TerminalCommandCloseWireless TerminalCommand = 7 // 关闭所有无线通信
+)
+
+func TerminalCommand2Str(cmd TerminalCommand) string {
+	switch cmd {
+	case TerminalCommandUpgrade:
+		return "upgrade"
+	case TerminalCommandConnectServer:
+		return "connect server"
+	case TerminalCommandPowerOff:
+		return "power off"
+	case TerminalCommandReset:
+		return "reset"
+	case TerminalCommandFactoryReset:
+		return "factory reset"
+	case TerminalCommandCloseDataComm:
+		return "close data communication"
+	case TerminalCommandCloseWireless:
+		return "close wireless communication"
+	}
+	return "unknown"
+}
+
+// 连接控制
+const (
+	ConnectSpecifiedServer uint8 = 0 // 切换到指定监管平台服务器
+	ConnectDefaultServer   uint8 = 1 // 切换回原缺省监控平台服务器，并恢复正常状态
+)
+
+const connectServerParamCnt = 9
+
+// ConnectServerParams 控制终端连接指定服务器的命令参数，以半角分号分隔
+type ConnectServerParams struct {
+	ConnectionControl uint8  `json:"connectionControl"` // 连接控制，0:切换到指定监管平台服务器;1:切换回原缺省监控平台服务器，此时无后继参数
+	AuthCode          string `json:"authCode"`          // 监管平台鉴权码
+	APN               string `json:"apn"`               // 拨号点名称
+	DialUser          string `json:"dialUser"`          // 拨号用户名
+	DialPassword      string `json:"dialPassword"`      // 拨号密码
+	Address           string `json:"address"`           // 服务器地址，IP或域名
+	TCPPort           uint16 `json:"tcpPort"`           // TCP端口
+	UDPPort           uint16 `json:"udpPort"`           // UDP端口
+	TimeLimit         uint16 `json:"timeLimit"`         // 连接到指定服务器时限，单位分钟，超时未连接成功时连回原服务器
+}
+
+// Validate 切换到指定服务器时，地址和端口必填
+func (p *ConnectServerParams) Validate() error {
+	switch p.ConnectionControl {
+	case ConnectDefaultServer:
+		return nil
+	case ConnectSpecifiedServer:
+		if p.Address == "" {
+			return errors.Wrap(ErrInvalidControlParams, "address is empty")
+		}
+		if p.TCPPort == 0 && p.UDPPort == 0 {
+			return errors.Wrap(ErrInvalidControlParams, "port is empty")
+		}
+		if strings.Contains(p.AuthCode+p.APN+p.DialUser+p.DialPassword+p.Address, ";") {
+			return errors.Wrap(ErrInvalidControlParams, "param contains ';'")
+		}
+		return nil
+	}
+	return errors.Wrapf(ErrInvalidControlParams, "connectionControl=%d", p.ConnectionControl)
+}
+
+func (p *ConnectServerParams) String() string {
+	if p.ConnectionControl == ConnectDefaultServer {
+		return strconv.Itoa(int(p.ConnectionControl))
+	}
+	return strings.Join([]string{
+		strconv.Itoa(int(p.ConnectionControl)),
+		p.AuthCode,
+		p.APN,
+		p.DialUser,
+		p.DialPassword,
+		p.Address,
+		strconv.Itoa(int(p.TCPPort)),
+		strconv.Itoa(int(p.UDPPort)),
+		strconv.Itoa(int(p.TimeLimit)),
+	}, ";")
+}
+
+// ParseConnectServerParams 解析以分号分隔的连接指定服务器参数
+func ParseConnectServerParams(s string) (*ConnectServerParams, error) {
+	fields := strings.Split(s, ";")
+	ctrl, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidControlParams, "connectionControl=%s", fields[0])
+	}
+	p := &ConnectServerParams{ConnectionControl: uint8(ctrl)}
+	if p.ConnectionControl == ConnectDefaultServer {
+		return p, nil
+	}
+	if len(fields) != connectServerParamCnt {
+		return nil, errors.Wrapf(ErrInvalidControlParams, "param count=%d", len(fields))
+	}
+	p.AuthCode, p.APN, p.DialUser, p.DialPassword, p.Address = fields[1], fields[2], fields[3], fields[4], fields[5]
+	ports := make([]uint16, 0, 3)
+	for _, f := range fields[6:] {
+		n := uint64(0)
+		if f != "" {
+			n, err = strconv.ParseUint(f, 10, 16)
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidControlParams, "field=%s", f)
+			}
+		}
+		ports = append(ports, uint16(n))
+	}
+	p.TCPPort, p.UDPPort, p.TimeLimit = ports[0], ports[1], ports[2]
+	return p, p.Validate()
+}
+
+// TCPAddr TCP连接地址
+func (p *ConnectServerParams) TCPAddr() string {
+	return fmt.Sprintf("%s:%d", p.Address, p.TCPPort)
+}
+
+// 0x8105 《8.15 终端控制》
+//
+// 终端以通用应答回复，命令参数各字段之间采用半角分号分隔
+type Msg8105 struct {
+	Header  *MsgHeader      `json:"header"`
+	Command TerminalCommand `json:"command"` // 命令字
+	Params  string          `json:"params"`  // 命令参数，仅命令字1和2有参数
+}
+
+// NewMsg8105 按命令字生成终端控制消息，仅命令字2使用连接参数，无线升级不在此处下发
+func NewMsg8105(cmd TerminalCommand, p *ConnectServerParams) (*Msg8105, error) {
+	switch cmd {
+	case TerminalCommandConnectServer:
+		if p == nil {
+			return nil, errors.Wrap(ErrInvalidControlParams, "connect params is empty")
+		}
+		return NewMsg8105ConnectServer(p)
+	case TerminalCommandPowerOff:
+		return NewMsg8105PowerOff(), nil
+	case TerminalCommandReset:
+		return NewMsg8105Reset(), nil
+	case TerminalCommandFactoryReset:
+		return NewMsg8105FactoryReset(), nil
+	case TerminalCommandCloseDataComm:
+		return NewMsg8105CloseDataComm(), nil
+	case TerminalCommandCloseWireless:
+		return NewMsg8105CloseWireless(), nil
+	}
+	return nil, errors.Wrapf(ErrInvalidControlParams, "command=%d", cmd)
+}
+
+// NewMsg8105ConnectServer 控制终端连接指定服务器
+func NewMsg8105ConnectServer(p *ConnectServerParams) (*Msg8105, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &Msg8105{Command: TerminalCommandConnectServer, Params: p.String()}, nil
+}
+
+// NewMsg8105PowerOff 终端关机
+func NewMsg8105PowerOff() *Msg8105 {
+	return &Msg8105{Command: TerminalCommandPowerOff}
+}
+
+// NewMsg8105Reset 终端复位
+func NewMsg8105Reset() *Msg8105 {
+	return &Msg8105{Command: TerminalCommandReset}
+}
+
+// NewMsg8105FactoryReset 终端恢复出厂设置
+func NewMsg8105FactoryReset() *Msg8105 {
+	return &Msg8105{Command: TerminalCommandFactoryReset}
+}
+
+// NewMsg8105CloseDataComm 关闭数据通信
+func NewMsg8105CloseDataComm() *Msg8105 {
+	return &Msg8105{Command: TerminalCommandCloseDataComm}
+}
+
+// NewMsg8105CloseWireless 关闭所有无线通信
+func NewMsg8105CloseWireless() *Msg8105 {
+	return &Msg8105{Command: TerminalCommandCloseWireless}
+}
+
+// ConnectServerParams 解析命令字2的参数
+func (m *Msg8105) ConnectServerParams() (*ConnectServerParams, error) {
+	if m.Command != TerminalCommandConnectServer {
+		return nil, errors.Wrapf(ErrInvalidControlParams, "command=%d", m.Command)
+	}
+	return ParseConnectServerParams(m.Params)
+}
+
+func (m *Msg8105) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Command = TerminalCommand(r.ReadUint8("command"))
+	m.Params = r.ReadGBK("params", r.Len())
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8105) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, byte(m.Command))
+	if m.Params != "" {
+		params, err := gbk.UTF82GBK([]byte(m.Params))
+		if err != nil {
+			log.Error().Err(err).Str("device", m.Header.PhoneNumber).Msg("Fail to encode control params to gbk")
+			return nil, ErrEncodeMsg
+		}
+		pkt = hex.WriteBytes(pkt, params)
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8105) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8105) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
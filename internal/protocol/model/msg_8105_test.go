@@ -0,0 +1,87 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg8105_EncodeDecode(t *testing.T) {
+	connect, err := NewMsg8105ConnectServer(&ConnectServerParams{
+		AuthCode:  "鉴权码",
+		APN:       "CMNET",
+		Address:   "10.0.0.1",
+		TCPPort:   1983,
+		TimeLimit: 5,
+	})
+	require.Nil(t, err)
+	tests := []struct {
+		name    string
+		msg     *Msg8105
+		wantLen int // 消息体长度
+	}{
+		{name: "case1: reset without params", msg: NewMsg8105Reset(), wantLen: 1},
+		{name: "case2: connect server with gbk params", msg: connect, wantLen: 1 + len("0;;CMNET;;;10.0.0.1;1983;0;5") + 6},
+		{name: "case3: back to default server", msg: &Msg8105{Command: TerminalCommandConnectServer, Params: "1"}, wantLen: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.Header = &MsgHeader{MsgID: 0x8105, Attr: &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019}, PhoneNumber: "00000000013800000001"}
+			pkt, err := tt.msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(tt.msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8105{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, tt.msg.Command, got.Command)
+			require.Equal(t, tt.msg.Params, got.Params)
+		})
+	}
+}
+
+func TestParseConnectServerParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    *ConnectServerParams
+		wantErr bool
+	}{
+		{
+			name:   "case1: specified server",
+			params: "0;auth;CMNET;user;pwd;jt808.example.com;1983;1984;10",
+			want: &ConnectServerParams{AuthCode: "auth", APN: "CMNET", DialUser: "user", DialPassword: "pwd",
+				Address: "jt808.example.com", TCPPort: 1983, UDPPort: 1984, TimeLimit: 10},
+		},
+		{name: "case2: default server", params: "1", want: &ConnectServerParams{ConnectionControl: ConnectDefaultServer}},
+		{name: "case3: empty udp port", params: "0;;;;;10.0.0.1;1983;;0", want: &ConnectServerParams{Address: "10.0.0.1", TCPPort: 1983}},
+		{name: "case4: missing fields", params: "0;auth;10.0.0.1;1983", wantErr: true},
+		{name: "case5: invalid port", params: "0;;;;;10.0.0.1;70000;0;0", wantErr: true},
+		{name: "case6: empty address", params: "0;;;;;;1983;0;0", wantErr: true},
+		{name: "case7: invalid control", params: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConnectServerParams(tt.params)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidControlParams)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewMsg8105(t *testing.T) {
+	_, err := NewMsg8105(TerminalCommandConnectServer, nil)
+	require.ErrorIs(t, err, ErrInvalidControlParams)
+	_, err = NewMsg8105(TerminalCommandUpgrade, nil)
+	require.ErrorIs(t, err, ErrInvalidControlParams)
+
+	msg, err := NewMsg8105(TerminalCommandFactoryReset, nil)
+	require.Nil(t, err)
+	require.Equal(t, TerminalCommandFactoryReset, msg.Command)
+	require.Empty(t, msg.Params)
+}
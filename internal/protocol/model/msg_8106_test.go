@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg8106_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []uint32
+	}{
+		{name: "case1: single id", ids: []uint32{0x0001}},
+		{name: "case2: multiple ids", ids: []uint32{0x0001, 0x0029, 0xF364}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8106{
+				Header:   &MsgHeader{MsgID: 0x8106, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+				ParamIDs: tt.ids,
+			}
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, 1+4*len(tt.ids), int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8106{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, uint8(len(tt.ids)), got.ParamCnt)
+			require.Equal(t, tt.ids, got.ParamIDs)
+		})
+	}
+
+	// 参数个数与消息体长度不符
+	got := &Msg8106{}
+	err := got.Decode(&PacketData{Header: &MsgHeader{MsgID: 0x8106}, Body: []byte{0x02, 0x00, 0x00, 0x00, 0x01}})
+	require.ErrorIs(t, err, ErrDecodeMsg)
+}
+
+func TestMsg0104_GenOutgoing8106(t *testing.T) {
+	in := &Msg8106{
+		Header:   &MsgHeader{MsgID: 0x8106, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001", SerialNumber: 12},
+		ParamIDs: []uint32{0x0029, 0x0001},
+	}
+	out := &Msg0104{}
+	require.Nil(t, out.GenOutgoing(in))
+	require.Equal(t, uint16(12), out.AnswerSerialNumber)
+	require.Equal(t, uint16(0x0104), out.Header.MsgID)
+
+	params := &DeviceParams{Params: []*ParamData{
+		{ParamID: 0x0001, ParamValue: uint32(10)},
+		{ParamID: 0x0013, ParamValue: "127.0.0.1"},
+		{ParamID: 0x0029, ParamValue: uint32(30)},
+	}}
+	selected := params.Select(in.ParamIDs)
+	require.Equal(t, uint8(2), selected.ParamCnt)
+	require.Equal(t, uint32(0x0029), selected.Params[0].ParamID)
+	require.Equal(t, uint32(0x0001), selected.Params[1].ParamID)
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 升级类型
+const (
+	UpgradeTypeTerminal   uint8 = 0  // 终端
+	UpgradeTypeICCard     uint8 = 12 // 道路运输证IC卡读卡器
+	UpgradeTypeGNSSModule uint8 = 52 // 北斗卫星定位模块
+)
+
+func UpgradeType2Str(t uint8) string {
+	switch t {
+	case UpgradeTypeTerminal:
+		return "terminal"
+	case UpgradeTypeICCard:
+		return "ic card reader"
+	case UpgradeTypeGNSSModule:
+		return "gnss module"
+	}
+	return "unknown"
+}
+
+// 制造商ID长度，2019版本为11位，2011/2013版本为5位
+func manufacturerLen(ver VersionType) int {
+	if ver == Version2019 {
+		return 11
+	}
+	return 5
+}
+
+// 0x8108 《8.17 下发终端升级包》
+//
+// 升级包超过单包长度时由编码器自动分包，终端以通用应答回复，升级完成后以0x0108通知结果
+type Msg8108 struct {
+	Header         *MsgHeader `json:"header"`
+	UpgradeType    uint8      `json:"upgradeType"`    // 升级类型，0:终端;12:道路运输证IC卡读卡器;52:北斗卫星定位模块
+	ManufacturerID string     `json:"manufacturerId"` // 制造商ID，2019版本BYTE[11]，2011/2013版本BYTE[5]
+	VersionLen     uint8      `json:"versionLen"`     // 版本号长度
+	Version        string     `json:"version"`        // 版本号
+	PackageLen     uint32     `json:"packageLen"`     // 升级数据包长度
+	Package        []byte     `json:"-"`              // 升级数据包
+}
+
+func (m *Msg8108) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.UpgradeType = r.ReadUint8("upgradeType")
+	m.ManufacturerID = strings.TrimRight(r.ReadString("manufacturerId", manufacturerLen(m.Header.Attr.VersionDesc)), "\x00")
+	m.VersionLen = r.ReadUint8("versionLen")
+	m.Version = r.ReadString("version", int(m.VersionLen))
+	m.PackageLen = r.ReadDoubleWord("packageLen")
+	m.Package = r.ReadBytes("package", int(m.PackageLen))
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8108) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.UpgradeType)
+	manu := make([]byte, manufacturerLen(m.Header.Attr.VersionDesc)) // 不足位补0x00
+	copy(manu, m.ManufacturerID)
+	pkt = hex.WriteBytes(pkt, manu)
+	m.VersionLen = uint8(len(m.Version))
+	pkt = hex.WriteByte(pkt, m.VersionLen)
+	pkt = hex.WriteString(pkt, m.Version)
+	m.PackageLen = uint32(len(m.Package))
+	pkt = hex.WriteDoubleWord(pkt, m.PackageLen)
+	pkt = hex.WriteBytes(pkt, m.Package)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8108) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8108) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg8108_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    *MsgBodyAttr
+		phone   string
+		wantLen int // 消息体长度
+	}{
+		{
+			name:    "case1: 2013 manufacturer 5 bytes",
+			attr:    &MsgBodyAttr{VersionDesc: Version2013},
+			phone:   "013800000001",
+			wantLen: 1 + 5 + 1 + 5 + 4 + 16,
+		},
+		{
+			name:    "case2: 2019 manufacturer 11 bytes",
+			attr:    &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019},
+			phone:   "00000000013800000001",
+			wantLen: 1 + 11 + 1 + 5 + 4 + 16,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8108{
+				Header:         &MsgHeader{MsgID: 0x8108, Attr: tt.attr, PhoneNumber: tt.phone, SerialNumber: 9},
+				UpgradeType:    UpgradeTypeTerminal,
+				ManufacturerID: "70111",
+				Version:        "1.2.0",
+				Package:        bytes.Repeat([]byte{0x7e, 0x01}, 8),
+			}
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8108{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			got.Header = msg.Header
+			require.Equal(t, msg, got)
+		})
+	}
+}
+
+func TestMsg0108_EncodeDecode(t *testing.T) {
+	msg := &Msg0108{
+		Header:      &MsgHeader{MsgID: 0x0108, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001"},
+		UpgradeType: UpgradeTypeGNSSModule,
+		Result:      UpgradeResultCanceled,
+	}
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg0108{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	got.Header = msg.Header
+	require.Equal(t, msg, got)
+	require.Equal(t, "canceled", Msg0108Result2Str(got.Result))
+}
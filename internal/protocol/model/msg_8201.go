@@ -0,0 +1,27 @@
+package model
+
+// 0x8201 《8.29 位置信息查询》
+//
+// 消息体为空，终端以0x0201应答
+type Msg8201 struct {
+	Header *MsgHeader `json:"header"`
+}
+
+func (m *Msg8201) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	return nil
+}
+
+func (m *Msg8201) Encode() (pkt []byte, err error) {
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8201) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8201) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
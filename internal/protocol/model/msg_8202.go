@@ -0,0 +1,42 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8202 《8.31 临时位置跟踪控制》
+//
+// 终端在有效期内按指定间隔上报0x0200，以通用应答回复
+type Msg8202 struct {
+	Header        *MsgHeader `json:"header"`
+	Interval      uint16     `json:"interval"`      // 时间间隔，单位秒，0表示停止跟踪，停止跟踪时无需带后继字段
+	ValidDuration uint32     `json:"validDuration"` // 位置跟踪有效期，单位秒，终端收到后在有效期截止前按间隔上报
+}
+
+func (m *Msg8202) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Interval = r.ReadWord("interval")
+	if m.Interval != 0 || r.Len() > 0 {
+		m.ValidDuration = r.ReadDoubleWord("validDuration")
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8202) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteWord(pkt, m.Interval)
+	if m.Interval != 0 {
+		pkt = hex.WriteDoubleWord(pkt, m.ValidDuration)
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8202) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8202) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
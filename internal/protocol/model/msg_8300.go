@@ -0,0 +1,112 @@
+package model
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/gbk"
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 文本信息标志位
+const (
+	TextFlagEmergency uint8 = 0b00000001 // bit0, 紧急，2013版本
+	TextFlagDisplay   uint8 = 0b00000100 // bit2, 终端显示器显示
+	TextFlagTTS       uint8 = 0b00001000 // bit3, 终端TTS播读
+	TextFlagAdScreen  uint8 = 0b00010000 // bit4, 广告屏显示，2013版本，2019版本保留
+	TextFlagCANFault  uint8 = 0b00100000 // bit5, 0:中心导航信息;1:CAN故障码信息
+
+	// 2019版本bit0-1表示消息类别
+	textFlagMask2019      uint8 = 0b00000011
+	TextFlagService2019   uint8 = 0b00000001 // 01:服务
+	TextFlagEmergency2019 uint8 = 0b00000010 // 10:紧急
+	TextFlagNotice2019    uint8 = 0b00000011 // 11:通知
+)
+
+// 文本类型，2019版本
+const (
+	TextTypeNotice  uint8 = 1 // 通知
+	TextTypeService uint8 = 2 // 服务
+)
+
+// TextOptions 文本信息下发选项，按终端协议版本生成标志位
+type TextOptions struct {
+	Emergency bool  `json:"emergency"` // 紧急
+	Display   bool  `json:"display"`   // 终端显示器显示
+	TTS       bool  `json:"tts"`       // 终端TTS播读
+	AdScreen  bool  `json:"adScreen"`  // 广告屏显示，仅2011/2013版本支持
+	TextType  uint8 `json:"textType"`  // 文本类型，1:通知;2:服务，仅2019版本使用
+}
+
+// Flag 按协议版本生成文本信息标志
+func (o *TextOptions) Flag(version VersionType) uint8 {
+	var flag uint8
+	if o.Display {
+		flag |= TextFlagDisplay
+	}
+	if o.TTS {
+		flag |= TextFlagTTS
+	}
+	if version != Version2019 {
+		if o.Emergency {
+			flag |= TextFlagEmergency
+		}
+		if o.AdScreen {
+			flag |= TextFlagAdScreen
+		}
+		return flag
+	}
+	switch {
+	case o.Emergency:
+		flag |= TextFlagEmergency2019
+	case o.TextType == TextTypeNotice:
+		flag |= TextFlagNotice2019
+	default:
+		flag |= TextFlagService2019
+	}
+	return flag
+}
+
+// 0x8300 《8.32 文本信息下发》
+//
+// 终端以通用应答回复，文本超过单包长度时由编码器自动分包
+type Msg8300 struct {
+	Header   *MsgHeader `json:"header"`
+	Flag     uint8      `json:"flag"`     // 标志
+	TextType uint8      `json:"textType"` // 文本类型，1:通知;2:服务，2019版本
+	Text     string     `json:"text"`     // 文本信息，GBK编码
+}
+
+func (m *Msg8300) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Flag = r.ReadUint8("flag")
+	if m.Header.Attr.VersionDesc == Version2019 {
+		m.TextType = r.ReadUint8("textType")
+	}
+	m.Text = r.ReadGBK("text", r.Len())
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8300) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.Flag)
+	if m.Header.Attr.VersionDesc == Version2019 {
+		pkt = hex.WriteByte(pkt, m.TextType)
+	}
+	text, err := gbk.UTF82GBK([]byte(m.Text))
+	if err != nil {
+		log.Error().Err(err).Str("device", m.Header.PhoneNumber).Msg("Fail to encode text to gbk")
+		return nil, ErrEncodeMsg
+	}
+	pkt = hex.WriteBytes(pkt, text)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8300) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8300) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
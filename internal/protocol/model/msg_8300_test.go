@@ -0,0 +1,91 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextOptions_Flag(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TextOptions
+		version VersionType
+		want    uint8
+	}{
+		{
+			name:    "case1: 2013 emergency display tts adScreen",
+			opts:    TextOptions{Emergency: true, Display: true, TTS: true, AdScreen: true},
+			version: Version2013,
+			want:    0b00011101,
+		},
+		{
+			name:    "case2: 2019 emergency ignores adScreen",
+			opts:    TextOptions{Emergency: true, Display: true, AdScreen: true},
+			version: Version2019,
+			want:    0b00000110,
+		},
+		{
+			name:    "case3: 2019 notice",
+			opts:    TextOptions{TTS: true, TextType: TextTypeNotice},
+			version: Version2019,
+			want:    0b00001011,
+		},
+		{
+			name:    "case4: 2019 service",
+			opts:    TextOptions{TextType: TextTypeService},
+			version: Version2019,
+			want:    0b00000001,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.opts.Flag(tt.version))
+		})
+	}
+}
+
+func TestMsg8300_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    *MsgBodyAttr
+		phone   string
+		wantLen int // 消息体长度
+	}{
+		{
+			name:    "case1: 2013 without text type",
+			attr:    &MsgBodyAttr{VersionDesc: Version2013},
+			phone:   "013800000001",
+			wantLen: 1 + 14,
+		},
+		{
+			name:    "case2: 2019 with text type",
+			attr:    &MsgBodyAttr{VersionSign: 1, VersionDesc: Version2019},
+			phone:   "00000000013800000001",
+			wantLen: 1 + 1 + 14,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Msg8300{
+				Header:   &MsgHeader{MsgID: 0x8300, Attr: tt.attr, PhoneNumber: tt.phone},
+				Flag:     TextFlagDisplay | TextFlagTTS,
+				TextType: TextTypeNotice,
+				Text:     "请到调度室报到", // GBK编码每个汉字2字节
+			}
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg8300{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, msg.Flag, got.Flag)
+			require.Equal(t, msg.Text, got.Text)
+			if tt.attr.VersionDesc == Version2019 {
+				require.Equal(t, TextTypeNotice, got.TextType)
+			}
+		})
+	}
+}
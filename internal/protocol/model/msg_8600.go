@@ -0,0 +1,52 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8600 《8.44 设置圆形区域》
+//
+// 终端以通用应答回复，设置属性为更新时终端先删除全部圆形区域再保存本次下发的圆形区域
+type Msg8600 struct {
+	Header *MsgHeader    `json:"header"`
+	Action uint8         `json:"action"` // 设置属性，0:更新区域;1:追加区域;2:修改区域
+	Count  uint8         `json:"count"`  // 区域总数
+	Areas  []*CircleArea `json:"areas"`  // 区域项
+}
+
+func (m *Msg8600) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Action = r.ReadUint8("action")
+	m.Count = r.ReadUint8("count")
+	m.Areas = make([]*CircleArea, 0, m.Count)
+	for i := 0; i < int(m.Count) && r.Err() == nil; i++ {
+		a := &CircleArea{}
+		a.decode(r, m.Header.Attr.VersionDesc)
+		m.Areas = append(m.Areas, a)
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8600) Encode() (pkt []byte, err error) {
+	m.Count = uint8(len(m.Areas))
+	pkt = hex.WriteByte(pkt, m.Action)
+	pkt = hex.WriteByte(pkt, m.Count)
+	for _, a := range m.Areas {
+		pkt, err = a.encode(pkt, m.Header.Attr.VersionDesc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8600) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8600) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
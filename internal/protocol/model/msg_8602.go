@@ -0,0 +1,52 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8602 《8.46 设置矩形区域》
+//
+// 终端以通用应答回复，设置属性为更新时终端先删除全部矩形区域再保存本次下发的矩形区域
+type Msg8602 struct {
+	Header *MsgHeader       `json:"header"`
+	Action uint8            `json:"action"` // 设置属性，0:更新区域;1:追加区域;2:修改区域
+	Count  uint8            `json:"count"`  // 区域总数
+	Areas  []*RectangleArea `json:"areas"`  // 区域项
+}
+
+func (m *Msg8602) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Action = r.ReadUint8("action")
+	m.Count = r.ReadUint8("count")
+	m.Areas = make([]*RectangleArea, 0, m.Count)
+	for i := 0; i < int(m.Count) && r.Err() == nil; i++ {
+		a := &RectangleArea{}
+		a.decode(r, m.Header.Attr.VersionDesc)
+		m.Areas = append(m.Areas, a)
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8602) Encode() (pkt []byte, err error) {
+	m.Count = uint8(len(m.Areas))
+	pkt = hex.WriteByte(pkt, m.Action)
+	pkt = hex.WriteByte(pkt, m.Count)
+	for _, a := range m.Areas {
+		pkt, err = a.encode(pkt, m.Header.Attr.VersionDesc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8602) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8602) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8604 《8.48 设置多边形区域》
+//
+// 每条消息只设置一个区域，顶点较多超过单包长度时由编码器分包，终端以通用应答回复
+type Msg8604 struct {
+	Header *MsgHeader   `json:"header"`
+	Area   *PolygonArea `json:"area"`
+}
+
+func (m *Msg8604) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Area = &PolygonArea{}
+	m.Area.decode(r, m.Header.Attr.VersionDesc)
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8604) Encode() (pkt []byte, err error) {
+	pkt, err = m.Area.encode(pkt, m.Header.Attr.VersionDesc)
+	if err != nil {
+		return nil, err
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8604) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8604) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
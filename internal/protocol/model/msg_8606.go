@@ -0,0 +1,39 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8606 《8.50 设置路线》
+//
+// 每条消息只设置一条路线，拐点较多超过单包长度时由编码器分包，终端以通用应答回复
+type Msg8606 struct {
+	Header *MsgHeader `json:"header"`
+	Route  *RouteArea `json:"route"`
+}
+
+func (m *Msg8606) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.Route = &RouteArea{}
+	m.Route.decode(r, m.Header.Attr.VersionDesc)
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8606) Encode() (pkt []byte, err error) {
+	pkt, err = m.Route.encode(pkt, m.Header.Attr.VersionDesc)
+	if err != nil {
+		return nil, err
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8606) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8606) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
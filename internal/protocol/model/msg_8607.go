@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8607 《8.51 删除路线》
+//
+// 终端以通用应答回复，单条消息最多删除125个路线
+type Msg8607 struct {
+	Header *MsgHeader `json:"header"`
+	Count  uint8      `json:"count"` // 路线数，不超过125个，0为删除所有路线
+	IDs    []uint32   `json:"ids"`   // 路线ID
+}
+
+func (m *Msg8607) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.IDs = decodeAreaIDs(r)
+	m.Count = uint8(len(m.IDs))
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8607) Encode() (pkt []byte, err error) {
+	m.Count = uint8(len(m.IDs))
+	pkt, err = encodeAreaIDs(pkt, m.IDs)
+	if err != nil {
+		return nil, err
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8607) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8607) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8608 《8.52 查询区域或线路数据》
+//
+// 2019版本新增，终端以0x0608应答
+type Msg8608 struct {
+	Header    *MsgHeader `json:"header"`
+	QueryType uint8      `json:"queryType"` // 查询类型，1:圆形;2:矩形;3:多边形;4:路线
+	Count     uint32     `json:"count"`     // 要查询的区域或线路ID数量，0为查询该类型的全部区域
+	IDs       []uint32   `json:"ids"`       // 区域或线路ID
+}
+
+func (m *Msg8608) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.QueryType = r.ReadUint8("queryType")
+	m.Count = r.ReadDoubleWord("count")
+	if r.Require("ids", 4*int(m.Count)) {
+		m.IDs = make([]uint32, 0, m.Count)
+		for i := 0; i < int(m.Count); i++ {
+			m.IDs = append(m.IDs, r.ReadDoubleWord("id"))
+		}
+	}
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8608) Encode() (pkt []byte, err error) {
+	m.Count = uint32(len(m.IDs))
+	pkt = hex.WriteByte(pkt, m.QueryType)
+	pkt = hex.WriteDoubleWord(pkt, m.Count)
+	for _, id := range m.IDs {
+		pkt = hex.WriteDoubleWord(pkt, id)
+	}
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8608) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8608) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
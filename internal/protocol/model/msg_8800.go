@@ -1,16 +1,38 @@
 package model
 
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
 // 多媒体数据上传应答
+//
+// 平台收到全部数据包后应答，重传包列表为空；有缺失时通过重传包列表要求终端重传
 type Msg8800 struct {
-	Header *MsgHeader `json:"header"`
+	Header          *MsgHeader `json:"header"`
+	MultiMediaID    uint32     `json:"multiMediaId"`    // 多媒体ID
+	RetransmitCount uint8      `json:"retransmitCount"` // 重传包总数
+	RetransmitIDs   []uint16   `json:"retransmitIds"`   // 重传包ID列表
 }
 
 func (m *Msg8800) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.MultiMediaID = r.ReadDoubleWord("multiMediaId")
+	// 2011版本全部收到时可以不携带重传包总数
+	if r.Len() > 0 {
+		m.RetransmitCount = r.ReadUint8("retransmitCount")
+		m.RetransmitIDs = readWords(r, "retransmitIds", int(m.RetransmitCount))
+	}
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg8800) Encode() (pkt []byte, err error) {
+	m.RetransmitCount = uint8(len(m.RetransmitIDs))
+	pkt = hex.WriteDoubleWord(pkt, m.MultiMediaID)
+	pkt = hex.WriteByte(pkt, m.RetransmitCount)
+	for _, id := range m.RetransmitIDs {
+		pkt = hex.WriteWord(pkt, id)
+	}
 	pkt, err = writeHeader(m, pkt)
 	return pkt, err
 }
@@ -19,6 +41,12 @@ func (m *Msg8800) GetHeader() *MsgHeader {
 	return m.Header
 }
 
-func (m *Msg8800) GenOutgoing(_ JT808Msg) error {
+func (m *Msg8800) GenOutgoing(incoming JT808Msg) error {
+	in, ok := incoming.(*Msg0801)
+	if !ok {
+		return ErrGenOutgoingMsg
+	}
+	m.MultiMediaID = in.MultiMediaID
+	m.Header = GenReplyHeader(in.Header, 0x8800)
 	return nil
 }
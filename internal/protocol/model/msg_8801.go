@@ -1,3 +1,68 @@
 package model
 
-//0x8801 《8.53 摄像头立即拍摄命令》
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 拍摄命令的特殊取值，其他值表示拍照张数
+const (
+	ShootCommandStop   uint16 = 0x0000 // 停止拍摄
+	ShootCommandRecord uint16 = 0xFFFF // 录像
+)
+
+// 0x8801 《8.53 摄像头立即拍摄命令》
+//
+// 终端以0x0805应答
+type Msg8801 struct {
+	Header       *MsgHeader `json:"header"`
+	ChannelID    uint8      `json:"channelId"`    // 通道ID，大于0
+	ShootCommand uint16     `json:"shootCommand"` // 拍摄命令，0表示停止拍摄，0xFFFF表示录像，其他表示拍照张数
+	Interval     uint16     `json:"interval"`     // 拍照间隔/录像时间，单位秒，0表示按最小间隔拍照或一直录像
+	SaveFlag     uint8      `json:"saveFlag"`     // 保存标志，1保存，0实时上传
+	Resolution   uint8      `json:"resolution"`   // 分辨率，0x01:320*240;0x02:640*480;0x03:800*600;0x04:1024*768;0x05:176*144;0x06:352*288;0x07:704*288;0x08:704*576
+	Quality      uint8      `json:"quality"`      // 图像/视频质量，1-10，1代表质量损失最小，10表示压缩比最大
+	Brightness   uint8      `json:"brightness"`   // 亮度，0-255
+	Contrast     uint8      `json:"contrast"`     // 对比度，0-127
+	Saturation   uint8      `json:"saturation"`   // 饱和度，0-127
+	Chroma       uint8      `json:"chroma"`       // 色度，0-255
+}
+
+func (m *Msg8801) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.ChannelID = r.ReadUint8("channelId")
+	m.ShootCommand = r.ReadWord("shootCommand")
+	m.Interval = r.ReadWord("interval")
+	m.SaveFlag = r.ReadUint8("saveFlag")
+	m.Resolution = r.ReadUint8("resolution")
+	m.Quality = r.ReadUint8("quality")
+	m.Brightness = r.ReadUint8("brightness")
+	m.Contrast = r.ReadUint8("contrast")
+	m.Saturation = r.ReadUint8("saturation")
+	m.Chroma = r.ReadUint8("chroma")
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8801) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.ChannelID)
+	pkt = hex.WriteWord(pkt, m.ShootCommand)
+	pkt = hex.WriteWord(pkt, m.Interval)
+	pkt = hex.WriteByte(pkt, m.SaveFlag)
+	pkt = hex.WriteByte(pkt, m.Resolution)
+	pkt = hex.WriteByte(pkt, m.Quality)
+	pkt = hex.WriteByte(pkt, m.Brightness)
+	pkt = hex.WriteByte(pkt, m.Contrast)
+	pkt = hex.WriteByte(pkt, m.Saturation)
+	pkt = hex.WriteByte(pkt, m.Chroma)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8801) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8801) GenOutgoing(_ JT808Msg) error {
+	// will not use
+	return nil
+}
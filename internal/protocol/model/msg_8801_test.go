@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsg8801_EncodeDecode(t *testing.T) {
+	msg := &Msg8801{
+		Header:       &MsgHeader{MsgID: 0x8801, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001", SerialNumber: 3},
+		ChannelID:    1,
+		ShootCommand: 2,
+		Interval:     5,
+		Resolution:   0x02,
+		Quality:      5,
+		Brightness:   128,
+		Contrast:     64,
+		Saturation:   64,
+		Chroma:       128,
+	}
+	pkt, err := msg.Encode()
+	require.Nil(t, err)
+	require.Equal(t, 12, int(msg.Header.Attr.BodyLength))
+
+	header := &MsgHeader{}
+	require.Nil(t, header.Decode(pkt))
+	got := &Msg8801{}
+	require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+	got.Header = msg.Header
+	require.Equal(t, msg, got)
+}
+
+func TestMsg0805_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  uint8
+		ids     []uint32
+		wantLen int // 消息体长度
+	}{
+		{name: "case1: success with ids", result: 0, ids: []uint32{0x00030001, 0x00030002}, wantLen: 2 + 1 + 2 + 4*2},
+		{name: "case2: failed without ids", result: 2, wantLen: 2 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Msg8801{Header: &MsgHeader{MsgID: 0x8801, Attr: &MsgBodyAttr{VersionDesc: Version2013}, PhoneNumber: "013800000001", SerialNumber: 3}}
+			msg := &Msg0805{}
+			require.Nil(t, msg.GenOutgoing(in))
+			msg.Result = tt.result
+			msg.MediaIDs = tt.ids
+			pkt, err := msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, tt.wantLen, int(msg.Header.Attr.BodyLength))
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(pkt))
+			got := &Msg0805{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: pkt[header.Idx:]}))
+			require.Equal(t, uint16(3), got.AnswerSerialNumber)
+			require.Equal(t, tt.result, got.Result)
+			require.Equal(t, tt.ids, got.MediaIDs)
+		})
+	}
+}
@@ -1,3 +1,35 @@
 package model
 
-//0x8900《8.60 数据下行透传》
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 0x8900《8.60 数据下行透传》
+type Msg8900 struct {
+	Header  *MsgHeader `json:"header"`
+	MsgType uint8      `json:"msgType"` // 透传消息类型，定义同0x0900
+	Data    []byte     `json:"data"`    // 透传消息内容
+}
+
+func (m *Msg8900) Decode(packet *PacketData) error {
+	m.Header = packet.Header
+	r := hex.NewReader(packet.Body)
+	m.MsgType = r.ReadUint8("msgType")
+	m.Data = r.ReadBytes("data", r.Len())
+	return decodeErr(m.Header.MsgID, r)
+}
+
+func (m *Msg8900) Encode() (pkt []byte, err error) {
+	pkt = hex.WriteByte(pkt, m.MsgType)
+	pkt = hex.WriteBytes(pkt, m.Data)
+	pkt, err = writeHeader(m, pkt)
+	return pkt, err
+}
+
+func (m *Msg8900) GetHeader() *MsgHeader {
+	return m.Header
+}
+
+func (m *Msg8900) GenOutgoing(_ JT808Msg) error {
+	return nil
+}
@@ -1,5 +1,9 @@
 package model
 
+import (
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
 // JT1078 查询资源列表
 type Msg9205 struct {
 	Header *MsgHeader `json:"header"`
@@ -8,9 +12,9 @@ type Msg9205 struct {
 
 func (m *Msg9205) Decode(packet *PacketData) error {
 	m.Header = packet.Header
-	pkt, idx := packet.Body, 0
-	m.DeviceMediaQuery.Decode(pkt, &idx)
-	return nil
+	r := hex.NewReader(packet.Body)
+	m.DeviceMediaQuery.Decode(r)
+	return decodeErr(m.Header.MsgID, r)
 }
 
 func (m *Msg9205) Encode() (pkt []byte, err error) {
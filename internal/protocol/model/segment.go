@@ -1,29 +1,82 @@
 package model
 
-// 分包消息结构
+import (
+	"time"
+)
+
+// 分包消息结构，按包序号缓存，可以乱序到达
 type Segment struct {
-	Phone    string `json:"phone"`
-	MsgID    uint16 `json:"msgId"`
-	SegTotal uint16 `json:"total"`
-	SegNo    uint16 `json:"no"`
-	Data     []byte `json:"data"`
+	Phone       string            `json:"phone"`
+	MsgID       uint16            `json:"msgId"`
+	FirstSerial uint16            `json:"firstSerial"` // 第一个分包的流水号，按包序号推算，补传请求中作为原始流水号
+	SegTotal    uint16            `json:"total"`
+	Parts       map[uint16][]byte `json:"-"` // <包序号, 消息体>
+	Data        []byte            `json:"-"` // 分包全部到达后组装的消息体
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
 }
 
 func (s *Segment) IsComplete() bool {
-	return s.SegNo == s.SegTotal
+	for i := 1; i <= int(s.SegTotal); i++ {
+		if _, ok := s.Parts[uint16(i)]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
+// Merge 合并同一消息的其他分包，重复的分包以后到达的为准
 func (s *Segment) Merge(ns *Segment) {
-	s.SegNo = ns.SegNo
-	s.Data = append(s.Data, ns.Data...)
+	for i, part := range ns.Parts {
+		s.Parts[i] = part
+	}
+	s.UpdatedAt = ns.UpdatedAt
+}
+
+// Assemble 按包序号组装消息体，分包不完整时返回false
+func (s *Segment) Assemble() bool {
+	if !s.IsComplete() {
+		return false
+	}
+	data := make([]byte, 0)
+	for i := 1; i <= int(s.SegTotal); i++ {
+		data = append(data, s.Parts[uint16(i)]...)
+	}
+	s.Data = data
+	return true
+}
+
+// IsMissing 包序号是否未到达
+func (s *Segment) IsMissing(index uint16) bool {
+	if index == 0 || index > s.SegTotal {
+		return false
+	}
+	_, ok := s.Parts[index]
+	return !ok
+}
+
+// MissingIDs 未到达的包序号
+func (s *Segment) MissingIDs() []uint16 {
+	ids := make([]uint16, 0)
+	for i := 1; i <= int(s.SegTotal); i++ {
+		if _, ok := s.Parts[uint16(i)]; !ok {
+			ids = append(ids, uint16(i))
+		}
+	}
+	return ids
 }
 
+// NewSegment 按分包流水号推算第一个分包的流水号，补传的分包流水号不连续，由缓存按等待补传的消息合并
 func NewSegment(pd *PacketData) *Segment {
+	index := pd.Header.Frag.Index
+	now := time.Now()
 	return &Segment{
-		Phone:    pd.Header.PhoneNumber,
-		MsgID:    pd.Header.MsgID,
-		SegTotal: pd.Header.Frag.Total,
-		SegNo:    pd.Header.Frag.Index,
-		Data:     pd.Body,
+		Phone:       pd.Header.PhoneNumber,
+		MsgID:       pd.Header.MsgID,
+		FirstSerial: pd.Header.SerialNumber - (index - 1),
+		SegTotal:    pd.Header.Frag.Total,
+		Parts:       map[uint16][]byte{index: pd.Body},
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 }
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegment_Merge(t *testing.T) {
+	newPart := func(index uint16, body []byte) *Segment {
+		return NewSegment(&PacketData{
+			Header: &MsgHeader{
+				MsgID:        0x0801,
+				PhoneNumber:  "013800000001",
+				SerialNumber: 10 + index - 1,
+				Frag:         &MsgFragmentation{Total: 3, Index: index},
+			},
+			Body: body,
+		})
+	}
+
+	seg := newPart(3, []byte{0x03})
+	require.Equal(t, uint16(10), seg.FirstSerial)
+	require.False(t, seg.Assemble())
+	require.Equal(t, []uint16{1, 2}, seg.MissingIDs())
+	require.True(t, seg.IsMissing(1))
+	require.False(t, seg.IsMissing(3))
+	require.False(t, seg.IsMissing(4))
+
+	seg.Merge(newPart(1, []byte{0x01}))
+	require.Equal(t, []uint16{2}, seg.MissingIDs())
+	require.False(t, seg.IsComplete())
+
+	seg.Merge(newPart(2, []byte{0x02}))
+	require.True(t, seg.Assemble())
+	require.Equal(t, []byte{0x01, 0x02, 0x03}, seg.Data)
+	require.Empty(t, seg.MissingIDs())
+}
@@ -3,7 +3,10 @@ package model
 import (
 	"math"
 	"net"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const (
@@ -16,6 +19,8 @@ type TransportProtocol string
 type (
 	ProcResponseCallBackKey struct{}
 
+	ProcResponseByMsgIDCallBackKey struct{} // 应答不携带流水号时按消息ID回调
+
 	SessionCtxKey struct{} // 定义全局session context key
 
 	FrameCtxKey struct{}
@@ -31,19 +36,62 @@ type (
 	PacketEncodeCtxKey struct{}
 )
 
+// 会话状态
+type SessionState int32
+
+const (
+	SessionStateConnected     SessionState = iota // 已连接，未注册或鉴权
+	SessionStateRegistered                        // 已注册
+	SessionStateAuthenticated                     // 已鉴权
+)
+
 type ProcResponseFn func(phone string, ansMsgId uint16, ansSN uint16, rsp any) error
 
+type ProcResponseByMsgIDFn func(phone string, ansMsgId uint16, rsp any) error
+
 type Session struct {
 	ID           string // remote addr
 	Conn         net.Conn
+	PeerIdentity *PeerIdentity // 双向TLS认证通过的客户端证书身份，非双向认证时为nil
 	serialNumber uint32
+	state        int32 // SessionState
+	lastActive   int64 // 最近一次收到数据的时间，unix纳秒
+
+	queue       *writeQueue // 发送队列，第一次发送时创建
+	queueClosed bool
+	queueMutex  sync.Mutex
+}
+
+// 客户端证书身份，用于和终端手机号绑定
+type PeerIdentity struct {
+	CommonName   string `json:"commonName"`   // 证书CN
+	SerialNumber string `json:"serialNumber"` // 证书序列号，16进制
+	Fingerprint  string `json:"fingerprint"`  // 证书sha256指纹，16进制
 }
 
 func (s *Session) GetTransProto() TransportProtocol {
-	if s.Conn != nil {
-		return TCPProto
+	if s.Conn == nil || strings.HasPrefix(s.Conn.LocalAddr().Network(), "udp") {
+		return UDPProto
 	}
-	return UDPProto
+	return TCPProto
+}
+
+func (s *Session) GetState() SessionState {
+	return SessionState(atomic.LoadInt32(&s.state))
+}
+
+func (s *Session) SetState(state SessionState) {
+	atomic.StoreInt32(&s.state, int32(state))
+}
+
+// Touch 记录收到数据的时间
+func (s *Session) Touch() {
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+}
+
+// IdleFor 距离最近一次收到数据的时长
+func (s *Session) IdleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&s.lastActive)))
 }
 
 func (s *Session) GetNextSerialNum() uint16 {
@@ -55,6 +103,46 @@ func (s *Session) GetNextSerialNum() uint16 {
 	return uint16(s.serialNumber)
 }
 
+// Enqueue 将编码后的数据帧放入发送队列，由写协程按顺序写入连接
+func (s *Session) Enqueue(packet []byte) error {
+	s.queueMutex.Lock()
+	if s.queue == nil && !s.queueClosed {
+		s.queue = newWriteQueue(s, writeQueueOpts)
+	}
+	q := s.queue
+	s.queueMutex.Unlock()
+
+	if q == nil {
+		return ErrWriteQueueClosed
+	}
+	return q.enqueue(packet)
+}
+
+// WriteQueueStats 发送队列统计，未发送过消息时返回零值
+func (s *Session) WriteQueueStats() WriteQueueStats {
+	s.queueMutex.Lock()
+	q := s.queue
+	s.queueMutex.Unlock()
+
+	if q == nil {
+		return WriteQueueStats{}
+	}
+	return q.stats()
+}
+
+// Close 停止发送队列，写完已入队的消息后关闭连接
+func (s *Session) Close() error {
+	s.queueMutex.Lock()
+	q := s.queue
+	s.queueClosed = true // 关闭后不再创建队列
+	s.queueMutex.Unlock()
+
+	if q != nil {
+		q.close()
+	}
+	return s.Conn.Close()
+}
+
 // 定义Packet Data结构
 type PacketData struct {
 	Header       *MsgHeader // 消息头
@@ -65,6 +153,7 @@ type PacketData struct {
 
 // 定义消息处理结果数据
 type ProcessData struct {
-	Incoming JT808Msg // 收到的消息
-	Outgoing JT808Msg // 发出的消息, 无需回复时可为nil
+	Incoming  JT808Msg // 收到的消息
+	Outgoing  JT808Msg // 发出的消息, 无需回复时可为nil
+	AfterSend func()   // 回复放入发送队列后调用，如终端控制命令需要在通用应答之后执行
 }
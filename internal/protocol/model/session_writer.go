@@ -0,0 +1,232 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+// 发送队列满时的处理策略
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"      // 阻塞等待，最长等待WriteTimeout
+	OverflowDropNewest OverflowPolicy = "dropNewest" // 丢弃当前消息
+	OverflowDropOldest OverflowPolicy = "dropOldest" // 丢弃队列中最早的消息
+	OverflowClose      OverflowPolicy = "close"      // 关闭连接，终端消费过慢
+)
+
+const (
+	defaultWriteQueueCap     = 128
+	defaultWriteQueueTimeout = 10 * time.Second
+)
+
+var (
+	ErrWriteQueueFull   = errors.New("Fail to enqueue packet, session write queue is full")
+	ErrWriteQueueClosed = errors.New("Fail to enqueue packet, session write queue is closed")
+)
+
+// 会话发送队列配置
+type WriteQueueOptions struct {
+	Capacity     int            // 队列容量
+	Overflow     OverflowPolicy // 队列满时的处理策略
+	WriteTimeout time.Duration  // 单次写socket的超时时间
+}
+
+var writeQueueOpts = &WriteQueueOptions{
+	Capacity:     defaultWriteQueueCap,
+	Overflow:     OverflowBlock,
+	WriteTimeout: defaultWriteQueueTimeout,
+}
+
+// SetWriteQueueOptions 设置会话发送队列配置，只对之后新建的队列生效，零值字段使用默认值
+func SetWriteQueueOptions(opts *WriteQueueOptions) {
+	o := *opts
+	if o.Capacity <= 0 {
+		o.Capacity = defaultWriteQueueCap
+	}
+	if o.Overflow == "" {
+		o.Overflow = OverflowBlock
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = defaultWriteQueueTimeout
+	}
+	writeQueueOpts = &o
+}
+
+// 发送队列统计
+type WriteQueueStats struct {
+	Depth    int    `json:"depth"`    // 当前排队的消息数
+	MaxDepth int    `json:"maxDepth"` // 历史最大排队消息数
+	Enqueued uint64 `json:"enqueued"` // 入队消息数
+	Written  uint64 `json:"written"`  // 写入socket的消息数
+	Dropped  uint64 `json:"dropped"`  // 因队列满丢弃的消息数
+	Failed   uint64 `json:"failed"`   // 写socket失败的消息数
+}
+
+// 会话发送队列，由单个协程按顺序写socket，避免多个发送方的数据帧交错
+type writeQueue struct {
+	session *Session
+	opts    *WriteQueueOptions
+	packets chan []byte
+	done    chan struct{} // 写协程已退出
+
+	closed   bool
+	closing  chan struct{}  // 关闭时通知阻塞等待的入队方
+	blocking sync.WaitGroup // 不持有锁阻塞等待入队的发送方
+	mutex    *sync.RWMutex
+
+	maxDepth int64
+	enqueued uint64
+	written  uint64
+	dropped  uint64
+	failed   uint64
+}
+
+func newWriteQueue(session *Session, opts *WriteQueueOptions) *writeQueue {
+	q := &writeQueue{
+		session: session,
+		opts:    opts,
+		packets: make(chan []byte, opts.Capacity),
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+		mutex:   &sync.RWMutex{},
+	}
+	routines.GoSafe(q.run)
+	return q
+}
+
+func (q *writeQueue) enqueue(packet []byte) error {
+	q.mutex.RLock()
+	if q.closed {
+		q.mutex.RUnlock()
+		return ErrWriteQueueClosed
+	}
+	if q.tryEnqueue(packet) {
+		q.mutex.RUnlock()
+		return nil
+	}
+	switch q.opts.Overflow {
+	case OverflowDropNewest, OverflowDropOldest, OverflowClose:
+		defer q.mutex.RUnlock()
+		return q.overflow(packet)
+	}
+
+	// 释放锁后再阻塞等待，close()通过closing唤醒阻塞的发送方，等待其退出后再关闭packets
+	q.blocking.Add(1)
+	q.mutex.RUnlock()
+	defer q.blocking.Done()
+
+	timer := time.NewTimer(q.opts.WriteTimeout)
+	defer timer.Stop()
+	select {
+	case q.packets <- packet:
+		q.onEnqueued()
+		return nil
+	case <-q.closing:
+		return ErrWriteQueueClosed
+	case <-timer.C:
+		atomic.AddUint64(&q.dropped, 1)
+		return ErrWriteQueueFull
+	}
+}
+
+// 非阻塞入队，队列已满时返回false
+func (q *writeQueue) tryEnqueue(packet []byte) bool {
+	select {
+	case q.packets <- packet:
+		q.onEnqueued()
+		return true
+	default:
+		return false
+	}
+}
+
+// 队列已满时按不阻塞的策略处理，调用方需持有读锁
+func (q *writeQueue) overflow(packet []byte) error {
+	switch q.opts.Overflow {
+	case OverflowDropNewest:
+		atomic.AddUint64(&q.dropped, 1)
+		return ErrWriteQueueFull
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.packets <- packet:
+				q.onEnqueued()
+				return nil
+			default:
+			}
+			select {
+			case <-q.packets:
+				atomic.AddUint64(&q.dropped, 1)
+			default:
+			}
+		}
+	default: // OverflowClose
+		atomic.AddUint64(&q.dropped, 1)
+		log.Warn().Str("sessionId", q.session.ID).Msg("Session write queue is full, close connection")
+		q.session.Conn.Close()
+		return ErrWriteQueueFull
+	}
+}
+
+func (q *writeQueue) onEnqueued() {
+	atomic.AddUint64(&q.enqueued, 1)
+	depth := int64(len(q.packets))
+	for {
+		max := atomic.LoadInt64(&q.maxDepth)
+		if depth <= max || atomic.CompareAndSwapInt64(&q.maxDepth, max, depth) {
+			return
+		}
+	}
+}
+
+// 按入队顺序写socket，队列关闭后写完剩余消息再退出
+func (q *writeQueue) run() {
+	defer close(q.done)
+	for packet := range q.packets {
+		_ = q.session.Conn.SetWriteDeadline(time.Now().Add(q.opts.WriteTimeout))
+		if _, err := q.session.Conn.Write(packet); err != nil {
+			atomic.AddUint64(&q.failed, 1)
+			log.Error().Err(err).Str("sessionId", q.session.ID).Msg("Fail to write packet to connection")
+			// 关闭连接，读协程会感知并清理session
+			q.session.Conn.Close()
+			continue
+		}
+		atomic.AddUint64(&q.written, 1)
+	}
+}
+
+// 停止入队，等待写协程写完剩余消息
+func (q *writeQueue) close() {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		<-q.done
+		return
+	}
+	q.closed = true
+	close(q.closing)
+	q.mutex.Unlock()
+
+	// 不再有新的发送方，阻塞的发送方收到closing后退出，此时可以安全关闭packets
+	q.blocking.Wait()
+	close(q.packets)
+	<-q.done
+}
+
+func (q *writeQueue) stats() WriteQueueStats {
+	return WriteQueueStats{
+		Depth:    len(q.packets),
+		MaxDepth: int(atomic.LoadInt64(&q.maxDepth)),
+		Enqueued: atomic.LoadUint64(&q.enqueued),
+		Written:  atomic.LoadUint64(&q.written),
+		Dropped:  atomic.LoadUint64(&q.dropped),
+		Failed:   atomic.LoadUint64(&q.failed),
+	}
+}
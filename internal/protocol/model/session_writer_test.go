@@ -0,0 +1,137 @@
+package model
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_Enqueue(t *testing.T) {
+	servConn, cliConn := net.Pipe()
+	defer cliConn.Close()
+	session := &Session{ID: "test", Conn: servConn}
+
+	// 多个发送方并发写，每帧在连接上保持完整
+	frames := [][]byte{
+		bytes.Repeat([]byte{0x01}, 100),
+		bytes.Repeat([]byte{0x02}, 100),
+		bytes.Repeat([]byte{0x03}, 100),
+	}
+	var wg sync.WaitGroup
+	for _, f := range frames {
+		f := f
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, session.Enqueue(f))
+		}()
+	}
+
+	got := make([]byte, 300)
+	_, err := io.ReadFull(cliConn, got)
+	require.Nil(t, err)
+	wg.Wait()
+	for i := 0; i < len(got); i += 100 {
+		require.Equal(t, bytes.Repeat(got[i:i+1], 100), got[i:i+100])
+	}
+
+	stats := session.WriteQueueStats()
+	require.Equal(t, uint64(3), stats.Enqueued)
+	require.Eventually(t, func() bool { return session.WriteQueueStats().Written == 3 }, time.Second, 10*time.Millisecond)
+
+	require.Nil(t, session.Close())
+	require.ErrorIs(t, session.Enqueue(frames[0]), ErrWriteQueueClosed)
+}
+
+func TestWriteQueue_overflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      OverflowPolicy
+		wantErr     error
+		wantPackets [][]byte
+	}{
+		{
+			name:        "case1: drop newest",
+			policy:      OverflowDropNewest,
+			wantErr:     ErrWriteQueueFull,
+			wantPackets: [][]byte{{0x01}, {0x02}},
+		},
+		{
+			name:        "case2: drop oldest",
+			policy:      OverflowDropOldest,
+			wantPackets: [][]byte{{0x02}, {0x03}},
+		},
+		{
+			name:        "case3: block until timeout",
+			policy:      OverflowBlock,
+			wantErr:     ErrWriteQueueFull,
+			wantPackets: [][]byte{{0x01}, {0x02}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servConn, cliConn := net.Pipe()
+			defer servConn.Close()
+			defer cliConn.Close()
+			// 不启动写协程，模拟终端消费过慢
+			q := &writeQueue{
+				session: &Session{Conn: servConn},
+				opts:    &WriteQueueOptions{Capacity: 2, Overflow: tt.policy, WriteTimeout: 10 * time.Millisecond},
+				packets: make(chan []byte, 2),
+				done:    make(chan struct{}),
+				closing: make(chan struct{}),
+				mutex:   &sync.RWMutex{},
+			}
+			require.Nil(t, q.enqueue([]byte{0x01}))
+			require.Nil(t, q.enqueue([]byte{0x02}))
+			require.ErrorIs(t, q.enqueue([]byte{0x03}), tt.wantErr)
+
+			close(q.packets)
+			got := make([][]byte, 0)
+			for p := range q.packets {
+				got = append(got, p)
+			}
+			require.Equal(t, tt.wantPackets, got)
+			require.Equal(t, uint64(1), q.stats().Dropped)
+			require.Equal(t, 2, q.stats().MaxDepth)
+		})
+	}
+}
+
+func TestWriteQueue_closeWhileBlocking(t *testing.T) {
+	servConn, cliConn := net.Pipe()
+	defer cliConn.Close()
+	// 终端不读取，写协程阻塞在第一帧，第二帧填满队列
+	q := newWriteQueue(&Session{Conn: servConn}, &WriteQueueOptions{Capacity: 1, Overflow: OverflowBlock, WriteTimeout: 5 * time.Second})
+	require.Nil(t, q.enqueue([]byte{0x01}))
+	require.Eventually(t, func() bool { return len(q.packets) == 0 }, time.Second, 10*time.Millisecond)
+	require.Nil(t, q.enqueue([]byte{0x02}))
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- q.enqueue([]byte{0x03}) }()
+
+	// 阻塞的发送方不持有锁，关闭队列时被唤醒并返回
+	closed := make(chan struct{})
+	go func() {
+		q.close()
+		close(closed)
+	}()
+	select {
+	case err := <-blocked:
+		require.ErrorIs(t, err, ErrWriteQueueClosed)
+	case <-time.After(time.Second):
+		t.Fatal("blocked sender is not woken up by close")
+	}
+
+	// 写完剩余消息后写协程退出
+	got := make([]byte, 2)
+	_, err := io.ReadFull(cliConn, got)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, got)
+	<-closed
+}
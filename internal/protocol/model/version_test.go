@@ -0,0 +1,160 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+)
+
+// 按JT/T 808-2011协议文档构造的消息，解码后再编码应与原始数据一致
+func TestVersion2011_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		pkt   string
+		msg   JT808Msg
+		check func(t *testing.T, msg JT808Msg)
+	}{
+		{
+			// 2011版本终端注册消息体：省域ID WORD，市县域ID WORD，制造商ID BYTE[5]，终端型号 BYTE[8]，终端ID BYTE[7]，车牌颜色 BYTE，车牌 STRING
+			name: "case1: 0x0100 register, 8 bytes device mode",
+			pkt: "010000210139123456780001002c012c37303131314a543830382d3131413030303030310" +
+				"2d4c1423132333435",
+			msg: &Msg0100{},
+			check: func(t *testing.T, msg JT808Msg) {
+				m := msg.(*Msg0100)
+				require.Equal(t, Version2011, m.Header.Attr.VersionDesc)
+				require.Equal(t, "013912345678", m.Header.PhoneNumber)
+				require.Equal(t, uint16(44), m.ProvinceID)
+				require.Equal(t, uint16(300), m.CityID)
+				require.Equal(t, "70111", m.ManufacturerID)
+				require.Equal(t, "JT808-11", m.DeviceMode)
+				require.Equal(t, "A000001", m.DeviceID)
+				require.Equal(t, byte(2), m.PlateColor)
+				require.Equal(t, "粤B12345", m.PlateNumber)
+			},
+		},
+		{
+			// 消息体37字节，与2013版本注册消息的最小长度相同，只能按终端版本解析
+			name: "case2: 0x0100 register, 12 bytes gbk plate",
+			pkt: "010000250139123456780005002c012c37303131314a543830382d3131413030303030310" +
+				"2d4c1423132333435d3a6bcb1",
+			msg: &Msg0100{},
+			check: func(t *testing.T, msg JT808Msg) {
+				m := msg.(*Msg0100)
+				require.Equal(t, "70111", m.ManufacturerID)
+				require.Equal(t, "JT808-11", m.DeviceMode)
+				require.Equal(t, "A000001", m.DeviceID)
+				require.Equal(t, byte(2), m.PlateColor)
+				require.Equal(t, "粤B12345应急", m.PlateNumber)
+			},
+		},
+		{
+			name: "case3: 0x0102 auth",
+			pkt:  "01020007013912345678000241424344454647",
+			msg:  &Msg0102{},
+			check: func(t *testing.T, msg JT808Msg) {
+				m := msg.(*Msg0102)
+				require.Equal(t, "ABCDEFG", m.AuthCode)
+				require.Empty(t, m.IMEI)
+			},
+		},
+		{
+			name: "case4: 0x0200 location with 2011 extras",
+			pkt: "0200002a013912345678000300000000000000030157faf806cc628900140258005a23010112" +
+				"00000104000030390202012c03020262",
+			msg: &Msg0200{},
+			check: func(t *testing.T, msg JT808Msg) {
+				m := msg.(*Msg0200)
+				require.Equal(t, uint32(3), m.StatusSign)
+				require.Equal(t, uint32(22543096), m.Latitude)
+				require.Equal(t, uint32(114057865), m.Longitude)
+				require.Equal(t, uint16(600), m.Speed)
+				require.Equal(t, "230101120000", m.Time)
+				require.Len(t, m.Extra, 3)
+				require.Equal(t, []byte{0x00, 0x00, 0x30, 0x39}, m.Extra[0].Value) // 里程
+			},
+		},
+		{
+			name: "case5: 0x0104 params",
+			pkt: "0104001a013912345678000400050200000001040000003c00000013093132372e302e30" +
+				"2e31",
+			msg: &Msg0104{},
+			check: func(t *testing.T, msg JT808Msg) {
+				m := msg.(*Msg0104)
+				require.Equal(t, uint16(5), m.AnswerSerialNumber)
+				require.Len(t, m.Parameters.Params, 2)
+				require.Equal(t, uint32(60), m.Parameters.Params[0].ParamValue)
+				require.Equal(t, "127.0.0.1", m.Parameters.Params[1].ParamValue)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := hex.Str2Byte(tt.pkt)
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(raw))
+			require.Equal(t, uint8(0), header.Attr.VersionSign)
+			// 消息头与2013版本相同，按终端缓存或配置的版本解析
+			header.Attr.VersionDesc = Version2011
+			require.Nil(t, tt.msg.Decode(&PacketData{Header: header, Body: raw[header.Idx:]}))
+			tt.check(t, tt.msg)
+
+			got, err := tt.msg.Encode()
+			require.Nil(t, err)
+			require.Equal(t, raw, got)
+		})
+	}
+}
+
+func TestMsg0100_DecodeVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version VersionType
+		plate   string
+	}{
+		{name: "case1: 2011 version", version: Version2011, plate: "粤B12345"},
+		{name: "case2: 2011 version with long plate", version: Version2011, plate: "粤B12345应急"},
+		{name: "case3: 2013 version", version: Version2013, plate: "粤B12345"},
+		{name: "case4: 2013 version without plate", version: Version2013, plate: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Msg0100{
+				Header: &MsgHeader{
+					MsgID:        0x0100,
+					Attr:         &MsgBodyAttr{VersionDesc: tt.version},
+					PhoneNumber:  "013912345678",
+					SerialNumber: 1,
+				},
+				ManufacturerID: "70111",
+				DeviceMode:     "JT808",
+				DeviceID:       "A000001",
+				PlateNumber:    tt.plate,
+			}
+			raw, err := m.Encode()
+			require.Nil(t, err)
+
+			header := &MsgHeader{}
+			require.Nil(t, header.Decode(raw))
+			header.Attr.VersionDesc = tt.version
+			got := &Msg0100{}
+			require.Nil(t, got.Decode(&PacketData{Header: header, Body: raw[header.Idx:]}))
+			require.Equal(t, tt.version, got.Header.Attr.VersionDesc)
+			require.Equal(t, "JT808", got.DeviceMode)
+			require.Equal(t, "A000001", got.DeviceID)
+			require.Equal(t, tt.plate, got.PlateNumber)
+		})
+	}
+}
+
+func TestParamData_DecodeUnknownID(t *testing.T) {
+	// 未定义的参数0xF001不影响后续参数解析
+	pkt := hex.Str2Byte("0000f00102abcd00000001040000003c")
+	params := &DeviceParams{}
+	require.Nil(t, params.Decode("013912345678", 2, hex.NewReader(pkt)))
+	require.Len(t, params.Params, 2)
+	require.Equal(t, "ABCD", params.Params[0].ParamValue)
+	require.Equal(t, uint32(60), params.Params[1].ParamValue)
+}
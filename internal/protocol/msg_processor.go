@@ -2,8 +2,10 @@ package protocol
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/fakeyanss/jt808-server-go/internal/codec/hash"
 	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+	"github.com/fakeyanss/jt808-server-go/internal/geofence"
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 	"github.com/fakeyanss/jt808-server-go/internal/storage"
 )
@@ -64,6 +67,12 @@ func initProcessOption() processOptions {
 		},
 		process: processMsg0003,
 	}
+	options[0x0005] = &action{ // 补传分包请求
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0005{}} // 直接重发分包，无需回复
+		},
+		process: processMsg0005,
+	}
 	options[0x0100] = &action{ // 注册
 		genData: func() *model.ProcessData {
 			return &model.ProcessData{Incoming: &model.Msg0100{}, Outgoing: &model.Msg8100{}}
@@ -82,12 +91,66 @@ func initProcessOption() processOptions {
 		},
 		process: processMsg0104,
 	}
+	options[0x0108] = &action{ // 终端升级结果通知
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0108{}, Outgoing: &model.Msg8001{}}
+		},
+		process: processMsg0108,
+	}
 	options[0x0200] = &action{ // 位置信息上报
 		genData: func() *model.ProcessData {
 			return &model.ProcessData{Incoming: &model.Msg0200{}, Outgoing: &model.Msg8001{}}
 		},
 		process: processMsg0200,
 	}
+	options[0x0201] = &action{ // 位置信息查询应答
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0201{}} // 无需回复
+		},
+		process: processMsg0201,
+	}
+	options[0x0608] = &action{ // 查询区域或线路数据应答
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0608{}} // 无需回复
+		},
+		process: processMsg0608,
+	}
+	options[0x0704] = &action{ // 定位数据批量上传
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0704{}, Outgoing: &model.Msg8001{}}
+		},
+		process: processMsg0704,
+	}
+	options[0x0800] = &action{ // 多媒体事件信息上传
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0800{}, Outgoing: &model.Msg8001{}}
+		},
+		process: processMsg0800,
+	}
+	options[0x0801] = &action{ // 多媒体数据上传
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0801{}, Outgoing: &model.Msg8800{}}
+		},
+		process: processMsg0801,
+	}
+	options[0x0805] = &action{ // 摄像头立即拍摄命令应答
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0805{}} // 无需回复
+		},
+		process: processMsg0805,
+	}
+	options[0x0900] = &action{ // 数据上行透传
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0900{}, Outgoing: &model.Msg8001{}}
+		},
+		process: processMsg0900,
+	}
+	options[0x0A00] = &action{ // 终端RSA公钥
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg0A00{}, Outgoing: &model.Msg8A00{}}
+		},
+		process: processMsg0A00,
+	}
 	options[0x1205] = &action{ // 终端上传音视频资源列表
 		genData: func() *model.ProcessData {
 			return &model.ProcessData{Incoming: &model.Msg1205{}} // 无需回复
@@ -117,6 +180,95 @@ func initProcessOption() processOptions {
 		},
 		process: processMsg8104,
 	}
+	options[0x8106] = &action{ // 查询指定终端参数
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8106{}, Outgoing: &model.Msg0104{}}
+		},
+		process: processMsg8106,
+	}
+	options[0x8105] = &action{ // 终端控制
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8105{}, Outgoing: &model.Msg0001{}}
+		},
+		process: processMsg8105,
+	}
+	options[0x8108] = &action{ // 下发终端升级包
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8108{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8201] = &action{ // 位置信息查询
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8201{}, Outgoing: &model.Msg0201{}}
+		},
+		process: processMsg8201,
+	}
+	options[0x8202] = &action{ // 临时位置跟踪控制
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8202{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8300] = &action{ // 文本信息下发
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8300{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8600] = &action{ // 设置圆形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8600{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8601] = &action{ // 删除圆形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8601{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8602] = &action{ // 设置矩形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8602{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8603] = &action{ // 删除矩形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8603{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8604] = &action{ // 设置多边形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8604{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8605] = &action{ // 删除多边形区域
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8605{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8606] = &action{ // 设置路线
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8606{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8607] = &action{ // 删除路线
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8607{}, Outgoing: &model.Msg0001{}}
+		},
+	}
+	options[0x8608] = &action{ // 查询区域或线路数据
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8608{}, Outgoing: &model.Msg0608{}}
+		},
+	}
+	options[0x8801] = &action{ // 摄像头立即拍摄命令
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8801{}, Outgoing: &model.Msg0805{}}
+		},
+		process: processMsg8801,
+	}
+	options[0x8900] = &action{ // 数据下行透传
+		genData: func() *model.ProcessData {
+			return &model.ProcessData{Incoming: &model.Msg8900{}, Outgoing: &model.Msg0001{}}
+		},
+	}
 	options[0x9205] = &action{ // 查询终端音视频资源列表
 		genData: func() *model.ProcessData {
 			return &model.ProcessData{Incoming: &model.Msg9205{}, Outgoing: &model.Msg1205{}}
@@ -191,6 +343,10 @@ func (mp *JT808MsgProcessor) Process(ctx context.Context, pkt *model.PacketData)
 		if err != nil {
 			return data, errors.Wrap(err, "Fail to generate outgoing msg")
 		}
+		// 应答消息使用本端的流水号
+		if session, ok := ctx.Value(model.SessionCtxKey{}).(*model.Session); ok && session != nil {
+			out.GetHeader().SerialNumber = session.GetNextSerialNum()
+		}
 
 		// print log of outgoing content
 		defer func() {
@@ -231,6 +387,9 @@ func processSegmentPacket(_ context.Context, pkt *model.PacketData) (*model.Proc
 		return nil, errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", phone)
 	}
 	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		return nil, err
+	}
 	header := model.GenMsgHeader(device, 0x8001, session.GetNextSerialNum())
 	outgoingMsg := &model.Msg8001{
 		Header:             header,
@@ -241,6 +400,47 @@ func processSegmentPacket(_ context.Context, pkt *model.PacketData) (*model.Proc
 	return &model.ProcessData{Outgoing: outgoingMsg}, nil
 }
 
+// 分包超时未收齐，下发0x8003请求终端补传缺失的分包
+func requestSegmentRetransmit(seg *model.Segment, missingIDs []uint16) {
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(seg.Phone)
+	if err != nil {
+		log.Warn().Err(err).Str("device", seg.Phone).Msg("Fail to request segment retransmit")
+		return
+	}
+	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		log.Warn().Err(err).Str("device", seg.Phone).Msg("Fail to request segment retransmit")
+		return
+	}
+
+	var msg model.JT808Msg = &model.Msg8003{
+		Header:               model.GenMsgHeader(device, 0x8003, session.GetNextSerialNum()),
+		OriginalSerialNumber: seg.FirstSerial,
+		RetransmitIDs:        missingIDs,
+	}
+	// 多媒体数据上传通过0x8800要求重传，多媒体ID在第一个分包中
+	if first, ok := seg.Parts[1]; ok && seg.MsgID == 0x0801 && len(first) >= 4 {
+		msg = &model.Msg8800{
+			Header:        model.GenMsgHeader(device, 0x8800, msg.GetHeader().SerialNumber),
+			MultiMediaID:  binary.BigEndian.Uint32(first),
+			RetransmitIDs: missingIDs,
+		}
+	}
+	frames, err := NewJT808PacketCodec().Encode(msg, session)
+	if err != nil {
+		log.Error().Err(err).Str("device", seg.Phone).Msg("Fail to encode segment retransmit request")
+		return
+	}
+	for _, frame := range frames {
+		if err := session.Enqueue(frame); err != nil {
+			log.Error().Err(err).Str("device", seg.Phone).Msg("Fail to send segment retransmit request")
+			return
+		}
+	}
+	log.Debug().Str("device", seg.Phone).Uint16("msgId", seg.MsgID).Interface("missing", missingIDs).
+		Msg("Request segment retransmit")
+}
+
 // 收到通用应答
 // 根据消息内容，确定平台下发内容是否生效
 func processMsg0001(ctx context.Context, data *model.ProcessData) error {
@@ -290,13 +490,39 @@ func processMsg0003(_ context.Context, data *model.ProcessData) error {
 	timer.Cancel(device.Phone)
 	// 清楚缓存
 	cache.DelDeviceByPhone(device.Phone)
+	storage.GetKeyCache().DelDeviceKey(device.Phone)
+	storage.GetTransparentCache().DelEvents(device.Phone)
 	// 为避免连接TIMEWAIT，应等待对方主动关闭
 	return nil
 }
 
+// 收到补传分包请求，从缓存中找到已发送的分包按顺序重发
+func processMsg0005(ctx context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0005)
+	frames, err := storage.GetSentFragments(in.Header.PhoneNumber, in.OriginalSerialNumber, in.RetransmitIDs)
+	if err != nil {
+		log.Warn().Err(err).Str("device", in.Header.PhoneNumber).Uint16("serialNumber", in.OriginalSerialNumber).
+			Msg("Fail to find fragments to retransmit")
+		return nil
+	}
+
+	session, ok := ctx.Value(model.SessionCtxKey{}).(*model.Session)
+	if !ok || session == nil {
+		return errors.New("Fail to find session to retransmit fragments")
+	}
+	for _, frame := range frames {
+		if err := session.Enqueue(frame); err != nil {
+			return errors.Wrap(err, "Fail to retransmit fragment")
+		}
+	}
+	return nil
+}
+
 // 收到注册，应校验设备ID，如果可注册，则缓存设备信息并返回鉴权码
 func processMsg0100(ctx context.Context, data *model.ProcessData) error {
 	in := data.Incoming.(*model.Msg0100)
+	// 重复注册也会返回鉴权码，均视为完成注册
+	setSessionState(ctx, model.SessionStateRegistered)
 
 	cache := storage.GetDeviceCache()
 	// 校验注册逻辑
@@ -309,6 +535,9 @@ func processMsg0100(ctx context.Context, data *model.ProcessData) error {
 			// 当出现重复注册时，将已注册的authcode返回
 			// 正常逻辑不应该返回authcode
 			device, _ := cache.GetDeviceByPlate(in.PlateNumber)
+			if device.Phone == in.Header.PhoneNumber {
+				device = refreshDeviceVersion(cache, device, in.Header)
+			}
 			out.AuthCode = genAuthCode(device) // 设置鉴权码
 		}
 		return nil
@@ -321,6 +550,7 @@ func processMsg0100(ctx context.Context, data *model.ProcessData) error {
 			// 当出现重复注册时，将已注册的authcode返回
 			// 正常逻辑不应该返回authcode
 			device, _ := cache.GetDeviceByPhone(in.Header.PhoneNumber)
+			device = refreshDeviceVersion(cache, device, in.Header)
 			out.AuthCode = genAuthCode(device) // 设置鉴权码
 		}
 		return nil
@@ -337,8 +567,20 @@ func processMsg0100(ctx context.Context, data *model.ProcessData) error {
 	return nil
 }
 
+// 终端重复注册时可能升级了协议版本，缓存中的设备会被其他协程读取，修改副本后重新缓存
+func refreshDeviceVersion(cache *storage.DeviceCache, device *model.Device, header *model.MsgHeader) *model.Device {
+	if device.VersionDesc == header.Attr.VersionDesc && device.ProtocolVersion == header.ProtocolVersion {
+		return device
+	}
+	updated := *device
+	updated.VersionDesc = header.Attr.VersionDesc
+	updated.ProtocolVersion = header.ProtocolVersion
+	cache.CacheDevice(&updated)
+	return &updated
+}
+
 // 收到鉴权，应校验鉴权token
-func processMsg0102(_ context.Context, data *model.ProcessData) error {
+func processMsg0102(ctx context.Context, data *model.ProcessData) error {
 	in := data.Incoming.(*model.Msg0102)
 
 	cache := storage.GetDeviceCache()
@@ -350,7 +592,8 @@ func processMsg0102(_ context.Context, data *model.ProcessData) error {
 
 	out := data.Outgoing.(*model.Msg8001)
 	// 校验鉴权逻辑
-	if in.AuthCode != genAuthCode(device) {
+	session, _ := ctx.Value(model.SessionCtxKey{}).(*model.Session)
+	if in.AuthCode != genAuthCode(device) || !matchCertIdentity(device, session) {
 		out.Result = model.ResultFail
 		// 取消定时任务
 		timer := NewKeepaliveTimer()
@@ -359,17 +602,164 @@ func processMsg0102(_ context.Context, data *model.ProcessData) error {
 		cache.DelDeviceByPhone(device.Phone)
 	} else {
 		// 鉴权通过
+		setSessionState(ctx, model.SessionStateAuthenticated)
 		device.LastComTime = time.Now()
 		device.AuthCode = in.AuthCode
 		device.IMEI = in.IMEI
 		device.SoftwareVersion = in.SoftwareVersion
 		// cache.CacheDevice(device)
 		cache.UpdateDeviceStatus(device, model.DeviceStatusOnline)
+		// 升级后重连鉴权上报的版本号用于确认升级结果
+		storage.GetCampaignCache().ReportVersion(device.Phone, in.SoftwareVersion)
 	}
 
 	return nil
 }
 
+// 收到升级结果通知，更新升级任务中终端的进度
+func processMsg0108(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0108)
+	log.Info().Str("device", in.Header.PhoneNumber).Str("upgradeType", model.UpgradeType2Str(in.UpgradeType)).
+		Str("result", model.Msg0108Result2Str(in.Result)).Msg("Received upgrade result")
+	storage.GetCampaignCache().ReportResult(in.Header.PhoneNumber, in.UpgradeType, in.Result)
+	return nil
+}
+
+func setSessionState(ctx context.Context, state model.SessionState) {
+	if session, ok := ctx.Value(model.SessionCtxKey{}).(*model.Session); ok && session != nil {
+		session.SetState(state)
+	}
+}
+
+// 收到多媒体事件信息，终端随后通过0x0801上传多媒体数据
+func processMsg0800(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0800)
+	log.Debug().Str("device", in.Header.PhoneNumber).Uint32("mediaId", in.MultiMediaID).
+		Uint8("eventId", in.EventID).Uint8("channelId", in.LogicChannelID).Msg("Received multimedia event")
+	return nil
+}
+
+// 收到多媒体数据(分包已组装完成)，保存为文件并记录索引
+func processMsg0801(_ context.Context, data *model.ProcessData) error {
+	cache := storage.GetDeviceCache()
+	_, err := cache.GetDeviceByPhone(data.Incoming.GetHeader().PhoneNumber)
+
+	// 缓存不存在，说明设备不合法，需要返回错误，让服务层处理关闭
+	if errors.Is(err, storage.ErrDeviceNotFound) {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", data.Incoming.GetHeader().PhoneNumber)
+	}
+
+	in := data.Incoming.(*model.Msg0801)
+	index := &storage.MediaIndex{
+		Phone:      in.Header.PhoneNumber,
+		MediaID:    in.MultiMediaID,
+		MediaType:  in.MultiMediaType,
+		Format:     in.MultiMediaContainer,
+		EventID:    in.EventID,
+		ChannelID:  in.LogicChannelID,
+		Latitude:   in.Location.Latitude,
+		Longitude:  in.Location.Longitude,
+		GeoTime:    in.Location.Time,
+		ReceivedAt: time.Now(),
+	}
+	err = storage.GetMediaStore().SaveMedia(index, model.MediaFormatExt(in.MultiMediaContainer), in.FragmentData)
+	if err != nil {
+		// 保存失败不影响应答，避免终端反复重传
+		log.Error().Err(err).Str("device", index.Phone).Uint32("mediaId", index.MediaID).Msg("Fail to save multimedia")
+	}
+	return nil
+}
+
+// 收到摄像头立即拍摄命令应答，回调等待中的0x8801请求
+func processMsg0805(ctx context.Context, data *model.ProcessData) error {
+	cache := storage.GetDeviceCache()
+	_, err := cache.GetDeviceByPhone(data.Incoming.GetHeader().PhoneNumber)
+
+	// 缓存不存在，说明设备不合法，需要返回错误，让服务层处理关闭
+	if errors.Is(err, storage.ErrDeviceNotFound) {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", data.Incoming.GetHeader().PhoneNumber)
+	}
+
+	msg := data.Incoming.(*model.Msg0805)
+	header := msg.GetHeader()
+
+	fn := ctx.Value(model.ProcResponseCallBackKey{}).(model.ProcResponseFn)
+	_ = fn(header.PhoneNumber, 0x8801 /*专用应答，固定msgid*/, msg.AnswerSerialNumber, msg)
+
+	return nil
+}
+
+// 收到上行透传，按消息类型解析后保存为事件
+func processMsg0900(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0900)
+	phone := in.Header.PhoneNumber
+	if _, err := storage.GetDeviceCache().GetDeviceByPhone(phone); err != nil {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", phone)
+	}
+
+	event := decodeTransparent(phone, in.MsgType, in.Data)
+	if event.Error != "" {
+		log.Warn().Str("device", phone).Uint8("msgType", in.MsgType).Str("err", event.Error).
+			Msg("Fail to decode transparent data")
+	}
+	storage.GetTransparentCache().CacheEvent(event)
+	return nil
+}
+
+// 收到终端RSA公钥，缓存后用于加密下发的消息体。
+// 终端主动上报时回复平台RSA公钥；平台已下发过公钥时(终端的应答)只回复通用应答
+func processMsg0A00(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0A00)
+	phone := in.Header.PhoneNumber
+	if _, err := storage.GetDeviceCache().GetDeviceByPhone(phone); err != nil {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", phone)
+	}
+
+	// 回复通用应答
+	reply := func(result model.ResultCode) {
+		out := &model.Msg8001{}
+		_ = out.GenOutgoing(in)
+		out.Header.SerialNumber = data.Outgoing.GetHeader().SerialNumber // 沿用已分配的流水号
+		out.Result = result
+		data.Outgoing = out
+	}
+
+	pub := in.PublicKey()
+	if pub.N.BitLen() == 0 || pub.E <= 1 {
+		reply(model.ResultFail)
+		return nil
+	}
+
+	keyCache := storage.GetKeyCache()
+	keyCache.StoreDeviceKey(phone, pub)
+	if keyCache.MarkPlatformKeySent(phone) {
+		reply(model.ResultSuccess)
+		return nil
+	}
+	out := data.Outgoing.(*model.Msg8A00)
+	out.SetPublicKey(getEncryption().publicKey())
+	return nil
+}
+
+// GenMsg8A00 生成下发平台RSA公钥的消息，由平台发起密钥交换
+func GenMsg8A00(header *model.MsgHeader) *model.Msg8A00 {
+	msg := &model.Msg8A00{Header: header}
+	msg.SetPublicKey(getEncryption().publicKey())
+	storage.GetKeyCache().MarkPlatformKeySent(header.PhoneNumber)
+	return msg
+}
+
+// 双向TLS认证时，连接的证书身份需要与注册时绑定的一致
+func matchCertIdentity(d *model.Device, session *model.Session) bool {
+	if d.CertIdentity == "" {
+		return true
+	}
+	if session == nil || session.PeerIdentity == nil {
+		return false
+	}
+	return session.PeerIdentity.CommonName == d.CertIdentity
+}
+
 func genAuthCode(d *model.Device) string {
 	var splitByte byte = '_'
 	codeBuilder := new(strings.Builder)
@@ -394,8 +784,13 @@ func processMsg0104(ctx context.Context, data *model.ProcessData) error {
 	msg := data.Incoming.(*model.Msg0104)
 	header := msg.GetHeader()
 
+	// 0x0104不携带应答消息ID，按流水号依次匹配0x8104、0x8106
 	fn := ctx.Value(model.ProcResponseCallBackKey{}).(model.ProcResponseFn)
-	_ = fn(header.PhoneNumber, 0x8104 /*专用应答，固定msgid*/, msg.AnswerSerialNumber, msg)
+	for _, ansMsgID := range []uint16{0x8104, 0x8106} {
+		if fn(header.PhoneNumber, ansMsgID, msg.AnswerSerialNumber, msg) == nil {
+			break
+		}
+	}
 
 	return nil
 }
@@ -411,21 +806,93 @@ func processMsg0200(_ context.Context, data *model.ProcessData) error {
 		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", in.Header.PhoneNumber)
 	}
 
+	return saveLocation(device, in, false)
+}
+
+// 收到位置信息查询应答，更新位置并回调等待中的0x8201请求
+func processMsg0201(ctx context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0201)
+
+	cache := storage.GetDeviceCache()
+	device, err := cache.GetDeviceByPhone(in.Header.PhoneNumber)
+	// 缓存不存在，说明设备不合法，需要返回错误，让服务层处理关闭
+	if errors.Is(err, storage.ErrDeviceNotFound) {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", in.Header.PhoneNumber)
+	}
+	err = saveLocation(device, in.Location, false)
+	if err != nil {
+		return err
+	}
+
+	fn := ctx.Value(model.ProcResponseCallBackKey{}).(model.ProcResponseFn)
+	_ = fn(in.Header.PhoneNumber, 0x8201 /*专用应答，固定msgid*/, in.AnswerSerialNumber, in)
+
+	return nil
+}
+
+// 收到查询区域或线路数据应答，应答不带流水号，按消息ID回调等待中的0x8608请求
+func processMsg0608(ctx context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0608)
+
+	_, err := storage.GetDeviceCache().GetDeviceByPhone(in.Header.PhoneNumber)
+	// 缓存不存在，说明设备不合法，需要返回错误，让服务层处理关闭
+	if errors.Is(err, storage.ErrDeviceNotFound) {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", in.Header.PhoneNumber)
+	}
+
+	fn := ctx.Value(model.ProcResponseByMsgIDCallBackKey{}).(model.ProcResponseByMsgIDFn)
+	_ = fn(in.Header.PhoneNumber, 0x8608 /*专用应答，固定msgid*/, in)
+
+	return nil
+}
+
+// 收到定位数据批量上传，按定位时间顺序保存，补报的历史位置不会作为最新位置
+func processMsg0704(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg0704)
+
+	cache := storage.GetDeviceCache()
+	device, err := cache.GetDeviceByPhone(in.Header.PhoneNumber)
+	// 缓存不存在，说明设备不合法，需要返回错误，让服务层处理关闭
+	if errors.Is(err, storage.ErrDeviceNotFound) {
+		return errors.Wrapf(err, "Fail to find device cache, phoneNumber=%s", in.Header.PhoneNumber)
+	}
+
+	locations := append([]*model.Msg0200{}, in.Locations...)
+	sort.SliceStable(locations, func(i, j int) bool {
+		return hex.ParseTime(locations[i].Time).Before(hex.ParseTime(locations[j].Time))
+	})
+	blindZone := in.Type == model.LocationBatchBlindZone
+	for _, loc := range locations {
+		if err = saveLocation(device, loc, blindZone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 保存位置信息和附带的告警信息
+func saveLocation(device *model.Device, in *model.Msg0200, blindZone bool) error {
 	// 解析状态位编码
-	dg := &model.DeviceGeo{}
-	err = dg.Decode(device.Phone, in)
+	dg := &model.DeviceGeo{BlindZone: blindZone}
+	err := dg.Decode(device.Phone, in)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to decode device geo, phoneNumber=%s", device.Phone)
 	}
 
-	if dg.Geo.ACCStatus == 0 { // ACC关闭，设备休眠
+	geoCache := storage.GetGeoCache()
+	latest := geoCache.SaveGeoInfoByPhone(device.Phone, dg)
+
+	// 只根据最新位置更新设备状态
+	if latest && dg.Geo.ACCStatus == 0 { // ACC关闭，设备休眠
 		// device.Status = model.DeviceStatusSleeping
 		device.LastComTime = time.Now()
-		cache.UpdateDeviceStatus(device, model.DeviceStatusSleeping)
+		storage.GetDeviceCache().UpdateDeviceStatus(device, model.DeviceStatusSleeping)
 	}
 
-	geoCache := storage.GetGeoCache()
-	geoCache.SaveGeoInfoByPhone(device.Phone, dg)
+	// 补报的历史位置不参与围栏判断，避免打乱进出状态
+	if latest {
+		geofence.GetEngine().Evaluate(dg)
+	}
 
 	// 尝试解析是否有告警信息上报
 	for i := 0; i < len(in.Extra); i++ {
@@ -545,7 +1012,7 @@ func processMsg8104(_ context.Context, data *model.ProcessData) error {
 			"00000031026C64" +
 			"000000320409302130" +
 			"0000007623030000010100010202000103030001"
-		_ = out.Parameters.Decode(out.Header.PhoneNumber, uint8(paramCnt), hex.Str2Byte(paramByteStr))
+		_ = out.Parameters.Decode(out.Header.PhoneNumber, uint8(paramCnt), hex.NewReader(hex.Str2Byte(paramByteStr)))
 		paramCache.CacheDeviceParams(out.Parameters)
 	} else {
 		out.Parameters = params
@@ -554,6 +1021,72 @@ func processMsg8104(_ context.Context, data *model.ProcessData) error {
 	return nil
 }
 
+// 收到查询指定终端参数请求，回复指定的终端参数(此时是作为client进程)
+func processMsg8106(ctx context.Context, data *model.ProcessData) error {
+	err := processMsg8104(ctx, data)
+	if err != nil {
+		return err
+	}
+	in := data.Incoming.(*model.Msg8106)
+	out := data.Outgoing.(*model.Msg0104)
+	out.Parameters = out.Parameters.Select(in.ParamIDs)
+	return nil
+}
+
+// 收到终端控制，交由注册的处理方法执行(此时是作为client进程)
+func processMsg8105(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg8105)
+	out := data.Outgoing.(*model.Msg0001)
+	if in.Command == model.TerminalCommandConnectServer {
+		if _, err := in.ConnectServerParams(); err != nil {
+			out.Result = uint8(model.ResultErrMsg)
+			return nil
+		}
+	}
+	result, afterSend := handleTerminalControl(in)
+	out.Result = uint8(result)
+	data.AfterSend = afterSend
+	return nil
+}
+
+// 收到位置信息查询，回复当前位置(此时是作为client进程)
+func processMsg8201(_ context.Context, data *model.ProcessData) error {
+	out := data.Outgoing.(*model.Msg0201)
+	out.Location = &model.Msg0200{Header: out.Header, Time: hex.FormatTime(time.Now())}
+	dg, err := storage.GetGeoCache().GetGeoLatestByPhone(out.Header.PhoneNumber)
+	if err != nil {
+		return nil // 未定位时回复空位置
+	}
+	out.Location.StatusSign = dg.Geo.Encode()
+	out.Location.Latitude = uint32(dg.Location.Latitude * model.LocationAccuracy)
+	out.Location.Longitude = uint32(dg.Location.Longitude * model.LocationAccuracy)
+	out.Location.Altitude = dg.Location.Altitude
+	out.Location.Speed = uint16(dg.Drive.Speed * model.SpeedAccuracy)
+	out.Location.Direction = dg.Drive.Direction
+	return nil
+}
+
+// 收到摄像头立即拍摄命令，模拟拍摄结果(此时是作为client进程)
+func processMsg8801(_ context.Context, data *model.ProcessData) error {
+	in := data.Incoming.(*model.Msg8801)
+	out := data.Outgoing.(*model.Msg0805)
+	out.Result = 0
+	cnt := 0
+	switch in.ShootCommand {
+	case model.ShootCommandStop:
+	case model.ShootCommandRecord:
+		cnt = 1
+	default:
+		cnt = int(in.ShootCommand)
+	}
+	// 以流水号生成多媒体ID，便于与0x0801上传的数据对应
+	out.MediaIDs = make([]uint32, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		out.MediaIDs = append(out.MediaIDs, uint32(in.Header.SerialNumber)<<16|uint32(i+1))
+	}
+	return nil
+}
+
 func processMsg9205(_ context.Context, data *model.ProcessData) error {
 	in := data.Incoming.(*model.Msg9205)
 	out := data.Outgoing.(*model.Msg1205)
@@ -0,0 +1,266 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestProcessMsg0704_BlindZone(t *testing.T) {
+	const phone = "013800000005"
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Status: model.DeviceStatusOnline})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+	geoCache := storage.GetGeoCache()
+	defer geoCache.DelGeoByPhone(phone)
+
+	header := &model.MsgHeader{MsgID: 0x0200, Attr: &model.MsgBodyAttr{VersionDesc: model.Version2013}, PhoneNumber: phone}
+	// 当前位置
+	current := &model.Msg0200{Header: header, StatusSign: 1, Latitude: 3, Time: "230101130000"}
+	require.Nil(t, processMsg0200(context.Background(), &model.ProcessData{Incoming: current}))
+
+	// 盲区补报的位置乱序到达，均早于当前位置
+	batch := &model.Msg0704{
+		Header: header,
+		Type:   model.LocationBatchBlindZone,
+		Locations: []*model.Msg0200{
+			{Header: header, StatusSign: 1, Latitude: 2, Time: "230101120500"},
+			{Header: header, StatusSign: 1, Latitude: 1, Time: "230101120000"},
+		},
+	}
+	require.Nil(t, processMsg0704(context.Background(), &model.ProcessData{Incoming: batch}))
+
+	latest, err := geoCache.GetGeoLatestByPhone(phone)
+	require.Nil(t, err)
+	require.Equal(t, 3e-6, latest.Location.Latitude)
+	require.False(t, latest.BlindZone)
+
+	// 历史记录按定位时间顺序写入
+	rb := geoCache.GetGeoRingByPhone(phone)
+	require.Equal(t, 1e-6, rb.Container[1].(*model.DeviceGeo).Location.Latitude)
+	require.Equal(t, 2e-6, rb.Container[2].(*model.DeviceGeo).Location.Latitude)
+	require.True(t, rb.Container[2].(*model.DeviceGeo).BlindZone)
+
+	// 补报的位置晚于当前位置时作为最新位置
+	batch.Locations = []*model.Msg0200{{Header: header, StatusSign: 1, Latitude: 4, Time: "230101140000"}}
+	require.Nil(t, processMsg0704(context.Background(), &model.ProcessData{Incoming: batch}))
+	latest, err = geoCache.GetGeoLatestByPhone(phone)
+	require.Nil(t, err)
+	require.Equal(t, 4e-6, latest.Location.Latitude)
+}
+
+func TestProcessMsg0201(t *testing.T) {
+	const phone = "013800000006"
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Status: model.DeviceStatusOnline})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+	defer storage.GetGeoCache().DelGeoByPhone(phone)
+
+	var answered uint16
+	ctx := context.WithValue(context.Background(), model.ProcResponseCallBackKey{},
+		model.ProcResponseFn(func(_ string, ansMsgID uint16, ansSN uint16, _ any) error {
+			require.Equal(t, uint16(0x8201), ansMsgID)
+			answered = ansSN
+			return nil
+		}))
+	header := &model.MsgHeader{MsgID: 0x0201, Attr: &model.MsgBodyAttr{VersionDesc: model.Version2013}, PhoneNumber: phone}
+	in := &model.Msg0201{
+		Header:             header,
+		AnswerSerialNumber: 8,
+		Location:           &model.Msg0200{Header: header, StatusSign: 1, Latitude: 5, Time: "230101120000"},
+	}
+	require.Nil(t, processMsg0201(ctx, &model.ProcessData{Incoming: in}))
+	require.Equal(t, uint16(8), answered)
+
+	latest, err := storage.GetGeoCache().GetGeoLatestByPhone(phone)
+	require.Nil(t, err)
+	require.Equal(t, 5e-6, latest.Location.Latitude)
+}
+
+func TestProcessMsg0100_Reregister(t *testing.T) {
+	const phone, plate = "013800000010", "plate-0100"
+	tests := []struct {
+		name        string
+		phone       string
+		plate       string
+		version     model.VersionType
+		protoVer    uint8
+		wantResult  model.ResultCodeType
+		wantVersion model.VersionType
+		wantProto   uint8
+	}{
+		{name: "case1: same plate and phone", phone: phone, plate: plate, version: model.Version2019, protoVer: 1, wantResult: model.ResCarAlreadyRegister, wantVersion: model.Version2019, wantProto: 1},
+		{name: "case2: same phone, new plate", phone: phone, plate: "plate-other", version: model.Version2013, wantResult: model.ResDeviceAlreadyRegister, wantVersion: model.Version2013},
+		{name: "case3: plate of another device", phone: "013800000011", plate: plate, version: model.Version2013, wantResult: model.ResCarAlreadyRegister, wantVersion: model.Version2011},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached := &model.Device{Phone: phone, Plate: plate, VersionDesc: model.Version2011}
+			storage.GetDeviceCache().CacheDevice(cached)
+			defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+
+			header := &model.MsgHeader{MsgID: 0x0100, Attr: &model.MsgBodyAttr{VersionDesc: tt.version}, ProtocolVersion: tt.protoVer, PhoneNumber: tt.phone}
+			out := &model.Msg8100{}
+			in := &model.Msg0100{Header: header, PlateNumber: tt.plate}
+			require.Nil(t, processMsg0100(context.Background(), &model.ProcessData{Incoming: in, Outgoing: out}))
+			require.Equal(t, tt.wantResult, out.Result)
+
+			device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+			require.Nil(t, err)
+			require.Equal(t, tt.wantVersion, device.VersionDesc)
+			require.Equal(t, tt.wantProto, device.ProtocolVersion)
+			// 不修改其他协程可能持有的设备
+			require.Equal(t, model.Version2011, cached.VersionDesc)
+		})
+	}
+}
+
+func TestProcessMsg0801(t *testing.T) {
+	const phone = "013800000009"
+	storage.GetMediaStore().SetMediaDir(t.TempDir())
+	defer storage.GetMediaStore().SetMediaDir("")
+
+	header := &model.MsgHeader{MsgID: 0x0801, Attr: &model.MsgBodyAttr{VersionDesc: model.Version2013}, PhoneNumber: phone}
+	in := &model.Msg0801{
+		Header:       header,
+		MultiMediaID: 1,
+		Location:     &model.Msg0200{Header: header, Time: "230101120000"},
+		FragmentData: []byte{0xff, 0xd8},
+	}
+
+	// 未注册的终端不保存
+	require.ErrorIs(t, processMsg0801(context.Background(), &model.ProcessData{Incoming: in}), storage.ErrDeviceNotFound)
+	indexes, err := storage.GetMediaStore().ListMedia(phone)
+	require.Nil(t, err)
+	require.Empty(t, indexes)
+
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Status: model.DeviceStatusOnline})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+	require.Nil(t, processMsg0801(context.Background(), &model.ProcessData{Incoming: in}))
+	indexes, err = storage.GetMediaStore().ListMedia(phone)
+	require.Nil(t, err)
+	require.Len(t, indexes, 1)
+}
+
+func TestProcessMsg8105(t *testing.T) {
+	header := &model.MsgHeader{MsgID: 0x8105, Attr: &model.MsgBodyAttr{VersionDesc: model.Version2013}, PhoneNumber: "013800000008"}
+	handled := make(chan model.TerminalCommand, 1)
+	tests := []struct {
+		name       string
+		msg        *model.Msg8105
+		register   bool
+		wantResult model.ResultCode
+	}{
+		{name: "case1: no handler", msg: &model.Msg8105{Header: header, Command: model.TerminalCommandReset}, wantResult: model.ResultNotSupported},
+		{name: "case2: invalid params", msg: &model.Msg8105{Header: header, Command: model.TerminalCommandConnectServer, Params: "0;auth"}, register: true, wantResult: model.ResultErrMsg},
+		{name: "case3: handled", msg: &model.Msg8105{Header: header, Command: model.TerminalCommandConnectServer, Params: "1"}, register: true, wantResult: model.ResultSuccess},
+	}
+	defer RegisterTerminalControlHandler(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterTerminalControlHandler(nil)
+			if tt.register {
+				RegisterTerminalControlHandler(func(_ string, msg *model.Msg8105) error {
+					handled <- msg.Command
+					return nil
+				})
+			}
+			out := &model.Msg0001{}
+			data := &model.ProcessData{Incoming: tt.msg, Outgoing: out}
+			require.Nil(t, processMsg8105(context.Background(), data))
+			require.Equal(t, uint8(tt.wantResult), out.Result)
+			if tt.wantResult != model.ResultSuccess {
+				require.Nil(t, data.AfterSend)
+				return
+			}
+			// 应答发出前不执行
+			select {
+			case <-handled:
+				t.Fatal("terminal control handled before ack was sent")
+			case <-time.After(50 * time.Millisecond):
+			}
+			data.AfterSend()
+			require.Equal(t, tt.msg.Command, <-handled)
+		})
+	}
+}
+
+func TestProcessMsg0108_Campaign(t *testing.T) {
+	phones := []string{"013800000011", "013800000012", "013800000013"}
+	online := func(string) bool { return true }
+	cache := storage.GetCampaignCache()
+	require.Nil(t, cache.SetCampaignDir(t.TempDir()))
+	c, err := cache.CreateCampaign(&storage.Campaign{
+		FirmwareID:  "fw",
+		UpgradeType: model.UpgradeTypeTerminal,
+		Version:     "2.0.0",
+		StageSize:   2,
+		Concurrency: 1,
+	}, phones)
+	require.Nil(t, err)
+	require.Equal(t, 2, c.Stages)
+
+	// 并发数为1，每次只下发一个终端
+	tasks := cache.AcquireTasks(online)
+	require.Len(t, tasks, 1)
+	require.Equal(t, phones[0], tasks[0].Phone)
+	cache.MarkSent(c.ID, phones[0], nil)
+	require.Len(t, cache.AcquireTasks(online), 0)
+
+	// 先鉴权上报新版本，再通知升级成功
+	cache.ReportVersion(phones[0], "2.0.0")
+	header := &model.MsgHeader{MsgID: 0x0108, Attr: &model.MsgBodyAttr{VersionDesc: model.Version2019}, PhoneNumber: phones[0]}
+	in := &model.Msg0108{Header: header, UpgradeType: model.UpgradeTypeTerminal, Result: model.UpgradeResultSuccess}
+	require.Nil(t, processMsg0108(context.Background(), &model.ProcessData{Incoming: in}))
+
+	// 第二个终端通知成功后上报的版本号不一致
+	tasks = cache.AcquireTasks(online)
+	require.Len(t, tasks, 1)
+	require.Equal(t, phones[1], tasks[0].Phone)
+	cache.MarkSent(c.ID, phones[1], nil)
+	cache.ReportResult(phones[1], model.UpgradeTypeTerminal, model.UpgradeResultSuccess)
+	cache.ReportVersion(phones[1], "1.0.0")
+
+	// 第一批完成后进入第二批
+	tasks = cache.AcquireTasks(online)
+	require.Len(t, tasks, 1)
+	require.Equal(t, phones[2], tasks[0].Phone)
+	cache.MarkSent(c.ID, phones[2], nil)
+	cache.ReportResult(phones[2], model.UpgradeTypeTerminal, model.UpgradeResultFail)
+
+	got, err := cache.GetCampaign(c.ID)
+	require.Nil(t, err)
+	require.Equal(t, storage.CampaignFinished, got.Status)
+	require.Equal(t, storage.CampaignSummary{storage.UpgradeSucceeded: 1, storage.UpgradeFailed: 2}, got.Summary())
+	require.Equal(t, "version mismatch", got.Devices[1].Error)
+}
+
+func TestProcessMsg0608(t *testing.T) {
+	const phone = "00000000013800000009"
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Status: model.DeviceStatusOnline})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+
+	sender := NewSender()
+	answered := make([]uint16, 0)
+	for _, sn := range []uint16{1, 65535} {
+		sn := sn
+		k := &SenderKey{Phone: phone, MsgId: 0x8608, SerialNumber: sn}
+		require.Nil(t, sender.Append(k, &SenderValue{Phone: phone, ResponseCallBack: func(any) error {
+			answered = append(answered, sn)
+			return nil
+		}}))
+		defer sender.Remove(k)
+	}
+
+	// 0x0608不带应答流水号，按流水号先后依次匹配，65535之后回绕到1
+	ctx := context.WithValue(context.Background(), model.ProcResponseByMsgIDCallBackKey{},
+		model.ProcResponseByMsgIDFn(sender.ProcResponseByMsgID))
+	header := &model.MsgHeader{MsgID: 0x0608, Attr: &model.MsgBodyAttr{VersionSign: 1, VersionDesc: model.Version2019}, PhoneNumber: phone}
+	for i := 0; i < 2; i++ {
+		require.Nil(t, processMsg0608(ctx, &model.ProcessData{Incoming: &model.Msg0608{Header: header, QueryType: model.AreaTypeCircle}}))
+	}
+	require.Equal(t, []uint16{65535, 1}, answered)
+}
@@ -15,18 +15,21 @@ const (
 	escapeMark   = 0x7d
 	escapeOne    = 0x01
 	escapeTwo    = 0x02
+
+	maxBodyLength = 1023 // 消息体属性中长度占10位，超过时需要分包
 )
 
 var (
 	ErrEmptyPacket  = errors.New("Empty packet")
 	ErrVerifyFailed = errors.New("Verify failed")
 	ErrEncodeType   = errors.New("Error data type")
+	ErrInvalidFrag  = errors.New("Invalid packet fragmentation")
 )
 
 type PacketCodec interface {
 	Decode([]byte) (*model.PacketData, error)
 
-	Encode(any) ([]byte, error)
+	Encode(any, *model.Session) ([][]byte, error)
 }
 
 type JT808PacketCodec struct {
@@ -38,14 +41,19 @@ var codecOnce sync.Once
 func NewJT808PacketCodec() *JT808PacketCodec {
 	codecOnce.Do(func() {
 		jt808PacketCodec = &JT808PacketCodec{}
+		// 分包超时未收齐时下发0x8003请求补传
+		storage.SetSegmentRetransmitHook(requestSegmentRetransmit)
 	})
 	return jt808PacketCodec
 }
 
 // Decode JT808 packet.
 //
-// 反转义 -> 校验 -> 反序列化
+// 反转义 -> 校验 -> 反序列化 -> 分包组装 -> 解密
 func (pc *JT808PacketCodec) Decode(payload []byte) (*model.PacketData, error) {
+	if len(payload) == 0 {
+		return nil, ErrEmptyPacket
+	}
 	pkt := pc.unescape(payload)
 
 	verifyCode := payload[len(payload)-1]
@@ -63,14 +71,34 @@ func (pc *JT808PacketCodec) Decode(payload []byte) (*model.PacketData, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "Fail to decode packet")
 	}
+	resolveVersion(pd.Header)
 
 	pd.Body = pkt[pd.Header.Idx:]
 
 	if pd.Header.IsFragmented() {
-		seg := model.NewSegment(pd)
-		pd.SegCompleted = storage.CacheSegment(seg)
-		// 分包接收完成
-		pd.Body = seg.Data
+		frag := pd.Header.Frag
+		if frag.Index == 0 || frag.Index > frag.Total {
+			return nil, ErrInvalidFrag
+		}
+		seg, completed, err := storage.CacheSegment(model.NewSegment(pd))
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to cache segment")
+		}
+		pd.SegCompleted = completed
+		if completed {
+			// 分包接收完成，使用组装后的消息体
+			pd.Body = seg.Data
+			pd.Header.Attr.BodyLength = uint16(len(seg.Data))
+		}
+	}
+
+	// 加密的分包在组装完成后整体解密
+	if !pd.Header.IsFragmented() || pd.SegCompleted {
+		pd.Body, err = getEncryption().decryptBody(pd.Header, pd.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to decrypt packet body")
+		}
+		pd.Header.Attr.BodyLength = uint16(len(pd.Body))
 	}
 
 	pd.Header.Idx = 0 // reset idx
@@ -78,25 +106,104 @@ func (pc *JT808PacketCodec) Decode(payload []byte) (*model.PacketData, error) {
 	return pd, nil
 }
 
+// DecodeHeader 仅解析消息头，不处理分包缓存等副作用。
+//
+// 用于在完整处理消息前识别终端，如UDP按手机号关联会话。
+func (pc *JT808PacketCodec) DecodeHeader(payload []byte) (*model.MsgHeader, error) {
+	pkt, err := pc.verify(pc.unescape(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	header := &model.MsgHeader{}
+	err = header.Decode(pkt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to decode packet header")
+	}
+	return header, nil
+}
+
 // Encode JT808 packet.
 //
-// 序列化 -> 生成校验码 -> 转义
-func (pc *JT808PacketCodec) Encode(data any) (pkt []byte, err error) {
-	if out, ok := data.(model.JT808Msg); ok {
-		pkt, err = out.Encode()
-	} else {
+// 序列化 -> 加密 -> 分包 -> 生成校验码 -> 转义
+//
+// 消息体超过1023字节时拆分为多个分包，第一个分包沿用消息的流水号，后续分包从session获取流水号，
+// session为nil时依次递增。返回的数据帧需要按顺序发送。
+func (pc *JT808PacketCodec) Encode(data any, session *model.Session) ([][]byte, error) {
+	out, ok := data.(model.JT808Msg)
+	if !ok {
 		return nil, ErrEncodeType
 	}
 
+	pkt, err := out.Encode()
 	if err != nil {
 		return nil, errors.Wrap(err, "Fail to encode jtmsg")
 	}
 
-	pkt = pc.genVerifier(pkt)
+	header := out.GetHeader()
+	headerPkt, err := header.Encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to encode jtmsg header")
+	}
+	body, err := getEncryption().encryptBody(header, pkt[len(headerPkt):])
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to encrypt jtmsg body")
+	}
+	if len(body) > maxBodyLength {
+		return pc.encodeFragments(header, body, session)
+	}
+
+	// 加密后消息体长度和加密标识有变化，重新生成消息头
+	header.Attr.BodyLength = uint16(len(body))
+	headerPkt, err = header.Encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to encode jtmsg header")
+	}
+	pkt = append(headerPkt, body...)
+	return [][]byte{pc.escape(pc.genVerifier(pkt))}, nil
+}
+
+// 将消息体拆分为多个分包，并缓存已编码的分包用于响应补传请求
+func (pc *JT808PacketCodec) encodeFragments(header *model.MsgHeader, body []byte, session *model.Session) ([][]byte, error) {
+	total := (len(body) + maxBodyLength - 1) / maxBodyLength
+	frames := make([][]byte, 0, total)
+	serial := header.SerialNumber
+	for i := 0; i < total; i++ {
+		chunk := body[i*maxBodyLength : min(len(body), (i+1)*maxBodyLength)]
 
-	payload := pc.escape(pkt)
+		attr := *header.Attr
+		attr.BodyLength = uint16(len(chunk))
+		attr.PacketFragmented = 1
+		attr.PacketFragmentedDesc = model.PacketFragmentedTrue
+		fragHeader := *header
+		fragHeader.Attr = &attr
+		fragHeader.Frag = &model.MsgFragmentation{Total: uint16(total), Index: uint16(i + 1)}
+		if i > 0 {
+			if session != nil {
+				serial = session.GetNextSerialNum()
+			} else {
+				serial++
+			}
+		}
+		fragHeader.SerialNumber = serial
 
-	return payload, nil
+		pkt, err := fragHeader.Encode()
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to encode fragment header")
+		}
+		pkt = append(pkt, chunk...)
+		frames = append(frames, pc.escape(pc.genVerifier(pkt)))
+	}
+
+	storage.CacheSentFragments(header.PhoneNumber, header.SerialNumber, frames)
+	return frames, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
 
 // Unescape JT808 packet.
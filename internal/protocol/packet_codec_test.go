@@ -1,13 +1,17 @@
 package protocol
 
 import (
+	"encoding/binary"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
 )
 
 func TestJT808PacketCodec_Decode(t *testing.T) {
@@ -98,3 +102,269 @@ func TestJT808PacketCodec_genVerifier(t *testing.T) {
 		})
 	}
 }
+
+func TestJT808PacketCodec_Encode(t *testing.T) {
+	genMsg := func(idCnt int) *model.Msg0005 {
+		ids := make([]uint16, idCnt)
+		for i := range ids {
+			ids[i] = uint16(i + 1)
+		}
+		return &model.Msg0005{
+			Header: &model.MsgHeader{
+				MsgID:           0x0005,
+				Attr:            &model.MsgBodyAttr{VersionSign: 1, VersionDesc: model.Version2019},
+				ProtocolVersion: 1,
+				PhoneNumber:     "00000000013800000001",
+				SerialNumber:    100,
+			},
+			RetransmitIDs: ids,
+		}
+	}
+	tests := []struct {
+		name       string
+		msg        *model.Msg0005
+		wantFrames int
+	}{
+		{
+			name:       "case1: single packet",
+			msg:        genMsg(10),
+			wantFrames: 1,
+		},
+		{
+			name:       "case2: body over 1023 bytes",
+			msg:        genMsg(1200), // 消息体2404字节
+			wantFrames: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewJT808PacketCodec()
+			session := &model.Session{ID: "test"}
+			frames, err := pc.Encode(tt.msg, session)
+			require.Nil(t, err)
+			require.Len(t, frames, tt.wantFrames)
+
+			body := make([]byte, 0)
+			for i, frame := range frames {
+				pkt, err := pc.verify(pc.unescape(frame))
+				require.Nil(t, err)
+				header := &model.MsgHeader{}
+				require.Nil(t, header.Decode(pkt))
+				require.LessOrEqual(t, int(header.Attr.BodyLength), maxBodyLength)
+				require.Equal(t, int(header.Attr.BodyLength), len(pkt)-header.Idx)
+				if tt.wantFrames > 1 {
+					require.True(t, header.IsFragmented())
+					require.Equal(t, uint16(tt.wantFrames), header.Frag.Total)
+					require.Equal(t, uint16(i+1), header.Frag.Index)
+				}
+				if i == 0 {
+					require.Equal(t, uint16(100), header.SerialNumber)
+				} else {
+					require.Equal(t, uint16(i), header.SerialNumber) // 后续分包使用session的流水号
+				}
+				body = append(body, pkt[header.Idx:]...)
+			}
+			require.Equal(t, 4+2*len(tt.msg.RetransmitIDs), len(body))
+
+			if tt.wantFrames > 1 {
+				// 补传请求可以找到已发送的分包
+				got, err := storage.GetSentFragments(tt.msg.Header.PhoneNumber, 100, []uint16{2, 3})
+				require.Nil(t, err)
+				require.Equal(t, frames[1:], got)
+			}
+		})
+	}
+}
+
+func TestJT808PacketCodec_Decode_fragments(t *testing.T) {
+	ids := make([]uint16, 1200)
+	msg := &model.Msg0005{
+		Header: &model.MsgHeader{
+			MsgID:           0x0005,
+			Attr:            &model.MsgBodyAttr{VersionSign: 1, VersionDesc: model.Version2019},
+			ProtocolVersion: 1,
+			PhoneNumber:     "00000000013800000002",
+			SerialNumber:    200,
+		},
+		RetransmitIDs: ids,
+	}
+	pc := NewJT808PacketCodec()
+	frames, err := pc.Encode(msg, nil)
+	require.Nil(t, err)
+	require.Len(t, frames, 3)
+
+	// 乱序到达，收齐后组装
+	for i, idx := range []int{2, 0, 1} {
+		pd, err := pc.Decode(frames[idx])
+		require.Nil(t, err)
+		if i < 2 {
+			require.False(t, pd.SegCompleted)
+			continue
+		}
+		require.True(t, pd.SegCompleted)
+		got := &model.Msg0005{}
+		require.Nil(t, got.Decode(pd))
+		require.Equal(t, uint16(1200), got.RetransmitCnt)
+	}
+	require.Equal(t, 0, storage.GetSegmentStats().Pending)
+}
+
+func TestJT808PacketCodec_Decode_retransmittedFragments(t *testing.T) {
+	pc := NewJT808PacketCodec()
+	// 2013版本分包，消息体属性第13位为分包标识
+	genFrame := func(phone string, serial, total, index uint16, body []byte) []byte {
+		pkt := []byte{0x09, 0x00}
+		pkt = binary.BigEndian.AppendUint16(pkt, uint16(len(body))|0x2000)
+		pkt = append(pkt, hex.Str2Byte(phone)...)
+		pkt = binary.BigEndian.AppendUint16(pkt, serial)
+		pkt = binary.BigEndian.AppendUint16(pkt, total)
+		pkt = binary.BigEndian.AppendUint16(pkt, index)
+		pkt = append(pkt, body...)
+		return pc.escape(pc.genVerifier(pkt))
+	}
+	// 丢弃未收齐的分包
+	defer storage.ExpireSegments(time.Now().Add(time.Hour))
+
+	t.Run("case1: retransmitted fragment with new serial", func(t *testing.T) {
+		const phone = "013800000021"
+		_, err := pc.Decode(genFrame(phone, 10, 3, 1, []byte{0x01}))
+		require.Nil(t, err)
+		_, err = pc.Decode(genFrame(phone, 12, 3, 3, []byte{0x03}))
+		require.Nil(t, err)
+
+		// 超时后请求补传，终端使用新的流水号补传第2包
+		storage.ExpireSegments(time.Now().Add(time.Minute))
+		pd, err := pc.Decode(genFrame(phone, 30, 3, 2, []byte{0x02}))
+		require.Nil(t, err)
+		require.True(t, pd.SegCompleted)
+		require.Equal(t, []byte{0x01, 0x02, 0x03}, pd.Body)
+	})
+
+	t.Run("case2: total mismatch", func(t *testing.T) {
+		const phone = "013800000022"
+		_, err := pc.Decode(genFrame(phone, 10, 3, 1, []byte{0x01}))
+		require.Nil(t, err)
+		_, err = pc.Decode(genFrame(phone, 11, 4, 2, []byte{0x02}))
+		require.ErrorIs(t, err, storage.ErrSegmentTotalMismatch)
+	})
+
+	t.Run("case3: device limit", func(t *testing.T) {
+		const phone = "013800000023"
+		before := storage.GetSegmentStats()
+		for i := 0; i < 20; i++ {
+			_, err := pc.Decode(genFrame(phone, uint16(100+i*2), 2, 1, []byte{0x01}))
+			require.Nil(t, err)
+		}
+		after := storage.GetSegmentStats()
+		require.Equal(t, before.Evicted+4, after.Evicted)
+		require.Equal(t, before.Pending+16, after.Pending)
+	})
+}
+
+func TestJT808PacketCodec_Decode_version2011(t *testing.T) {
+	const phone = "013912345679"
+	pc := NewJT808PacketCodec()
+	genPayload := func() []byte {
+		pkt := hex.Str2Byte("01020007" + phone + "000241424344454647")
+		return pc.escape(pc.genVerifier(pkt))
+	}
+
+	// 未注册时无法区分2011和2013版本
+	pd, err := pc.Decode(genPayload())
+	require.Nil(t, err)
+	require.Equal(t, model.Version2013, pd.Header.Attr.VersionDesc)
+
+	// 已注册为2011版本的终端按2011版本解析
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Plate: phone, VersionDesc: model.Version2011})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+	pd, err = pc.Decode(genPayload())
+	require.Nil(t, err)
+	require.Equal(t, model.Version2011, pd.Header.Attr.VersionDesc)
+}
+
+func TestJT808PacketCodec_Decode_register2011(t *testing.T) {
+	const phone = "013912345680"
+	// 2011版本终端注册，车牌GBK编码12字节，消息体长度与2013版本注册消息相同
+	frame := hex.Str2Byte("7e010000250139123456800005002c012c37303131314a543830382d313141303030303031" +
+		"02d4c1423132333435d3a6bcb1817e")
+	pc := NewJT808PacketCodec()
+	decode := func() *model.Msg0100 {
+		pd, err := pc.Decode(frame)
+		require.Nil(t, err)
+		msg := &model.Msg0100{}
+		require.Nil(t, msg.Decode(pd))
+		return msg
+	}
+
+	// 未配置时按2013版本解析，字段错位
+	msg := decode()
+	require.Equal(t, model.Version2013, msg.Header.Attr.VersionDesc)
+	require.NotEqual(t, "JT808-11", msg.DeviceMode)
+
+	SetVersion2011Phones([]string{phone})
+	defer SetVersion2011Phones(nil)
+	msg = decode()
+	require.Equal(t, model.Version2011, msg.Header.Attr.VersionDesc)
+	require.Equal(t, "70111", msg.ManufacturerID)
+	require.Equal(t, "JT808-11", msg.DeviceMode)
+	require.Equal(t, "A000001", msg.DeviceID)
+	require.Equal(t, "粤B12345应急", msg.PlateNumber)
+}
+
+func TestJT808PacketCodec_Encode_longText(t *testing.T) {
+	text := strings.Repeat("调度指令", 200) // GBK编码1600字节，超过单包长度
+	msg := &model.Msg8300{
+		Header: &model.MsgHeader{
+			MsgID:        0x8300,
+			Attr:         &model.MsgBodyAttr{VersionDesc: model.Version2013},
+			PhoneNumber:  "013800000007",
+			SerialNumber: 300,
+		},
+		Flag: model.TextFlagDisplay,
+		Text: text,
+	}
+	pc := NewJT808PacketCodec()
+	frames, err := pc.Encode(msg, nil)
+	require.Nil(t, err)
+	require.Len(t, frames, 2)
+
+	var pd *model.PacketData
+	for _, frame := range frames {
+		pd, err = pc.Decode(frame)
+		require.Nil(t, err)
+	}
+	require.True(t, pd.SegCompleted)
+	got := &model.Msg8300{}
+	require.Nil(t, got.Decode(pd))
+	require.Equal(t, text, got.Text)
+}
+
+func TestJT808PacketCodec_Encode_largePolygon(t *testing.T) {
+	vertices := make([]*model.AreaPoint, 200) // 200个顶点1600字节，超过单包长度
+	for i := range vertices {
+		vertices[i] = &model.AreaPoint{Latitude: uint32(30000000 + i), Longitude: uint32(120000000 + i)}
+	}
+	msg := &model.Msg8604{
+		Header: &model.MsgHeader{
+			MsgID:        0x8604,
+			Attr:         &model.MsgBodyAttr{VersionSign: 1, VersionDesc: model.Version2019},
+			PhoneNumber:  "00000000013800000008",
+			SerialNumber: 400,
+		},
+		Area: &model.PolygonArea{ID: 7, Attr: model.AreaAttrEnterAlarmPlatform, Vertices: vertices, Name: "园区"},
+	}
+	pc := NewJT808PacketCodec()
+	frames, err := pc.Encode(msg, nil)
+	require.Nil(t, err)
+	require.Len(t, frames, 2)
+
+	var pd *model.PacketData
+	for _, frame := range frames {
+		pd, err = pc.Decode(frame)
+		require.Nil(t, err)
+	}
+	require.True(t, pd.SegCompleted)
+	got := &model.Msg8604{}
+	require.Nil(t, got.Decode(pd))
+	require.Equal(t, msg.Area, got.Area)
+}
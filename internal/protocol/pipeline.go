@@ -44,6 +44,13 @@ func (p *Pipeline) ProcessConnWrite(ctx context.Context) error {
 	return p.callWithBlocking(ctx, actions)
 }
 
+// WriteMsg 将消息编码后放入session的发送队列
+func WriteMsg(session *model.Session, msg model.JT808Msg) error {
+	ctx := context.WithValue(context.Background(), model.SessionCtxKey{}, session)
+	ctx = context.WithValue(ctx, model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
+	return NewPipeline(session.Conn).ProcessConnWrite(ctx)
+}
+
 func (p *Pipeline) callWithBlocking(ctx context.Context, funcs []delegateFunc) error {
 	// todo: 重构err定义，通过errors.Cause, 区分breakErr, continueErr
 	curCtx := ctx
@@ -60,6 +67,9 @@ func (p *Pipeline) callWithBlocking(ctx context.Context, funcs []delegateFunc) e
 func recv() delegateFunc {
 	return delegateFunc(func(ctx context.Context, p *Pipeline) (context.Context, error) {
 		framePayload, err := p.fh.Recv(ctx)
+		if session, ok := ctx.Value(model.SessionCtxKey{}).(*model.Session); ok && session != nil && err == nil {
+			session.Touch()
+		}
 		nxtCtx := context.WithValue(ctx, model.FrameCtxKey{}, framePayload)
 		return nxtCtx, err
 	})
@@ -92,16 +102,33 @@ func encode() delegateFunc {
 		if pd == nil || pd.Outgoing == nil { // 不需要回复，不用后续处理
 			return nil, nil
 		}
-		pkt, err := p.pc.Encode(pd.Outgoing)
-		nxtCtx := context.WithValue(ctx, model.PacketEncodeCtxKey{}, pkt)
+		session, _ := ctx.Value(model.SessionCtxKey{}).(*model.Session)
+		frames, err := p.pc.Encode(pd.Outgoing, session)
+		nxtCtx := context.WithValue(ctx, model.PacketEncodeCtxKey{}, frames)
 		return nxtCtx, err
 	})
 }
 
+// 有session时放入session的发送队列，由写协程统一写入连接，避免多个发送方的数据帧交错
 func send() delegateFunc {
 	return delegateFunc(func(ctx context.Context, p *Pipeline) (context.Context, error) {
-		packet := ctx.Value(model.PacketEncodeCtxKey{}).([]byte)
-		err := p.fh.Send(packet)
-		return ctx, err
+		frames := ctx.Value(model.PacketEncodeCtxKey{}).([][]byte)
+		session, _ := ctx.Value(model.SessionCtxKey{}).(*model.Session)
+		// 分包按顺序发送
+		for _, frame := range frames {
+			var err error
+			if session != nil {
+				err = session.Enqueue(frame)
+			} else {
+				err = p.fh.Send(frame)
+			}
+			if err != nil {
+				return ctx, err
+			}
+		}
+		if pd, _ := ctx.Value(model.ProcessDataCtxKey{}).(*model.ProcessData); pd != nil && pd.AfterSend != nil {
+			pd.AfterSend()
+		}
+		return ctx, nil
 	})
 }
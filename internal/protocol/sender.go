@@ -6,8 +6,6 @@ import (
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 	"github.com/fakeyanss/jt808-server-go/internal/storage"
 	"github.com/rs/zerolog/log"
-	"io"
-	"net"
 	"sync"
 	"time"
 )
@@ -45,15 +43,28 @@ type Sender struct {
 	Sending []*SenderValue             `json:"sending"` // 发送队列
 	Waiting map[SenderKey]*SenderValue `json:"waiting"` // 等待相应队列
 	Mutex   *sync.Mutex
+
+	runOnce  sync.Once
+	stopOnce sync.Once
+	stop     chan struct{} // 通知run退出
+	stopped  chan struct{} // run已退出
 }
 
+// sender单例，tcp/udp server共用，终端的应答无论从哪种连接上来都能找到等待中的消息
+var senderSingleton *Sender
+var senderInitOnce sync.Once
+
 func NewSender() *Sender {
-	sender := &Sender{
-		Sending: make([]*SenderValue, 0),
-		Waiting: make(map[SenderKey]*SenderValue, 0),
-		Mutex:   &sync.Mutex{},
-	}
-	return sender
+	senderInitOnce.Do(func() {
+		senderSingleton = &Sender{
+			Sending: make([]*SenderValue, 0),
+			Waiting: make(map[SenderKey]*SenderValue, 0),
+			Mutex:   &sync.Mutex{},
+			stop:    make(chan struct{}),
+			stopped: make(chan struct{}),
+		}
+	})
+	return senderSingleton
 }
 
 func (s *Sender) Append(k *SenderKey, v *SenderValue) error {
@@ -70,6 +81,13 @@ func (s *Sender) Append(k *SenderKey, v *SenderValue) error {
 	return nil
 }
 
+// Remove 取消等待响应，用于调用方等待超时后清理
+func (s *Sender) Remove(k *SenderKey) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	delete(s.Waiting, *k)
+}
+
 // ProcResponse
 // 由外部回調
 // 处理由终端回复的响应数据
@@ -94,13 +112,47 @@ func (s *Sender) ProcResponse(phone string, ansMsgId uint16, ansSN uint16, msg a
 	return nil
 }
 
+// ProcResponseByMsgID 处理不携带应答流水号的响应，如0x0608，回调该终端最早发出的同类消息
+func (s *Sender) ProcResponseByMsgID(phone string, ansMsgId uint16, msg any) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	var key *SenderKey
+	for k := range s.Waiting {
+		if k.Phone != phone || k.MsgId != ansMsgId {
+			continue
+		}
+		if key == nil || serialBefore(k.SerialNumber, key.SerialNumber) {
+			found := k
+			key = &found
+		}
+	}
+	if key == nil {
+		err := errors.New("没有找到对应的key")
+		return err
+	}
+
+	v := s.Waiting[*key]
+	delete(s.Waiting, *key)
+	_ = v.ResponseCallBack(msg)
+	return nil
+}
+
+// 流水号循环累加，按差值判断先后
+func serialBefore(a, b uint16) bool {
+	return int16(a-b) < 0
+}
+
 // send
 // 发送数据给终端
 func (s *Sender) send() error {
+	// 取出待发送队列后释放锁，写队列阻塞时不影响其他终端的应答处理
 	s.Mutex.Lock()
-	defer s.Mutex.Unlock()
-	for i := 0; i < len(s.Sending); i++ {
-		v := s.Sending[i]
+	sending := s.Sending
+	s.Sending = make([]*SenderValue, 0, len(sending))
+	s.Mutex.Unlock()
+
+	for _, v := range sending {
 		device, err := storage.GetDeviceCache().GetDeviceByPhone(v.Phone)
 		if err != nil {
 			continue
@@ -111,37 +163,44 @@ func (s *Sender) send() error {
 			continue
 		}
 
-		msg := v.Msg.(model.JT808Msg)
-		pg := NewPipeline(session.Conn)
-
-		// 记录value ctx
-		ctx := context.WithValue(context.Background(), model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
-
-		err = pg.ProcessConnWrite(ctx)
+		// 写连接失败时由session的写协程关闭连接，读协程负责清理session
+		err = WriteMsg(session, v.Msg.(model.JT808Msg))
 		if err != nil {
-			if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) {
-				session.Conn.Close()
-				storage.ClearSession(session.ID)
-				// delete(serv.sessions, session.ID)
-
-				log.Debug().Str("sessionId", session.ID).Msg("Closing connection from remote.")
-			}
-
 			log.Error().Err(err).Str("device", session.ID).Msg("Failed to send jtmsg to device")
-			break
 		}
 	}
-
-	s.Sending = s.Sending[:0]
 	return nil
 }
 
-// 将sending队列中的数据发送出去
+// 将sending队列中的数据发送出去，多个server共用时只会运行一次
 func (s *Sender) Run(ctx context.Context) {
+	s.runOnce.Do(func() {
+		defer close(s.stopped)
+		s.run(ctx)
+	})
+}
+
+// Stop 停止定时发送，并将sending队列中剩余的消息发送出去
+func (s *Sender) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	// 未启动时直接标记为已退出
+	s.runOnce.Do(func() { close(s.stopped) })
+
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.send()
+}
+
+func (s *Sender) run(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-s.stop:
+			return
 		case <-time.After(1 * time.Second):
 			s.send()
 			break
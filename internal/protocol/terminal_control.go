@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+// TerminalControlHandler 终端执行控制命令，在通用应答放入发送队列后异步调用。
+// 处理方法需要断开连接时应调用Session.Close，写完已入队的应答后再关闭
+type TerminalControlHandler func(phone string, msg *model.Msg8105) error
+
+var (
+	terminalControlHandler TerminalControlHandler
+	terminalControlMutex   sync.RWMutex
+)
+
+// RegisterTerminalControlHandler 注册终端控制命令的处理方法，handler为nil时取消注册。用于模拟终端
+func RegisterTerminalControlHandler(handler TerminalControlHandler) {
+	terminalControlMutex.Lock()
+	defer terminalControlMutex.Unlock()
+	terminalControlHandler = handler
+}
+
+// 未注册处理方法时回复不支持。返回的方法在通用应答发出后调用，避免复位、关机等命令在应答前断开连接
+func handleTerminalControl(msg *model.Msg8105) (model.ResultCode, func()) {
+	terminalControlMutex.RLock()
+	handler := terminalControlHandler
+	terminalControlMutex.RUnlock()
+	if handler == nil {
+		return model.ResultNotSupported, nil
+	}
+	phone := msg.Header.PhoneNumber
+	return model.ResultSuccess, func() {
+		routines.GoSafe(func() {
+			if err := handler(phone, msg); err != nil {
+				log.Error().Err(err).Str("phone", phone).Uint8("command", uint8(msg.Command)).Msg("Fail to handle terminal control")
+			}
+		})
+	}
+}
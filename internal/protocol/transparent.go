@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+// TransparentDecoder 将上行透传数据解析为结构化数据，返回值作为事件的payload序列化为JSON
+type TransparentDecoder func(phone string, data []byte) (any, error)
+
+// 按透传消息类型注册的解析器
+type transparentRegistry struct {
+	decoders map[uint8]TransparentDecoder
+	mutex    *sync.RWMutex
+}
+
+var transparentRegistrySingleton *transparentRegistry
+var transparentRegistryInitOnce sync.Once
+
+func getTransparentRegistry() *transparentRegistry {
+	transparentRegistryInitOnce.Do(func() {
+		transparentRegistrySingleton = &transparentRegistry{
+			decoders: make(map[uint8]TransparentDecoder),
+			mutex:    &sync.RWMutex{},
+		}
+	})
+	return transparentRegistrySingleton
+}
+
+// RegisterTransparentDecoder 注册透传消息类型的解析器，重复注册时覆盖。decoder为nil时取消注册
+func RegisterTransparentDecoder(msgType uint8, decoder TransparentDecoder) {
+	reg := getTransparentRegistry()
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	if decoder == nil {
+		delete(reg.decoders, msgType)
+		return
+	}
+	reg.decoders[msgType] = decoder
+}
+
+// 解析透传数据生成事件，未注册解析器或解析失败时只保留原始数据
+func decodeTransparent(phone string, msgType uint8, data []byte) *model.TransparentEvent {
+	event := &model.TransparentEvent{
+		Phone:      phone,
+		MsgType:    msgType,
+		TypeName:   model.TransparentTypeName(msgType),
+		Raw:        hex.Byte2Str(data),
+		ReceivedAt: time.Now(),
+	}
+
+	reg := getTransparentRegistry()
+	reg.mutex.RLock()
+	decoder, ok := reg.decoders[msgType]
+	reg.mutex.RUnlock()
+	if !ok {
+		return event
+	}
+	payload, err := decoder(phone, data)
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+	event.Payload = payload
+	return event
+}
@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+func TestDecodeTransparent(t *testing.T) {
+	const phone = "013800000004"
+	type temperature struct {
+		Value uint16 `json:"value"`
+	}
+	RegisterTransparentDecoder(0xF0, func(_ string, data []byte) (any, error) {
+		if len(data) != 2 {
+			return nil, errors.New("Fail to decode temperature, invalid length")
+		}
+		return &temperature{Value: binary.BigEndian.Uint16(data)}, nil
+	})
+	defer RegisterTransparentDecoder(0xF0, nil)
+
+	tests := []struct {
+		name        string
+		msgType     uint8
+		data        []byte
+		wantType    string
+		wantPayload any
+		wantErr     bool
+	}{
+		{
+			name:        "case1: registered decoder",
+			msgType:     0xF0,
+			data:        []byte{0x01, 0x2c},
+			wantType:    "userDefined",
+			wantPayload: &temperature{Value: 300},
+		},
+		{
+			name:     "case2: decoder error keeps raw data",
+			msgType:  0xF0,
+			data:     []byte{0x01},
+			wantType: "userDefined",
+			wantErr:  true,
+		},
+		{
+			name:     "case3: no decoder registered",
+			msgType:  model.TransparentSerial1,
+			data:     []byte{0x01, 0x2c},
+			wantType: "serial1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := decodeTransparent(phone, tt.msgType, tt.data)
+			require.Equal(t, phone, event.Phone)
+			require.Equal(t, tt.wantType, event.TypeName)
+			require.Equal(t, tt.wantPayload, event.Payload)
+			require.Equal(t, tt.wantErr, event.Error != "")
+			require.Equal(t, tt.data, hex.Str2Byte(event.Raw))
+		})
+	}
+}
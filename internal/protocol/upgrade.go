@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fakeyanss/gron"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+const (
+	upgradeScheduleInterval      = time.Second
+	defaultUpgradeResultTimeout  = 30 * time.Minute
+	upgradeScheduleJobID         = "upgrade-schedule"
+	upgradeSchedulerFirmwareSize = 4               // 缓存的升级包个数，避免每次下发都读文件
+	upgradeAckTimeout            = 2 * time.Minute // 等待终端0x0001应答升级包的超时时间，升级包分包较多时发送耗时较长
+)
+
+// UpgradeScheduler 定时为升级任务分配终端并下发0x8108升级包
+type UpgradeScheduler struct {
+	cron          *gron.Cron
+	resultTimeout time.Duration // 下发后等待升级结果和重新鉴权的超时时间
+
+	firmwares map[string][]byte
+	mutex     *sync.Mutex
+	startOnce sync.Once
+}
+
+var upgradeSchedulerSingleton *UpgradeScheduler
+var upgradeSchedulerInitOnce sync.Once
+
+func NewUpgradeScheduler() *UpgradeScheduler {
+	upgradeSchedulerInitOnce.Do(func() {
+		upgradeSchedulerSingleton = &UpgradeScheduler{
+			cron:          gron.New(),
+			resultTimeout: defaultUpgradeResultTimeout,
+			firmwares:     make(map[string][]byte),
+			mutex:         &sync.Mutex{},
+		}
+	})
+	return upgradeSchedulerSingleton
+}
+
+// SetResultTimeout 设置等待升级结果的超时时间，0使用默认值
+func (s *UpgradeScheduler) SetResultTimeout(timeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if timeout <= 0 {
+		timeout = defaultUpgradeResultTimeout
+	}
+	s.resultTimeout = timeout
+}
+
+// Start 开始定时调度，重复调用只会启动一次
+func (s *UpgradeScheduler) Start() {
+	s.startOnce.Do(func() {
+		s.cron.AddFuncWithJobID(gron.Every(upgradeScheduleInterval), upgradeScheduleJobID, s.schedule)
+		s.cron.Start()
+	})
+}
+
+// Stop 停止调度，已下发的升级包不受影响
+func (s *UpgradeScheduler) Stop() {
+	s.cron.Stop()
+}
+
+func (s *UpgradeScheduler) schedule() {
+	s.mutex.Lock()
+	timeout := s.resultTimeout
+	s.mutex.Unlock()
+
+	cache := storage.GetCampaignCache()
+	cache.ExpireTasks(timeout)
+	for _, task := range cache.AcquireTasks(isDeviceOnline) {
+		t := task
+		// 升级包分包较多时会阻塞在session发送队列，每个终端单独下发
+		routines.GoSafe(func() {
+			err := s.send(t)
+			if err != nil {
+				log.Error().Err(err).Str("device", t.Phone).Str("campaign", t.CampaignID).Msg("Fail to send upgrade package")
+			}
+			cache.MarkSent(t.CampaignID, t.Phone, err)
+		})
+	}
+}
+
+func isDeviceOnline(phone string) bool {
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+	if err != nil || device.Status == model.DeviceStatusOffline {
+		return false
+	}
+	_, err = storage.GetSession(device.SessionID)
+	return err == nil
+}
+
+// 通过sender下发升级包，等待终端0x0001应答后才视为下发成功。
+//
+// 超过单包长度时由编码器分包，终端可通过0x0005请求补传
+func (s *UpgradeScheduler) send(task *storage.UpgradeTask) error {
+	fw, err := storage.GetFirmwareStore().GetFirmware(task.FirmwareID)
+	if err != nil {
+		return err
+	}
+	data, err := s.loadFirmware(task.FirmwareID)
+	if err != nil {
+		return err
+	}
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(task.Phone)
+	if err != nil {
+		return err
+	}
+	session, err := storage.GetSession(device.SessionID)
+	if err != nil {
+		return err
+	}
+	msg := &model.Msg8108{
+		Header:         model.GenMsgHeader(device, 0x8108, session.GetNextSerialNum()),
+		UpgradeType:    task.UpgradeType,
+		ManufacturerID: fw.ManufacturerID,
+		Version:        task.Version,
+		Package:        data,
+	}
+	key := &SenderKey{Phone: task.Phone, MsgId: msg.Header.MsgID, SerialNumber: msg.Header.SerialNumber}
+	ackCh := make(chan *model.Msg0001, 1)
+	err = NewSender().Append(key, &SenderValue{
+		Phone: task.Phone,
+		Msg:   msg,
+		ResponseCallBack: func(m any) error {
+			ackCh <- m.(*model.Msg0001)
+			return nil
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Fail to send upgrade package")
+	}
+
+	select {
+	case ack := <-ackCh:
+		if ack.Result != uint8(model.ResultSuccess) {
+			return errors.Errorf("Fail to send upgrade package, device answered %s", ack.Result2Str())
+		}
+	case <-time.After(upgradeAckTimeout):
+		NewSender().Remove(key)
+		return errors.Errorf("Fail to wait for upgrade package answer, timeout=%v", upgradeAckTimeout)
+	}
+	log.Info().Str("device", task.Phone).Str("campaign", task.CampaignID).Str("version", task.Version).
+		Int("size", len(data)).Msg("Sent upgrade package")
+	return nil
+}
+
+func (s *UpgradeScheduler) loadFirmware(id string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if data, ok := s.firmwares[id]; ok {
+		return data, nil
+	}
+	data, err := storage.GetFirmwareStore().LoadFirmware(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.firmwares) >= upgradeSchedulerFirmwareSize {
+		for k := range s.firmwares {
+			delete(s.firmwares, k)
+			break
+		}
+	}
+	s.firmwares[id] = data
+	return data, nil
+}
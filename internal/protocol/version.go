@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"sync"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+// 2011与2013版本的消息头相同，需要通过终端缓存或配置确定2011版本的终端
+type versionRegistry struct {
+	phones2011 map[string]struct{} // 配置为2011版本的终端手机号
+	mutex      *sync.RWMutex
+}
+
+var versionRegistrySingleton *versionRegistry
+var versionRegistryInitOnce sync.Once
+
+func getVersionRegistry() *versionRegistry {
+	versionRegistryInitOnce.Do(func() {
+		versionRegistrySingleton = &versionRegistry{
+			phones2011: make(map[string]struct{}),
+			mutex:      &sync.RWMutex{},
+		}
+	})
+	return versionRegistrySingleton
+}
+
+// SetVersion2011Phones 设置使用2011版本协议的终端，未注册时按此解析注册消息
+func SetVersion2011Phones(phones []string) {
+	r := getVersionRegistry()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.phones2011 = make(map[string]struct{}, len(phones))
+	for _, phone := range phones {
+		r.phones2011[phone] = struct{}{}
+	}
+}
+
+func (r *versionRegistry) is2011(phone string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.phones2011[phone]
+	return ok
+}
+
+// 2011与2013版本的消息头无法区分，已缓存的终端按缓存的版本解析，未缓存的终端按配置解析
+func resolveVersion(header *model.MsgHeader) {
+	if header.Attr.VersionDesc != model.Version2013 {
+		return
+	}
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(header.PhoneNumber)
+	if err == nil {
+		if device.VersionDesc == model.Version2011 {
+			header.Attr.VersionDesc = model.Version2011
+		}
+		return
+	}
+	if getVersionRegistry().is2011(header.PhoneNumber) {
+		header.Attr.VersionDesc = model.Version2011
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+// 拒绝连接的原因
+type RejectReason string
+
+const (
+	RejectMaxConns         RejectReason = "maxConns"         // 超过最大连接数
+	RejectMaxConnsPerIP    RejectReason = "maxConnsPerIP"    // 超过单IP最大连接数
+	RejectAcceptRate       RejectReason = "acceptRate"       // 超过每秒新建连接数
+	RejectHandshakeTimeout RejectReason = "handshakeTimeout" // 超时未完成注册或鉴权
+	RejectProxyHeader      RejectReason = "proxyHeader"      // 可信来源的PROXY头缺失或非法
+)
+
+// 连接准入配置，零值表示不限制
+type AdmissionOptions struct {
+	MaxConns         int           // 最大连接数
+	MaxConnsPerIP    int           // 单IP最大连接数
+	AcceptRate       int           // 每秒最多新建连接数
+	HandshakeTimeout time.Duration // 连接后需要在此时间内完成0x0100注册或0x0102鉴权
+}
+
+// 连接准入控制，tcp/tls server共用，限制对所有监听端口生效
+type admission struct {
+	opts *AdmissionOptions
+
+	total    int
+	perIP    map[string]int
+	tokens   float64 // 令牌桶，控制新建连接速率
+	lastFill time.Time
+	rejected map[RejectReason]uint64
+
+	mutex *sync.Mutex
+}
+
+var admissionSingleton *admission
+var admissionInitOnce sync.Once
+
+func getAdmission() *admission {
+	admissionInitOnce.Do(func() {
+		admissionSingleton = newAdmission(&AdmissionOptions{})
+	})
+	return admissionSingleton
+}
+
+func newAdmission(opts *AdmissionOptions) *admission {
+	return &admission{
+		opts:     opts,
+		perIP:    make(map[string]int),
+		tokens:   float64(opts.AcceptRate),
+		lastFill: time.Now(),
+		rejected: make(map[RejectReason]uint64),
+		mutex:    &sync.Mutex{},
+	}
+}
+
+// SetAdmissionOptions 设置连接准入配置
+func SetAdmissionOptions(opts *AdmissionOptions) {
+	a := getAdmission()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.opts = opts
+	a.tokens = float64(opts.AcceptRate)
+	a.lastFill = time.Now()
+}
+
+// AdmissionStats 当前连接数和按原因统计的拒绝次数
+func AdmissionStats() (int, map[RejectReason]uint64) {
+	a := getAdmission()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	rejected := make(map[RejectReason]uint64, len(a.rejected))
+	for k, v := range a.rejected {
+		rejected[k] = v
+	}
+	return a.total, rejected
+}
+
+// 判断是否接受新连接，接受时计入连接数，连接关闭时需要调用release
+func (a *admission) admit(addr net.Addr) bool {
+	ip := hostOf(addr)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var reason RejectReason
+	switch {
+	case a.opts.AcceptRate > 0 && !a.takeToken():
+		reason = RejectAcceptRate
+	case a.opts.MaxConns > 0 && a.total >= a.opts.MaxConns:
+		reason = RejectMaxConns
+	case a.opts.MaxConnsPerIP > 0 && a.perIP[ip] >= a.opts.MaxConnsPerIP:
+		reason = RejectMaxConnsPerIP
+	default:
+		a.total++
+		a.perIP[ip]++
+		return true
+	}
+
+	a.rejected[reason]++
+	log.Warn().Str("addr", addr.String()).Str("reason", string(reason)).Msg("Reject connection")
+	return false
+}
+
+// 记录在准入之外拒绝的连接
+func (a *admission) reject(addr net.Addr, reason RejectReason, err error) {
+	a.mutex.Lock()
+	a.rejected[reason]++
+	a.mutex.Unlock()
+	log.Warn().Err(err).Str("addr", addr.String()).Str("reason", string(reason)).Msg("Reject connection")
+}
+
+func (a *admission) release(addr net.Addr) {
+	ip := hostOf(addr)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.total--
+	if a.perIP[ip]--; a.perIP[ip] <= 0 {
+		delete(a.perIP, ip)
+	}
+}
+
+// 按速率补充令牌，桶容量为1秒的配额
+func (a *admission) takeToken() bool {
+	now := time.Now()
+	rate := float64(a.opts.AcceptRate)
+	a.tokens += now.Sub(a.lastFill).Seconds() * rate
+	if a.tokens > rate {
+		a.tokens = rate
+	}
+	a.lastFill = now
+	if a.tokens < 1 {
+		return false
+	}
+	a.tokens--
+	return true
+}
+
+// 超时未完成注册或鉴权时关闭连接，返回的timer需要在连接关闭时停止
+func (a *admission) watchHandshake(session *model.Session) *time.Timer {
+	a.mutex.Lock()
+	timeout := a.opts.HandshakeTimeout
+	a.mutex.Unlock()
+	if timeout <= 0 {
+		return nil
+	}
+
+	return time.AfterFunc(timeout, func() {
+		if session.GetState() != model.SessionStateConnected {
+			return
+		}
+		a.reject(session.Conn.RemoteAddr(), RejectHandshakeTimeout, nil)
+		session.Conn.Close()
+	})
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
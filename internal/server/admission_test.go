@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+func TestAdmission_admit(t *testing.T) {
+	ip1 := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1001}
+	ip1b := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1002}
+	ip2 := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 1001}
+	ip3 := &net.TCPAddr{IP: net.ParseIP("10.0.0.3"), Port: 1001}
+
+	a := newAdmission(&AdmissionOptions{MaxConns: 2, MaxConnsPerIP: 1})
+	require.True(t, a.admit(ip1))
+	require.False(t, a.admit(ip1b)) // 单IP超限
+	require.True(t, a.admit(ip2))
+	require.False(t, a.admit(ip3)) // 总数超限
+
+	a.release(ip1)
+	require.True(t, a.admit(ip1b))
+	require.Equal(t, uint64(1), a.rejected[RejectMaxConnsPerIP])
+	require.Equal(t, uint64(1), a.rejected[RejectMaxConns])
+
+	// 速率限制
+	a = newAdmission(&AdmissionOptions{AcceptRate: 2})
+	require.True(t, a.admit(ip1))
+	require.True(t, a.admit(ip1))
+	require.False(t, a.admit(ip1))
+	require.Equal(t, uint64(1), a.rejected[RejectAcceptRate])
+}
+
+func TestAdmission_watchHandshake(t *testing.T) {
+	a := newAdmission(&AdmissionOptions{HandshakeTimeout: 20 * time.Millisecond})
+
+	servConn, cliConn := net.Pipe()
+	defer cliConn.Close()
+	session := &model.Session{ID: "pending", Conn: servConn}
+	a.watchHandshake(session)
+	// 超时未注册，连接被关闭
+	_, err := cliConn.Read(make([]byte, 1))
+	require.NotNil(t, err)
+	require.Equal(t, uint64(1), a.rejected[RejectHandshakeTimeout])
+
+	servConn2, cliConn2 := net.Pipe()
+	defer servConn2.Close()
+	defer cliConn2.Close()
+	authed := &model.Session{ID: "authed", Conn: servConn2}
+	authed.SetState(model.SessionStateAuthenticated)
+	a.watchHandshake(authed)
+	time.Sleep(50 * time.Millisecond)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	require.Equal(t, uint64(1), a.rejected[RejectHandshakeTimeout])
+}
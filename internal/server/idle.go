@@ -0,0 +1,129 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+const (
+	defaultPreAuthIdleTimeout  = 2 * time.Minute
+	defaultPostAuthIdleTimeout = 30 * time.Minute
+	defaultReapInterval        = 30 * time.Second
+)
+
+// 连接空闲超时配置，超时时间为0表示不限制
+type IdleOptions struct {
+	PreAuthTimeout  time.Duration // 未鉴权连接的空闲超时时间，包括从未注册的连接
+	PostAuthTimeout time.Duration // 已鉴权连接的空闲超时时间
+	ReapInterval    time.Duration // 清理空闲session的间隔
+}
+
+// 空闲session清理器，tcp/udp server共用
+type idleReaper struct {
+	opts *IdleOptions
+
+	runOnce  sync.Once
+	stopOnce sync.Once
+	stop     chan struct{}
+	mutex    *sync.RWMutex
+}
+
+var reaperSingleton *idleReaper
+var reaperInitOnce sync.Once
+
+func getIdleReaper() *idleReaper {
+	reaperInitOnce.Do(func() {
+		reaperSingleton = &idleReaper{
+			opts: &IdleOptions{
+				PreAuthTimeout:  defaultPreAuthIdleTimeout,
+				PostAuthTimeout: defaultPostAuthIdleTimeout,
+				ReapInterval:    defaultReapInterval,
+			},
+			stop:  make(chan struct{}),
+			mutex: &sync.RWMutex{},
+		}
+	})
+	return reaperSingleton
+}
+
+// SetIdleOptions 设置连接空闲超时配置
+func SetIdleOptions(opts *IdleOptions) {
+	r := getIdleReaper()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	o := *opts
+	if o.ReapInterval <= 0 {
+		o.ReapInterval = defaultReapInterval
+	}
+	r.opts = &o
+}
+
+// 按连接状态获取空闲超时时间
+func (r *idleReaper) timeoutOf(session *model.Session) time.Duration {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if session.GetState() == model.SessionStateAuthenticated {
+		return r.opts.PostAuthTimeout
+	}
+	return r.opts.PreAuthTimeout
+}
+
+// 每次读取前设置读超时，半开连接在超时后读取失败
+func (r *idleReaper) setReadDeadline(session *model.Session) {
+	var deadline time.Time
+	if timeout := r.timeoutOf(session); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	_ = session.Conn.SetReadDeadline(deadline)
+}
+
+// 定期清理空闲session，多个server共用时只会运行一次
+func (r *idleReaper) run() {
+	r.runOnce.Do(func() {
+		routines.GoSafe(func() {
+			for {
+				r.mutex.RLock()
+				interval := r.opts.ReapInterval
+				r.mutex.RUnlock()
+
+				select {
+				case <-r.stop:
+					return
+				case <-time.After(interval):
+					r.reap()
+				}
+			}
+		})
+	})
+}
+
+func (r *idleReaper) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// 关闭并清理超时的session，对应设备改为离线
+func (r *idleReaper) reap() {
+	for _, session := range storage.ListSession() {
+		timeout := r.timeoutOf(session)
+		if timeout <= 0 || session.IdleFor() < timeout {
+			continue
+		}
+		log.Debug().Str("sessionId", session.ID).Dur("idle", session.IdleFor()).Msg("Reap idle session")
+
+		session.Conn.Close()
+		storage.ClearSession(session.ID)
+
+		cache := storage.GetDeviceCache()
+		for _, d := range cache.ListDevice() {
+			if d.SessionID == session.ID && d.Status != model.DeviceStatusOffline {
+				cache.UpdateDeviceStatus(d, model.DeviceStatusOffline)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestIdleReaper_reap(t *testing.T) {
+	r := &idleReaper{
+		opts:  &IdleOptions{PreAuthTimeout: 20 * time.Millisecond, PostAuthTimeout: time.Hour},
+		stop:  make(chan struct{}),
+		mutex: &sync.RWMutex{},
+	}
+
+	newSession := func(id string, state model.SessionState) (*model.Session, net.Conn) {
+		servConn, cliConn := net.Pipe()
+		s := &model.Session{ID: id, Conn: servConn}
+		s.SetState(state)
+		s.Touch()
+		storage.StoreSession(s)
+		return s, cliConn
+	}
+	_, cli1 := newSession("idle-unauthed", model.SessionStateConnected)
+	defer cli1.Close()
+	authed, cli2 := newSession("idle-authed", model.SessionStateAuthenticated)
+	defer cli2.Close()
+	defer authed.Conn.Close()
+
+	device := &model.Device{Phone: "013800000001", Plate: "idle-plate", SessionID: "idle-unauthed", Status: model.DeviceStatusOnline}
+	storage.GetDeviceCache().CacheDevice(device)
+	defer storage.GetDeviceCache().DelDeviceByPhone(device.Phone)
+
+	time.Sleep(30 * time.Millisecond)
+	r.reap()
+
+	// 未鉴权的连接超时被清理，设备改为离线
+	_, err := storage.GetSession("idle-unauthed")
+	require.ErrorIs(t, err, storage.ErrSessionClosed)
+	_, err = cli1.Read(make([]byte, 1))
+	require.NotNil(t, err)
+	d, err := storage.GetDeviceCache().GetDeviceByPhone(device.Phone)
+	require.Nil(t, err)
+	require.Equal(t, model.DeviceStatusOffline, d.Status)
+
+	// 已鉴权的连接未超时
+	_, err = storage.GetSession("idle-authed")
+	require.Nil(t, err)
+	storage.ClearSession("idle-authed")
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const proxyHeaderTimeout = 5 * time.Second
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+const proxyV1MaxLen = 107 // v1头最大长度，包括\r\n
+
+var (
+	ErrProxyHeaderMissing = errors.New("Fail to find proxy protocol header from trusted source")
+	ErrInvalidProxyHeader = errors.New("Fail to parse proxy protocol header")
+)
+
+// PROXY protocol配置，只解析来自可信地址的连接
+type ProxyProtocolOptions struct {
+	Enable       bool
+	TrustedCIDRs []string // 负载均衡的地址段
+}
+
+type proxyProtocol struct {
+	enable  bool
+	trusted []*net.IPNet
+	mutex   *sync.RWMutex
+}
+
+var proxyProtoSingleton *proxyProtocol
+var proxyProtoInitOnce sync.Once
+
+func getProxyProtocol() *proxyProtocol {
+	proxyProtoInitOnce.Do(func() {
+		proxyProtoSingleton = &proxyProtocol{mutex: &sync.RWMutex{}}
+	})
+	return proxyProtoSingleton
+}
+
+// SetProxyProtocolOptions 设置PROXY protocol配置
+func SetProxyProtocolOptions(opts *ProxyProtocolOptions) error {
+	trusted, err := parseCIDRs(opts.TrustedCIDRs)
+	if err != nil {
+		return err
+	}
+	p := getProxyProtocol()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.enable = opts.Enable
+	p.trusted = trusted
+	return nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			// 单个IP
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to parse trusted cidr %s", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (p *proxyProtocol) isTrusted(addr net.Addr) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if !p.enable {
+		return false
+	}
+	ip := net.ParseIP(hostOf(addr))
+	for _, n := range p.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 可信地址的连接读取PROXY头，返回使用真实客户端地址的连接；非可信地址原样返回
+func (p *proxyProtocol) wrap(conn net.Conn) (net.Conn, error) {
+	if !p.isTrusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	remote, err := readProxyHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	pc := &proxyConn{Conn: conn, reader: reader, remote: remote}
+	if remote == nil {
+		pc.remote = conn.RemoteAddr() // LOCAL/UNKNOWN，使用连接本身的地址
+	}
+	return pc, nil
+}
+
+// 解析v1或v2头，返回nil地址表示头中没有客户端地址
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err == nil && bytes.Equal(sig, proxyV2Signature) {
+		return readProxyV2(r)
+	}
+	prefix, err := r.Peek(len(proxyV1Prefix))
+	if err == nil && bytes.Equal(prefix, proxyV1Prefix) {
+		return readProxyV1(r)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrap(err, "Fail to read proxy protocol header")
+	}
+	return nil, ErrProxyHeaderMissing
+}
+
+// PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, proxyV1MaxLen)
+	for len(line) < proxyV1MaxLen {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to read proxy protocol v1 header")
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidProxyHeader
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidProxyHeader
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil || port < 0 || port > 65535 {
+		return nil, ErrInvalidProxyHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "Fail to read proxy protocol v2 header")
+	}
+	verCmd, fam := header[12], header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+	if verCmd>>4 != 0x2 {
+		return nil, ErrInvalidProxyHeader
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errors.Wrap(err, "Fail to read proxy protocol v2 addresses")
+	}
+
+	if verCmd&0x0F == 0x0 { // LOCAL，负载均衡自身的健康检查
+		return nil, nil
+	}
+	switch fam >> 4 {
+	case 0x1: // AF_INET
+		if len(payload) < 12 {
+			return nil, ErrInvalidProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x2: // AF_INET6
+		if len(payload) < 36 {
+			return nil, ErrInvalidProxyHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	default: // AF_UNSPEC/AF_UNIX，没有可用的客户端地址
+		return nil, nil
+	}
+}
+
+// 读取PROXY头后的连接，RemoteAddr返回真实客户端地址
+type proxyConn struct {
+	net.Conn
+	reader *bufio.Reader // 读取头时可能多读了后续数据
+	remote net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
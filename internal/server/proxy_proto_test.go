@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := func(verCmd, fam byte, addrs []byte) []byte {
+		buf := append([]byte{}, proxyV2Signature...)
+		buf = append(buf, verCmd, fam, byte(len(addrs)>>8), byte(len(addrs)))
+		return append(buf, addrs...)
+	}
+	ipv4Addrs := []byte{
+		203, 0, 113, 7, // src
+		10, 0, 0, 1, // dst
+		0xDB, 0xF0, // src port 56304
+		0x07, 0xBF, // dst port 1983
+	}
+	jtFrame := []byte{0x7e, 0x00, 0x02, 0x7e}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantAddr net.Addr
+		wantErr  error
+	}{
+		{
+			name:     "case1: v1 tcp4",
+			data:     append([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 56304 1983\r\n"), jtFrame...),
+			wantAddr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 56304},
+		},
+		{
+			name:     "case2: v1 tcp6",
+			data:     append([]byte("PROXY TCP6 2001:db8::1 2001:db8::2 56304 1983\r\n"), jtFrame...),
+			wantAddr: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56304},
+		},
+		{
+			name: "case3: v1 unknown",
+			data: append([]byte("PROXY UNKNOWN\r\n"), jtFrame...),
+		},
+		{
+			name:     "case4: v2 proxy ipv4",
+			data:     append(v2(0x21, 0x11, ipv4Addrs), jtFrame...),
+			wantAddr: &net.TCPAddr{IP: net.IP{203, 0, 113, 7}, Port: 56304},
+		},
+		{
+			name: "case5: v2 local",
+			data: append(v2(0x20, 0x00, nil), jtFrame...),
+		},
+		{
+			name:    "case6: missing header",
+			data:    append(jtFrame, jtFrame...),
+			wantErr: ErrProxyHeaderMissing,
+		},
+		{
+			name:    "case7: invalid v1",
+			data:    append([]byte("PROXY TCP4 bad 10.0.0.1 56304 1983\r\n"), jtFrame...),
+			wantErr: ErrInvalidProxyHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(tt.data))
+			addr, err := readProxyHeader(r)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			require.Equal(t, tt.wantAddr, addr)
+			// 头之后的数据保持不变
+			rest, err := io.ReadAll(r)
+			require.Nil(t, err)
+			require.Equal(t, jtFrame, rest)
+		})
+	}
+}
+
+func TestProxyProtocol_isTrusted(t *testing.T) {
+	trusted, err := parseCIDRs([]string{"10.0.0.0/8", "192.168.1.10"})
+	require.Nil(t, err)
+	p := &proxyProtocol{enable: true, trusted: trusted, mutex: &sync.RWMutex{}}
+
+	require.True(t, p.isTrusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1}))
+	require.True(t, p.isTrusted(&net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 1}))
+	require.False(t, p.isTrusted(&net.TCPAddr{IP: net.ParseIP("192.168.1.11"), Port: 1}))
+
+	_, err = parseCIDRs([]string{"10.0.0.0/33"})
+	require.NotNil(t, err)
+}
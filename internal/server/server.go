@@ -1,9 +1,11 @@
 package server
 
+import "context"
+
 type Server interface {
 	Listen(addr string) error
 	Start()
-	Stop()
+	Stop(ctx context.Context) error // 优雅停止，ctx超时后强制关闭
 	Send(sessionId string, smsg any)
 	SendV2(phone string, smsg any, rspFn func(any) error) error
 }
@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,18 +19,29 @@ import (
 )
 
 type TCPServer struct {
-	listener net.Listener
-	Sender   *protocol.Sender
-
-	// sessions map[string]*model.Session
-	// mutex *sync.Mutex
+	listener  net.Listener
+	tlsConfig *tls.Config // 不为nil时，在解析PROXY头后进行tls握手
+	Sender    *protocol.Sender
+	admission *admission     // 连接准入控制
+	idle      *idleReaper    // 空闲连接清理
+	proxy     *proxyProtocol // 负载均衡PROXY头解析
+	stopping  chan struct{}
+	stopOnce  sync.Once
+
+	sessions map[string]*model.Session // 当前server上的连接，停止时用于中断读取和强制关闭
+	mutex    *sync.Mutex
+	wg       sync.WaitGroup // 处理中的session协程
 }
 
 func NewTCPServer() *TCPServer {
 	return &TCPServer{
-		Sender: protocol.NewSender(),
-		// mutex: &sync.Mutex{},
-		// sessions: make(map[string]*model.Session),
+		Sender:    protocol.NewSender(),
+		admission: getAdmission(),
+		idle:      getIdleReaper(),
+		proxy:     getProxyProtocol(),
+		stopping:  make(chan struct{}),
+		mutex:     &sync.Mutex{},
+		sessions:  make(map[string]*model.Session),
 	}
 }
 
@@ -41,9 +55,30 @@ func (serv *TCPServer) Listen(addr string) error {
 	return err
 }
 
+// ListenTLS 监听tls加密连接，clientCAFile不为空时要求终端提供证书进行双向认证
+//
+// 收到SIGHUP信号时重新加载证书
+func (serv *TCPServer) ListenTLS(addr, certFile, keyFile, clientCAFile string) error {
+	reloader, err := newCertReloader(certFile, keyFile, clientCAFile)
+	if err != nil {
+		return err
+	}
+	// PROXY头在tls握手之前，不能直接使用tls.Listen
+	l, err := net.Listen("tcp", addr)
+	if err == nil {
+		serv.listener = l
+		serv.tlsConfig = reloader.tlsConfig()
+		log.Debug().Bool("mutualTLS", clientCAFile != "").Msgf("Listening tls on %v", addr)
+	}
+
+	return err
+}
+
 func (serv *TCPServer) Start() {
 	// 启动sender
 	routines.GoSafe(func() { serv.Sender.Run(context.Background()) })
+	// 启动空闲连接清理
+	serv.idle.run()
 
 	for {
 		conn, err := serv.listener.Accept()
@@ -58,38 +93,100 @@ func (serv *TCPServer) Start() {
 				continue
 			}
 		} else {
-			session := serv.accept(conn)
-			routines.GoSafe(func() { serv.serve(session) })
+			serv.wg.Add(1)
+			routines.GoSafe(func() {
+				defer serv.wg.Done()
+				serv.handle(conn)
+			})
 		}
 	}
 }
 
-func (serv *TCPServer) Stop() {
-	serv.listener.Close()
+// 解析PROXY头得到真实客户端地址，准入通过后封装为session处理
+func (serv *TCPServer) handle(rawConn net.Conn) {
+	conn, err := serv.proxy.wrap(rawConn)
+	if err != nil {
+		serv.admission.reject(rawConn.RemoteAddr(), RejectProxyHeader, err)
+		rawConn.Close()
+		return
+	}
+	if !serv.admission.admit(conn.RemoteAddr()) {
+		conn.Close()
+		return
+	}
+	if serv.tlsConfig != nil {
+		conn = tls.Server(conn, serv.tlsConfig)
+	}
+	session := serv.accept(conn)
+	serv.serve(session)
+}
+
+// Stop 优雅停止
+//
+// 停止接收新连接 -> 发送sender队列中的消息 -> 中断连接读取，等待处理中的消息完成 -> 关闭连接。
+// ctx超时后强制关闭所有连接。可重复调用
+func (serv *TCPServer) Stop(ctx context.Context) error {
+	serv.stopOnce.Do(func() {
+		close(serv.stopping)
+		serv.listener.Close()
+		serv.idle.close()
+	})
+
+	if err := serv.Sender.Stop(ctx); err != nil {
+		log.Error().Err(err).Msg("Fail to flush sender before stopping")
+	}
+
+	// 读超时后serve协程会在处理完已读取的消息后退出
+	serv.mutex.Lock()
+	for _, session := range serv.sessions {
+		_ = session.Conn.SetReadDeadline(time.Now())
+	}
+	serv.mutex.Unlock()
+
+	done := make(chan struct{})
+	routines.GoSafe(func() {
+		serv.wg.Wait()
+		close(done)
+	})
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		serv.mutex.Lock()
+		defer serv.mutex.Unlock()
+		for _, session := range serv.sessions {
+			session.Conn.Close()
+		}
+		return ctx.Err()
+	}
 }
 
 // 将conn封装为逻辑session
 func (serv *TCPServer) accept(conn net.Conn) *model.Session {
 	remoteAddr := conn.RemoteAddr().String()
-	// serv.mutex.Lock()
+	serv.mutex.Lock()
+	defer serv.mutex.Unlock()
 	session := &model.Session{
 		Conn: conn,
 		ID:   remoteAddr, // using remote addr default
 	}
-	// serv.sessions[remoteAddr] = session
+	session.Touch()
+	serv.sessions[remoteAddr] = session
 	storage.StoreSession(session)
-	// serv.mutex.Unlock()
 
 	return session
 }
 
 func (serv *TCPServer) remove(session *model.Session) {
-	// serv.mutex.Lock()
-	// defer serv.mutex.Unlock()
+	// 写完发送队列中的消息再关闭连接
+	session.Close()
+	serv.admission.release(session.Conn.RemoteAddr())
 
-	session.Conn.Close()
+	serv.mutex.Lock()
+	defer serv.mutex.Unlock()
 	storage.ClearSession(session.ID)
-	// delete(serv.sessions, session.ID)
+	delete(serv.sessions, session.ID)
 
 	log.Debug().Str("sessionId", session.ID).Msg("Closing connection from remote.")
 }
@@ -98,6 +195,15 @@ func (serv *TCPServer) remove(session *model.Session) {
 func (serv *TCPServer) serve(session *model.Session) {
 	defer serv.remove(session)
 
+	if timer := serv.admission.watchHandshake(session); timer != nil {
+		defer timer.Stop()
+	}
+
+	if err := tlsHandshake(session); err != nil {
+		log.Error().Err(err).Str("sessionId", session.ID).Msg("Failed to serve session")
+		return
+	}
+
 	pg := protocol.NewPipeline(session.Conn)
 	for {
 		// 记录value ctx
@@ -107,7 +213,11 @@ func (serv *TCPServer) serve(session *model.Session) {
 		ctx = context.WithValue(ctx,
 			model.ProcResponseCallBackKey{},
 			model.ProcResponseFn(serv.Sender.ProcResponse))
+		ctx = context.WithValue(ctx,
+			model.ProcResponseByMsgIDCallBackKey{},
+			model.ProcResponseByMsgIDFn(serv.Sender.ProcResponseByMsgID))
 
+		serv.setReadDeadline(session)
 		err := pg.ProcessConnRead(ctx)
 
 		if err == nil {
@@ -120,6 +230,7 @@ func (serv *TCPServer) serve(session *model.Session) {
 		case errors.Is(err, io.EOF),
 			errors.Is(err, io.ErrClosedPipe),
 			errors.Is(err, net.ErrClosed),
+			errors.Is(err, os.ErrDeadlineExceeded),
 			errors.Is(err, storage.ErrDeviceNotFound):
 			return // close connection when EOF or closed
 		default:
@@ -128,6 +239,16 @@ func (serv *TCPServer) serve(session *model.Session) {
 	}
 }
 
+// 按连接状态设置读超时，停止中时立即超时，读完已接收的数据后退出
+func (serv *TCPServer) setReadDeadline(session *model.Session) {
+	select {
+	case <-serv.stopping:
+		_ = session.Conn.SetReadDeadline(time.Now())
+	default:
+		serv.idle.setReadDeadline(session)
+	}
+}
+
 // 发送消息到终端设备, 外部调用
 func (serv *TCPServer) Send(id string, smsg any) {
 	msg := smsg.(model.JT808Msg)
@@ -141,7 +262,8 @@ func (serv *TCPServer) Send(id string, smsg any) {
 	pg := protocol.NewPipeline(session.Conn)
 
 	// 记录value ctx
-	ctx := context.WithValue(context.Background(), model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
+	ctx := context.WithValue(context.Background(), model.SessionCtxKey{}, session)
+	ctx = context.WithValue(ctx, model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
 
 	err = pg.ProcessConnWrite(ctx)
 
@@ -149,7 +271,7 @@ func (serv *TCPServer) Send(id string, smsg any) {
 		return
 	}
 
-	if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) {
+	if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) || errors.Is(err, model.ErrWriteQueueClosed) {
 		serv.remove(session)
 	}
 
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"context"
+	"io"
 	"net"
-	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 )
@@ -11,7 +15,6 @@ import (
 func TestTCPServer_serve(t *testing.T) {
 	type fields struct {
 		listener net.Listener
-		mutex    *sync.Mutex
 	}
 	type args struct {
 		session *model.Session
@@ -27,9 +30,38 @@ func TestTCPServer_serve(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			serv := &TCPServer{
 				listener: tt.fields.listener,
-				mutex:    tt.fields.mutex,
 			}
 			serv.serve(tt.args.session)
 		})
 	}
 }
+
+func TestTCPServer_Stop(t *testing.T) {
+	serv := NewTCPServer()
+	require.Nil(t, serv.Listen("127.0.0.1:0"))
+	go serv.Start()
+
+	conn, err := net.Dial("tcp", serv.listener.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		serv.mutex.Lock()
+		defer serv.mutex.Unlock()
+		return len(serv.sessions) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	require.Nil(t, serv.Stop(ctx))
+	require.Empty(t, serv.sessions)
+
+	// 停止后连接被关闭，也不再接收新连接
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	_, err = net.Dial("tcp", serv.listener.Addr().String())
+	require.NotNil(t, err)
+
+	// 重复停止不会panic
+	require.Nil(t, serv.Stop(ctx))
+}
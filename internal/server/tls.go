@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+const tlsHandshakeTimeout = 10 * time.Second
+
+var ErrInvalidClientCA = errors.New("Fail to parse client ca certificate")
+
+// 证书加载器，收到SIGHUP信号时重新加载证书，无需重启服务
+type certReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	mutex     *sync.RWMutex
+}
+
+func newCertReloader(certFile, keyFile, clientCAFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		mutex:        &sync.RWMutex{},
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	routines.GoSafe(func() { r.watchSighup() })
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "Fail to load tls key pair")
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		caPem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "Fail to read client ca file")
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPem) {
+			return ErrInvalidClientCA
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+func (r *certReloader) watchSighup() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := r.reload(); err != nil {
+			log.Error().Err(err).Str("cert", r.certFile).Msg("Fail to reload tls certificate, keep the old one")
+			continue
+		}
+		log.Info().Str("cert", r.certFile).Msg("Reloaded tls certificate")
+	}
+}
+
+// 每次握手时读取最新的证书和CA
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+			conf := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCAs != nil {
+				conf.ClientCAs = r.clientCAs
+				conf.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return conf, nil
+		},
+	}
+}
+
+// 完成tls握手，双向认证时将客户端证书身份记录到session
+func tlsHandshake(session *model.Session) error {
+	tlsConn, ok := session.Conn.(*tls.Conn)
+	if !ok {
+		return nil // 非tls连接
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return errors.Wrap(err, "Fail to do tls handshake")
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil // 单向认证
+	}
+	cert := state.VerifiedChains[0][0]
+	fingerprint := sha256.Sum256(cert.Raw)
+	session.PeerIdentity = &model.PeerIdentity{
+		CommonName:   cert.Subject.CommonName,
+		SerialNumber: cert.SerialNumber.Text(16),
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+	}
+	log.Debug().Str("sessionId", session.ID).Str("cn", cert.Subject.CommonName).Msg("Verified client certificate")
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+// 签发测试证书，parent为nil时生成自签名CA
+func genTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return cert, key, certPem, keyPem
+}
+
+func TestTLSHandshake_mutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPem, _ := genTestCert(t, "jt808-ca", nil, nil)
+	_, _, servPem, servKeyPem := genTestCert(t, "jt808-server", ca, caKey)
+	_, _, cliPem, cliKeyPem := genTestCert(t, "013812345678", ca, caKey)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+	reloader, err := newCertReloader(write("server.crt", servPem), write("server.key", servKeyPem), write("ca.crt", caPem))
+	require.Nil(t, err)
+
+	cliCert, err := tls.X509KeyPair(cliPem, cliKeyPem)
+	require.Nil(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	servConn, cliConn := net.Pipe()
+	defer servConn.Close()
+	defer cliConn.Close()
+	go func() {
+		cli := tls.Client(cliConn, &tls.Config{
+			ServerName:   "127.0.0.1",
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{cliCert},
+		})
+		_ = cli.Handshake()
+	}()
+
+	session := &model.Session{Conn: tls.Server(servConn, reloader.tlsConfig())}
+	require.Nil(t, tlsHandshake(session))
+	require.NotNil(t, session.PeerIdentity)
+	require.Equal(t, "013812345678", session.PeerIdentity.CommonName)
+}
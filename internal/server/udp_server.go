@@ -0,0 +1,453 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+const (
+	udpMaxDatagramLen = 65535 // udp数据报最大长度
+	udpRecvQueueLen   = 64    // 每个会话缓存的待处理数据报个数
+)
+
+type UDPServer struct {
+	conn   *net.UDPConn
+	Sender *protocol.Sender
+
+	connByAddr  map[string]*udpConn // <remote addr, conn>
+	connByPhone map[string]*udpConn // <phone, conn>, 已鉴权的会话，终端地址变化时(如NAT重新映射)替换为新地址鉴权通过的会话
+	mutex       *sync.Mutex
+	done        chan struct{}
+	stopOnce    sync.Once
+	idle        *idleReaper    // 空闲会话清理，按会话状态区分超时时间
+	wg          sync.WaitGroup // 处理中的session协程
+}
+
+func NewUDPServer() *UDPServer {
+	return &UDPServer{
+		Sender:      protocol.NewSender(),
+		connByAddr:  make(map[string]*udpConn),
+		connByPhone: make(map[string]*udpConn),
+		mutex:       &sync.Mutex{},
+		done:        make(chan struct{}),
+		idle:        getIdleReaper(),
+	}
+}
+
+func (serv *UDPServer) Listen(addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err == nil {
+		serv.conn = conn
+		log.Debug().Msgf("Listening udp on %v", addr)
+	}
+
+	return err
+}
+
+func (serv *UDPServer) Start() {
+	// 启动sender
+	routines.GoSafe(func() { serv.Sender.Run(context.Background()) })
+	// 清理空闲会话
+	serv.idle.run()
+
+	buf := make([]byte, udpMaxDatagramLen)
+	for {
+		n, addr, err := serv.conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Error().Err(err).Msg("Fail to read udp datagram")
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		serv.dispatch(addr, datagram)
+	}
+}
+
+// Stop 优雅停止
+//
+// 丢弃新的数据报 -> 发送sender队列中的消息 -> 处理完会话中已缓存的数据报 -> 关闭socket。
+// ctx超时后强制关闭所有会话。可重复调用
+func (serv *UDPServer) Stop(ctx context.Context) error {
+	serv.stopOnce.Do(func() {
+		close(serv.done)
+		serv.idle.close()
+	})
+
+	if err := serv.Sender.Stop(ctx); err != nil {
+		log.Error().Err(err).Msg("Fail to flush sender before stopping")
+	}
+
+	// 读超时后serve协程会在处理完已缓存的数据报后退出
+	serv.mutex.Lock()
+	for _, uc := range serv.connByAddr {
+		_ = uc.SetReadDeadline(time.Now())
+	}
+	serv.mutex.Unlock()
+
+	done := make(chan struct{})
+	routines.GoSafe(func() {
+		serv.wg.Wait()
+		close(done)
+	})
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		serv.mutex.Lock()
+		for _, uc := range serv.connByAddr {
+			uc.Close()
+		}
+		serv.mutex.Unlock()
+		err = ctx.Err()
+	}
+
+	serv.conn.Close()
+	return err
+}
+
+// 将数据报分发到对应的逻辑session，新的终端地址会创建session
+func (serv *UDPServer) dispatch(addr *net.UDPAddr, datagram []byte) {
+	select {
+	case <-serv.done:
+		return // 停止中，不再接收新的数据报
+	default:
+	}
+
+	uc, isNew := serv.lookup(addr, datagram)
+	if isNew {
+		session := serv.accept(uc)
+		serv.wg.Add(1)
+		routines.GoSafe(func() {
+			defer serv.wg.Done()
+			serv.serve(session)
+		})
+	}
+	uc.deliver(datagram)
+}
+
+// 按地址查找会话，找不到则新建。新地址即使携带已有会话的手机号也新建未鉴权的会话，鉴权通过后才替换原会话
+func (serv *UDPServer) lookup(addr *net.UDPAddr, datagram []byte) (*udpConn, bool) {
+	serv.mutex.Lock()
+	defer serv.mutex.Unlock()
+
+	remoteAddr := addr.String()
+	if uc, ok := serv.connByAddr[remoteAddr]; ok {
+		return uc, false
+	}
+
+	uc := newUDPConn(serv.conn, addr)
+	uc.phone = peekPhone(datagram)
+	serv.connByAddr[remoteAddr] = uc
+	return uc, true
+}
+
+// 会话鉴权通过后按手机号绑定。终端地址变化(如NAT重新映射)时，新地址鉴权通过后关闭原会话，终端改用新会话
+func (serv *UDPServer) bindPhone(session *model.Session) {
+	uc, ok := session.Conn.(*udpConn)
+	if !ok || uc.phone == "" || session.GetState() != model.SessionStateAuthenticated {
+		return
+	}
+
+	serv.mutex.Lock()
+	prev, bound := serv.connByPhone[uc.phone]
+	if bound && prev == uc {
+		serv.mutex.Unlock()
+		return
+	}
+	serv.connByPhone[uc.phone] = uc
+	serv.mutex.Unlock()
+
+	if bound {
+		log.Debug().Str("phone", uc.phone).Str("from", prev.RemoteAddr().String()).Str("to", uc.RemoteAddr().String()).
+			Msg("Rebind udp session to new remote addr")
+		prev.Close()
+	}
+
+	// 终端未重新注册时仍指向原会话，缓存中的设备会被其他协程读取，修改副本后重新缓存
+	cache := storage.GetDeviceCache()
+	if device, err := cache.GetDeviceByPhone(uc.phone); err == nil && device.SessionID != session.ID {
+		updated := *device
+		updated.SessionID = session.ID
+		updated.Conn = session.Conn
+		cache.CacheDevice(&updated)
+	}
+}
+
+// 从数据报的第一帧中解析终端手机号，解析失败返回空字符串
+func peekPhone(datagram []byte) (phone string) {
+	start := bytes.IndexByte(datagram, 0x7e)
+	if start < 0 {
+		return ""
+	}
+	end := bytes.IndexByte(datagram[start+1:], 0x7e)
+	if end < 0 {
+		return ""
+	}
+	frame := datagram[start : start+end+2]
+	routines.RunSafe(func() {
+		header, err := protocol.NewJT808PacketCodec().DecodeHeader(frame)
+		if err == nil {
+			phone = header.PhoneNumber
+		}
+	})
+	return phone
+}
+
+// 将udpConn封装为逻辑session
+func (serv *UDPServer) accept(uc *udpConn) *model.Session {
+	session := &model.Session{
+		Conn: uc,
+		ID:   uc.RemoteAddr().String(), // using first remote addr default
+	}
+	session.Touch()
+	storage.StoreSession(session)
+
+	return session
+}
+
+func (serv *UDPServer) remove(session *model.Session) {
+	// 写完发送队列中的消息再关闭会话
+	session.Close()
+	storage.ClearSession(session.ID)
+
+	serv.mutex.Lock()
+	defer serv.mutex.Unlock()
+	for addr, uc := range serv.connByAddr {
+		if uc == session.Conn {
+			delete(serv.connByAddr, addr)
+		}
+	}
+	for phone, uc := range serv.connByPhone {
+		if uc == session.Conn {
+			delete(serv.connByPhone, phone)
+		}
+	}
+
+	log.Debug().Str("sessionId", session.ID).Msg("Closing udp session.")
+}
+
+// 处理每个session的消息
+func (serv *UDPServer) serve(session *model.Session) {
+	defer serv.remove(session)
+
+	pg := protocol.NewPipeline(session.Conn)
+	for {
+		// 记录value ctx
+		ctx := context.WithValue(context.Background(), model.SessionCtxKey{}, session)
+		ctx = context.WithValue(ctx,
+			model.ProcResponseCallBackKey{},
+			model.ProcResponseFn(serv.Sender.ProcResponse))
+		ctx = context.WithValue(ctx,
+			model.ProcResponseByMsgIDCallBackKey{},
+			model.ProcResponseByMsgIDFn(serv.Sender.ProcResponseByMsgID))
+
+		serv.setReadDeadline(session)
+		err := pg.ProcessConnRead(ctx)
+
+		if err == nil {
+			serv.bindPhone(session)
+			continue
+		}
+
+		log.Error().Err(err).Str("sessionId", session.ID).Msg("Failed to serve session")
+
+		switch {
+		case errors.Is(err, io.EOF),
+			errors.Is(err, io.ErrClosedPipe),
+			errors.Is(err, net.ErrClosed),
+			errors.Is(err, os.ErrDeadlineExceeded),
+			errors.Is(err, storage.ErrDeviceNotFound):
+			return // close session when EOF or closed
+		default:
+			continue // udp数据报彼此独立，无需等待
+		}
+	}
+}
+
+// 按会话状态设置读超时，停止中时立即超时，读完已缓存的数据报后退出
+func (serv *UDPServer) setReadDeadline(session *model.Session) {
+	select {
+	case <-serv.done:
+		_ = session.Conn.SetReadDeadline(time.Now())
+	default:
+		serv.idle.setReadDeadline(session)
+	}
+}
+
+// 发送消息到终端设备, 外部调用
+func (serv *UDPServer) Send(id string, smsg any) {
+	msg := smsg.(model.JT808Msg)
+	session, err := storage.GetSession(id)
+	if err != nil && errors.Is(err, storage.ErrSessionClosed) {
+		log.Warn().Str("id", id).Msg("Fail to get session from cache, maybe conn was closed.")
+		return
+	}
+
+	pg := protocol.NewPipeline(session.Conn)
+
+	// 记录value ctx
+	ctx := context.WithValue(context.Background(), model.SessionCtxKey{}, session)
+	ctx = context.WithValue(ctx, model.ProcessDataCtxKey{}, &model.ProcessData{Outgoing: msg})
+
+	err = pg.ProcessConnWrite(ctx)
+
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) || errors.Is(err, model.ErrWriteQueueClosed) {
+		serv.remove(session)
+	}
+
+	log.Error().Err(err).Str("device", id).Msg("Failed to send jtmsg to device")
+}
+
+// SendV2
+// 异步发送消息
+// 并在异步状态下缓存消息，等待响应消息并回调
+func (serv *UDPServer) SendV2(phone string, smsg any, rspFn func(any) error) error {
+	msg := smsg.(model.JT808Msg)
+
+	key := &protocol.SenderKey{
+		Phone:        phone,
+		MsgId:        msg.GetHeader().MsgID,
+		SerialNumber: msg.GetHeader().SerialNumber,
+	}
+	value := &protocol.SenderValue{
+		Phone:            phone,
+		Msg:              msg,
+		ResponseCallBack: rspFn,
+	}
+
+	return serv.Sender.Append(key, value)
+}
+
+// 基于udp数据报模拟的net.Conn，使Pipeline可以像处理tcp连接一样处理udp会话
+type udpConn struct {
+	serv   *net.UDPConn
+	remote *net.UDPAddr // 终端地址，下行消息发往此地址
+
+	phone        string // 第一个数据报中的终端手机号，鉴权通过后按手机号绑定
+	datagrams    chan []byte
+	rbuf         []byte // 未读完的数据报
+	readDeadline time.Time
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	mutex     *sync.Mutex
+}
+
+func newUDPConn(serv *net.UDPConn, remote *net.UDPAddr) *udpConn {
+	return &udpConn{
+		serv:      serv,
+		remote:    remote,
+		datagrams: make(chan []byte, udpRecvQueueLen),
+		closed:    make(chan struct{}),
+		mutex:     &sync.Mutex{},
+	}
+}
+
+// 投递收到的数据报，队列满时丢弃
+func (c *udpConn) deliver(datagram []byte) {
+	select {
+	case c.datagrams <- datagram:
+	default:
+		log.Warn().Str("addr", c.RemoteAddr().String()).Msg("Udp session queue is full, drop datagram")
+	}
+}
+
+func (c *udpConn) Read(b []byte) (int, error) {
+	// 优先读取已缓存的数据报，停止时读超时也不会丢弃它们
+	if len(c.rbuf) == 0 {
+		select {
+		case d := <-c.datagrams:
+			c.rbuf = d
+		default:
+		}
+	}
+	if len(c.rbuf) == 0 {
+		c.mutex.Lock()
+		deadline := c.readDeadline
+		c.mutex.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case d := <-c.datagrams:
+			c.rbuf = d
+		case <-c.closed:
+			return 0, io.EOF
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+func (c *udpConn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	return c.serv.WriteToUDP(b, c.RemoteAddr().(*net.UDPAddr))
+}
+
+func (c *udpConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *udpConn) LocalAddr() net.Addr {
+	return c.serv.LocalAddr()
+}
+
+func (c *udpConn) RemoteAddr() net.Addr {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.remote
+}
+
+func (c *udpConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *udpConn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+// udp共用一个socket，写操作不会阻塞在单个会话上，忽略写超时
+func (c *udpConn) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/fakeyanss/jt808-server-go/internal/codec/hex"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/storage"
+)
+
+func TestUDPServer_lookup(t *testing.T) {
+	serv := NewUDPServer()
+	require.Nil(t, serv.Listen("127.0.0.1:0"))
+	defer serv.conn.Close()
+
+	// 0x0200位置上报，手机号223456789015
+	datagram := hex.Str2Byte("7E0200001C2234567890150000000000000002080301CD779E0728C032003C0000008F230125145158FB7E")
+	addr1 := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1001}
+	addr2 := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 1002}
+	addr3 := &net.UDPAddr{IP: net.ParseIP("10.0.0.3"), Port: 1003}
+
+	uc1, isNew := serv.lookup(addr1, datagram)
+	require.True(t, isNew)
+	require.Equal(t, addr1, uc1.RemoteAddr())
+	require.Equal(t, "223456789015", uc1.phone)
+
+	uc, isNew := serv.lookup(addr1, []byte{0x00})
+	require.False(t, isNew)
+	require.Same(t, uc1, uc)
+
+	// 相同手机号从新地址上报，新建未鉴权的会话，不影响原会话
+	uc, isNew = serv.lookup(addr2, datagram)
+	require.True(t, isNew)
+	require.NotSame(t, uc1, uc)
+	require.Equal(t, addr1, uc1.RemoteAddr())
+
+	// 无法识别手机号的数据报，按地址新建会话
+	uc, isNew = serv.lookup(addr3, []byte{0x7e, 0x00, 0x7e})
+	require.True(t, isNew)
+	require.NotSame(t, uc1, uc)
+}
+
+func TestUDPServer_bindPhone(t *testing.T) {
+	const phone = "223456789016"
+	serv := NewUDPServer()
+	require.Nil(t, serv.Listen("127.0.0.1:0"))
+	defer serv.conn.Close()
+
+	// 0x0200位置上报，手机号223456789016
+	datagram := hex.Str2Byte("7E0200001C2234567890160000000000000002080301CD779E0728C032003C0000008F230125145158F87E")
+	newSession := func(addr *net.UDPAddr) *model.Session {
+		uc, isNew := serv.lookup(addr, datagram)
+		require.True(t, isNew)
+		require.Equal(t, phone, uc.phone)
+		return &model.Session{Conn: uc, ID: addr.String()}
+	}
+
+	// 已鉴权的会话按手机号绑定
+	origin := newSession(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1001})
+	storage.GetDeviceCache().CacheDevice(&model.Device{Phone: phone, Plate: phone, SessionID: origin.ID})
+	defer storage.GetDeviceCache().DelDeviceByPhone(phone)
+	origin.SetState(model.SessionStateAuthenticated)
+	serv.bindPhone(origin)
+	require.Same(t, origin.Conn, serv.connByPhone[phone])
+
+	// 伪造手机号的数据报未鉴权，不能接管下行
+	spoofed := newSession(&net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 1002})
+	serv.bindPhone(spoofed)
+	require.Same(t, origin.Conn, serv.connByPhone[phone])
+	device, err := storage.GetDeviceCache().GetDeviceByPhone(phone)
+	require.Nil(t, err)
+	require.Equal(t, origin.ID, device.SessionID)
+	require.Equal(t, "10.0.0.1:1001", origin.Conn.RemoteAddr().String())
+
+	// 新地址鉴权通过后替换原会话
+	rebound := newSession(&net.UDPAddr{IP: net.ParseIP("10.0.0.3"), Port: 1003})
+	rebound.SetState(model.SessionStateAuthenticated)
+	serv.bindPhone(rebound)
+	require.Same(t, rebound.Conn, serv.connByPhone[phone])
+	device, err = storage.GetDeviceCache().GetDeviceByPhone(phone)
+	require.Nil(t, err)
+	require.Equal(t, rebound.ID, device.SessionID)
+	_, err = origin.Conn.Read(make([]byte, 1))
+	require.NotNil(t, err) // 原会话已关闭
+}
+
+func TestUDPConn_Read(t *testing.T) {
+	uc := newUDPConn(nil, &net.UDPAddr{})
+	uc.deliver([]byte{0x01, 0x02, 0x03})
+
+	buf := make([]byte, 2)
+	n, err := uc.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, buf[:n])
+	n, err = uc.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x03}, buf[:n])
+
+	uc.Close()
+	_, err = uc.Read(buf)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+var ErrAreaNotFound = errors.New("area not found")
+
+// InstalledArea 已下发到终端的区域或路线
+type InstalledArea struct {
+	Type        uint8      `json:"type"`
+	TypeName    string     `json:"typeName"`
+	ID          uint32     `json:"id"`
+	Name        string     `json:"name"`
+	Area        model.Area `json:"area"`
+	InstalledAt time.Time  `json:"installedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type areaKey struct {
+	Type uint8
+	ID   uint32
+}
+
+// AreaCatalog 记录每个终端已安装的区域，终端通用应答成功后再更新
+type AreaCatalog struct {
+	areas map[string]map[areaKey]*InstalledArea // key为终端手机号
+	mutex *sync.RWMutex
+}
+
+var areaCatalogSingleton *AreaCatalog
+var areaCatalogInitOnce sync.Once
+
+func GetAreaCatalog() *AreaCatalog {
+	areaCatalogInitOnce.Do(func() {
+		areaCatalogSingleton = &AreaCatalog{
+			areas: make(map[string]map[areaKey]*InstalledArea),
+			mutex: &sync.RWMutex{},
+		}
+	})
+	return areaCatalogSingleton
+}
+
+// Apply 按终端已成功应答的设置或删除消息更新目录
+func (c *AreaCatalog) Apply(phone string, msg model.JT808Msg) {
+	switch m := msg.(type) {
+	case *model.Msg8600:
+		areas := make([]model.Area, 0, len(m.Areas))
+		for _, a := range m.Areas {
+			areas = append(areas, a)
+		}
+		c.SetAreas(phone, model.AreaTypeCircle, m.Action, areas)
+	case *model.Msg8602:
+		areas := make([]model.Area, 0, len(m.Areas))
+		for _, a := range m.Areas {
+			areas = append(areas, a)
+		}
+		c.SetAreas(phone, model.AreaTypeRectangle, m.Action, areas)
+	case *model.Msg8604:
+		c.SetAreas(phone, model.AreaTypePolygon, model.AreaActionModify, []model.Area{m.Area})
+	case *model.Msg8606:
+		c.SetAreas(phone, model.AreaTypeRoute, model.AreaActionModify, []model.Area{m.Route})
+	case *model.Msg8601:
+		c.DelAreas(phone, model.AreaTypeCircle, m.IDs)
+	case *model.Msg8603:
+		c.DelAreas(phone, model.AreaTypeRectangle, m.IDs)
+	case *model.Msg8605:
+		c.DelAreas(phone, model.AreaTypePolygon, m.IDs)
+	case *model.Msg8607:
+		c.DelAreas(phone, model.AreaTypeRoute, m.IDs)
+	}
+}
+
+// SetAreas 保存区域，更新时先删除该类型的全部区域，追加和修改时按ID覆盖
+func (c *AreaCatalog) SetAreas(phone string, typ, action uint8, areas []model.Area) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	installed, ok := c.areas[phone]
+	if !ok {
+		installed = make(map[areaKey]*InstalledArea)
+		c.areas[phone] = installed
+	}
+	if action == model.AreaActionUpdate {
+		deleteAreas(installed, typ, nil)
+	}
+	now := time.Now()
+	for _, a := range areas {
+		k := areaKey{Type: typ, ID: a.AreaID()}
+		ia, ok := installed[k]
+		if !ok {
+			ia = &InstalledArea{Type: typ, TypeName: model.AreaTypeName(typ), ID: a.AreaID(), InstalledAt: now}
+			installed[k] = ia
+		}
+		ia.Name = a.AreaName()
+		ia.Area = a
+		ia.UpdatedAt = now
+	}
+}
+
+// DelAreas 删除区域，ids为空时删除该类型的全部区域
+func (c *AreaCatalog) DelAreas(phone string, typ uint8, ids []uint32) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	installed, ok := c.areas[phone]
+	if !ok {
+		return
+	}
+	deleteAreas(installed, typ, ids)
+	if len(installed) == 0 {
+		delete(c.areas, phone)
+	}
+}
+
+func deleteAreas(installed map[areaKey]*InstalledArea, typ uint8, ids []uint32) {
+	if len(ids) == 0 {
+		for k := range installed {
+			if k.Type == typ {
+				delete(installed, k)
+			}
+		}
+		return
+	}
+	for _, id := range ids {
+		delete(installed, areaKey{Type: typ, ID: id})
+	}
+}
+
+// ListAreas 按类型和ID排序返回终端已安装的区域，typ为0时返回全部类型
+func (c *AreaCatalog) ListAreas(phone string, typ uint8) []*InstalledArea {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	res := make([]*InstalledArea, 0)
+	for k, ia := range c.areas[phone] {
+		if typ == 0 || k.Type == typ {
+			res = append(res, ia)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Type != res[j].Type {
+			return res[i].Type < res[j].Type
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+func (c *AreaCatalog) GetArea(phone string, typ uint8, id uint32) (*InstalledArea, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	ia, ok := c.areas[phone][areaKey{Type: typ, ID: id}]
+	if !ok {
+		return nil, errors.Wrapf(ErrAreaNotFound, "phone=%s, type=%d, id=%d", phone, typ, id)
+	}
+	return ia, nil
+}
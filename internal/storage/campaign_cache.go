@@ -0,0 +1,591 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+var (
+	ErrCampaignNotFound      = errors.New("campaign not found")
+	ErrInvalidCampaign       = errors.New("invalid campaign")
+	ErrInvalidCampaignStatus = errors.New("invalid campaign status")
+)
+
+const (
+	defaultCampaignConcurrency = 10
+	maxUpgradeAttempts         = 3 // 下发失败(如终端断线)时的最大尝试次数
+	campaignFile               = "campaigns.json"
+)
+
+type CampaignStatus string
+
+const (
+	CampaignRunning  CampaignStatus = "running"
+	CampaignPaused   CampaignStatus = "paused" // 手动暂停，或失败数达到上限
+	CampaignFinished CampaignStatus = "finished"
+	CampaignCanceled CampaignStatus = "canceled"
+)
+
+type UpgradeStatus string
+
+const (
+	UpgradePending   UpgradeStatus = "pending"   // 等待下发，终端离线时保持等待
+	UpgradeSending   UpgradeStatus = "sending"   // 正在下发升级包
+	UpgradeSent      UpgradeStatus = "sent"      // 已下发，等待终端0x0108通知升级结果
+	UpgradeNotified  UpgradeStatus = "notified"  // 终端通知升级成功，等待0x0102鉴权上报版本号
+	UpgradeSucceeded UpgradeStatus = "succeeded" // 鉴权上报的版本号与升级包一致
+	UpgradeFailed    UpgradeStatus = "failed"
+	UpgradeCanceled  UpgradeStatus = "canceled"
+)
+
+// 升级完成，不再占用并发数
+func (s UpgradeStatus) done() bool {
+	return s == UpgradeSucceeded || s == UpgradeFailed || s == UpgradeCanceled
+}
+
+// 正在升级，占用并发数
+func (s UpgradeStatus) inflight() bool {
+	return s == UpgradeSending || s == UpgradeSent || s == UpgradeNotified
+}
+
+// UpgradeProgress 单个终端的升级进度
+type UpgradeProgress struct {
+	Phone           string        `json:"phone"`
+	Stage           int           `json:"stage"` // 所在批次，从1开始
+	Status          UpgradeStatus `json:"status"`
+	Attempts        int           `json:"attempts"`        // 下发次数
+	FromVersion     string        `json:"fromVersion"`     // 创建任务时终端的软件版本号
+	Result          *uint8        `json:"result"`          // 0x0108升级结果，0:成功;1:失败;2:取消
+	ReportedVersion string        `json:"reportedVersion"` // 下发后最近一次鉴权上报的软件版本号，2011/2013版本终端不上报
+	ReportedAt      time.Time     `json:"reportedAt"`
+	Error           string        `json:"error,omitempty"`
+	SentAt          time.Time     `json:"sentAt"`
+	UpdatedAt       time.Time     `json:"updatedAt"`
+}
+
+// Campaign 升级任务，终端按批次下发，每批全部完成后进入下一批
+type Campaign struct {
+	ID          string             `json:"id"`
+	FirmwareID  string             `json:"firmwareId"`
+	UpgradeType uint8              `json:"upgradeType"`
+	Version     string             `json:"version"`     // 升级包版本号
+	StageSize   int                `json:"stageSize"`   // 每批终端数，0表示只有一批
+	Concurrency int                `json:"concurrency"` // 同时升级的终端数上限
+	MaxFailures int                `json:"maxFailures"` // 失败数达到上限时暂停任务，0表示不限制
+	Status      CampaignStatus     `json:"status"`
+	Reason      string             `json:"reason,omitempty"` // 暂停原因
+	Stage       int                `json:"stage"`            // 当前批次，从1开始
+	Stages      int                `json:"stages"`
+	Devices     []*UpgradeProgress `json:"devices"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+
+	failureBase int // 恢复任务时已有的失败数，恢复后重新计算失败上限
+}
+
+// CampaignSummary 升级任务各状态的终端数
+type CampaignSummary map[UpgradeStatus]int
+
+func (c *Campaign) Summary() CampaignSummary {
+	sum := CampaignSummary{}
+	for _, p := range c.Devices {
+		sum[p.Status]++
+	}
+	return sum
+}
+
+// 深拷贝，避免调用方读取时与调度并发修改
+func (c *Campaign) clone() *Campaign {
+	cc := *c
+	cc.Devices = make([]*UpgradeProgress, 0, len(c.Devices))
+	for _, p := range c.Devices {
+		pc := *p
+		cc.Devices = append(cc.Devices, &pc)
+	}
+	return &cc
+}
+
+// UpgradeTask 调度产生的下发任务
+type UpgradeTask struct {
+	CampaignID  string
+	FirmwareID  string
+	UpgradeType uint8
+	Version     string
+	Phone       string
+}
+
+// 升级任务持久化记录，failureBase不对外展示，单独保存
+type campaignRecord struct {
+	*Campaign
+	FailureBase int `json:"failureBase"`
+}
+
+// 升级任务缓存，常驻内存，变更时写入本地文件，重启后继续调度
+type CampaignCache struct {
+	dir       string
+	campaigns map[string]*Campaign
+	seq       int
+	mutex     *sync.Mutex
+}
+
+var campaignCacheSingleton *CampaignCache
+var campaignCacheInitOnce sync.Once
+
+func GetCampaignCache() *CampaignCache {
+	campaignCacheInitOnce.Do(func() {
+		campaignCacheSingleton = &CampaignCache{
+			dir:       defaultFirmwareDir,
+			campaigns: make(map[string]*Campaign),
+			mutex:     &sync.Mutex{},
+		}
+	})
+	return campaignCacheSingleton
+}
+
+// SetCampaignDir 设置保存目录并加载已保存的升级任务，为空时使用默认目录。
+//
+// 重启前正在下发的终端无法确认是否收到升级包，重新等待下发
+func (cache *CampaignCache) SetCampaignDir(dir string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if dir == "" {
+		dir = defaultFirmwareDir
+	}
+	records := []*campaignRecord{}
+	if err := readJSONFile(filepath.Join(dir, campaignFile), &records); err != nil {
+		return err
+	}
+	campaigns := make(map[string]*Campaign, len(records))
+	for _, r := range records {
+		if r.Campaign == nil {
+			continue
+		}
+		c := r.Campaign
+		c.failureBase = r.FailureBase
+		for _, p := range c.Devices {
+			if p.Status == UpgradeSending {
+				p.Status = UpgradePending
+			}
+		}
+		campaigns[c.ID] = c
+	}
+	cache.dir, cache.campaigns = dir, campaigns
+	return nil
+}
+
+// SelectDevicesByVersion 按软件版本号选择已缓存的终端
+func SelectDevicesByVersion(version string) []string {
+	phones := []string{}
+	for _, d := range GetDeviceCache().ListDevice() {
+		if d.SoftwareVersion == version {
+			phones = append(phones, d.Phone)
+		}
+	}
+	sort.Strings(phones)
+	return phones
+}
+
+// CreateCampaign 创建升级任务并立即开始，终端去重后按顺序分批
+func (cache *CampaignCache) CreateCampaign(c *Campaign, phones []string) (*Campaign, error) {
+	if c.FirmwareID == "" {
+		return nil, errors.Wrap(ErrInvalidCampaign, "firmware is empty")
+	}
+	if c.StageSize < 0 || c.Concurrency < 0 || c.MaxFailures < 0 {
+		return nil, errors.Wrap(ErrInvalidCampaign, "negative stageSize, concurrency or maxFailures")
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = defaultCampaignConcurrency
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	c.Devices = make([]*UpgradeProgress, 0, len(phones))
+	for _, phone := range phones {
+		if phone == "" || seen[phone] {
+			continue
+		}
+		seen[phone] = true
+		p := &UpgradeProgress{Phone: phone, Status: UpgradePending, UpdatedAt: now}
+		if d, err := GetDeviceCache().GetDeviceByPhone(phone); err == nil {
+			p.FromVersion = d.SoftwareVersion
+		}
+		c.Devices = append(c.Devices, p)
+	}
+	if len(c.Devices) == 0 {
+		return nil, errors.Wrap(ErrInvalidCampaign, "no target devices")
+	}
+	stageSize := c.StageSize
+	if stageSize == 0 {
+		stageSize = len(c.Devices)
+	}
+	for i, p := range c.Devices {
+		p.Stage = i/stageSize + 1
+	}
+	c.Stages = c.Devices[len(c.Devices)-1].Stage
+	c.Stage = 1
+	c.Status = CampaignRunning
+	c.CreatedAt, c.UpdatedAt = now, now
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.seq++
+	c.ID = fmt.Sprintf("%s-%d", now.Format("20060102150405"), cache.seq)
+	cache.campaigns[c.ID] = c
+	if err := cache.write(); err != nil {
+		delete(cache.campaigns, c.ID)
+		return nil, err
+	}
+	return c.clone(), nil
+}
+
+// ListCampaigns 升级任务列表，按创建时间排列
+func (cache *CampaignCache) ListCampaigns() []*Campaign {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	res := make([]*Campaign, 0, len(cache.campaigns))
+	for _, c := range cache.campaigns {
+		res = append(res, c.clone())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
+
+func (cache *CampaignCache) GetCampaign(id string) (*Campaign, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	c, ok := cache.campaigns[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrCampaignNotFound, "id=%s", id)
+	}
+	return c.clone(), nil
+}
+
+// SetCampaignStatus 暂停、恢复或取消升级任务。
+//
+// 暂停只停止下发新的终端，正在升级的终端继续跟踪结果；取消时等待中的终端标记为取消
+func (cache *CampaignCache) SetCampaignStatus(id string, status CampaignStatus) (*Campaign, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	c, ok := cache.campaigns[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrCampaignNotFound, "id=%s", id)
+	}
+	if c.Status == CampaignFinished || c.Status == CampaignCanceled {
+		return nil, errors.Wrapf(ErrInvalidCampaignStatus, "campaign is %s", c.Status)
+	}
+	now := time.Now()
+	switch status {
+	case CampaignPaused:
+		c.Reason = "paused manually"
+	case CampaignRunning:
+		c.Reason = ""
+		c.failureBase = c.failures()
+	case CampaignCanceled:
+		for _, p := range c.Devices {
+			if p.Status == UpgradePending {
+				p.Status, p.UpdatedAt = UpgradeCanceled, now
+			}
+		}
+	default:
+		return nil, errors.Wrapf(ErrInvalidCampaignStatus, "status=%s", status)
+	}
+	c.Status, c.UpdatedAt = status, now
+	if status == CampaignRunning {
+		cache.evaluate(c, now)
+	}
+	if err := cache.write(); err != nil {
+		return nil, err
+	}
+	return c.clone(), nil
+}
+
+// AcquireTasks 为运行中的任务分配下发任务，只选择当前批次内在线的终端，且不超过并发数。
+//
+// 同一终端同时只会在一个任务中升级。isOnline会读取设备和session缓存，在锁外调用
+func (cache *CampaignCache) AcquireTasks(isOnline func(phone string) bool) []*UpgradeTask {
+	online := make(map[string]bool)
+	for _, phone := range cache.pendingPhones() {
+		online[phone] = isOnline(phone)
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	busy := make(map[string]bool)
+	for _, c := range cache.campaigns {
+		for _, p := range c.Devices {
+			if p.Status.inflight() {
+				busy[p.Phone] = true
+			}
+		}
+	}
+
+	now := time.Now()
+	tasks := []*UpgradeTask{}
+	for _, c := range cache.sortedCampaigns() {
+		if c.Status != CampaignRunning {
+			continue
+		}
+		slots := c.Concurrency
+		for _, p := range c.Devices {
+			if p.Status.inflight() {
+				slots--
+			}
+		}
+		for _, p := range c.Devices {
+			if slots <= 0 {
+				break
+			}
+			if p.Stage != c.Stage || p.Status != UpgradePending || busy[p.Phone] || !online[p.Phone] {
+				continue
+			}
+			p.Status, p.UpdatedAt = UpgradeSending, now
+			p.Attempts++
+			busy[p.Phone] = true
+			slots--
+			tasks = append(tasks, &UpgradeTask{
+				CampaignID:  c.ID,
+				FirmwareID:  c.FirmwareID,
+				UpgradeType: c.UpgradeType,
+				Version:     c.Version,
+				Phone:       p.Phone,
+			})
+		}
+	}
+	if len(tasks) > 0 {
+		cache.persist()
+	}
+	return tasks
+}
+
+// 运行中的任务当前批次内等待下发的终端
+func (cache *CampaignCache) pendingPhones() []string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	phones := []string{}
+	for _, c := range cache.campaigns {
+		if c.Status != CampaignRunning {
+			continue
+		}
+		for _, p := range c.Devices {
+			if p.Stage == c.Stage && p.Status == UpgradePending {
+				phones = append(phones, p.Phone)
+			}
+		}
+	}
+	return phones
+}
+
+// MarkSent 记录升级包下发结果，sendErr为nil表示终端已应答0x0001。失败时重新等待下发，超过尝试次数后标记为失败
+func (cache *CampaignCache) MarkSent(id, phone string, sendErr error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	c, ok := cache.campaigns[id]
+	if !ok {
+		return
+	}
+	p := c.progress(phone)
+	if p == nil || p.Status != UpgradeSending {
+		return
+	}
+	now := time.Now()
+	p.UpdatedAt = now
+	switch {
+	case sendErr == nil:
+		p.Status, p.SentAt = UpgradeSent, now
+	case p.Attempts < maxUpgradeAttempts:
+		p.Status, p.Error = UpgradePending, sendErr.Error()
+	default:
+		p.Status, p.Error = UpgradeFailed, sendErr.Error()
+	}
+	cache.evaluate(c, now)
+	cache.persist()
+}
+
+// ReportResult 终端0x0108通知升级结果
+func (cache *CampaignCache) ReportResult(phone string, upgradeType, result uint8) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	now := time.Now()
+	changed := false
+	for _, c := range cache.campaigns {
+		if c.UpgradeType != upgradeType {
+			continue
+		}
+		p := c.progress(phone)
+		if p == nil || (p.Status != UpgradeSent && p.Status != UpgradeSending) {
+			continue
+		}
+		res := result
+		p.Result, p.UpdatedAt = &res, now
+		switch result {
+		case model.UpgradeResultSuccess:
+			// 终端重连时先鉴权再通知结果，已上报版本号时直接比对
+			if p.ReportedAt.After(p.SentAt) {
+				c.resolveVersion(p)
+			} else {
+				p.Status = UpgradeNotified
+			}
+		case model.UpgradeResultCanceled:
+			p.Status, p.Error = UpgradeCanceled, "canceled by device"
+		default:
+			p.Status, p.Error = UpgradeFailed, fmt.Sprintf("device reported %s", model.Msg0108Result2Str(result))
+		}
+		cache.evaluate(c, now)
+		changed = true
+	}
+	if changed {
+		cache.persist()
+	}
+}
+
+// ReportVersion 终端鉴权上报软件版本号。
+//
+// 终端升级后重连通常先鉴权再发送0x0108，因此下发后的版本号都先记录，收到升级成功通知后再比对
+func (cache *CampaignCache) ReportVersion(phone, version string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	now := time.Now()
+	changed := false
+	for _, c := range cache.campaigns {
+		p := c.progress(phone)
+		if p == nil || (p.Status != UpgradeSent && p.Status != UpgradeNotified) {
+			continue
+		}
+		p.ReportedVersion, p.ReportedAt, p.UpdatedAt = version, now, now
+		if p.Status == UpgradeNotified {
+			c.resolveVersion(p)
+			cache.evaluate(c, now)
+		}
+		changed = true
+	}
+	if changed {
+		cache.persist()
+	}
+}
+
+// 版本号为空(2011/2013版本)时以0x0108结果为准
+func (c *Campaign) resolveVersion(p *UpgradeProgress) {
+	if p.ReportedVersion == "" || p.ReportedVersion == c.Version {
+		p.Status = UpgradeSucceeded
+	} else {
+		p.Status, p.Error = UpgradeFailed, "version mismatch"
+	}
+}
+
+// ExpireTasks 超时未收到升级结果或未重新鉴权的终端标记为失败
+func (cache *CampaignCache) ExpireTasks(timeout time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	now := time.Now()
+	changed := false
+	for _, c := range cache.campaigns {
+		expired := false
+		for _, p := range c.Devices {
+			if p.Status != UpgradeSent && p.Status != UpgradeNotified {
+				continue
+			}
+			if now.Sub(p.SentAt) < timeout {
+				continue
+			}
+			if p.Status == UpgradeSent {
+				p.Error = "wait for upgrade result timeout"
+			} else {
+				p.Error = "wait for authentication timeout"
+			}
+			p.Status, p.UpdatedAt = UpgradeFailed, now
+			expired = true
+		}
+		if expired {
+			cache.evaluate(c, now)
+			changed = true
+		}
+	}
+	if changed {
+		cache.persist()
+	}
+}
+
+// 失败数达到上限时暂停，当前批次全部完成后进入下一批，最后一批完成后结束
+func (cache *CampaignCache) evaluate(c *Campaign, now time.Time) {
+	c.UpdatedAt = now
+	if c.Status != CampaignRunning {
+		return
+	}
+	failures := c.failures() - c.failureBase
+	if c.MaxFailures > 0 && failures >= c.MaxFailures {
+		c.Status, c.Reason = CampaignPaused, fmt.Sprintf("failures reached %d", failures)
+		return
+	}
+	for c.Stage <= c.Stages {
+		for _, p := range c.Devices {
+			if p.Stage == c.Stage && !p.Status.done() {
+				return
+			}
+		}
+		c.Stage++
+	}
+	c.Stage = c.Stages
+	c.Status = CampaignFinished
+}
+
+func (c *Campaign) failures() int {
+	n := 0
+	for _, p := range c.Devices {
+		if p.Status == UpgradeFailed {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Campaign) progress(phone string) *UpgradeProgress {
+	for _, p := range c.Devices {
+		if p.Phone == phone {
+			return p
+		}
+	}
+	return nil
+}
+
+// 先创建的任务优先分配并发
+func (cache *CampaignCache) sortedCampaigns() []*Campaign {
+	res := make([]*Campaign, 0, len(cache.campaigns))
+	for _, c := range cache.campaigns {
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
+
+// 写入本地文件，调用方需持有锁
+func (cache *CampaignCache) write() error {
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return errors.Wrapf(err, "Fail to create campaign dir, dir=%s", cache.dir)
+	}
+	records := make([]*campaignRecord, 0, len(cache.campaigns))
+	for _, c := range cache.sortedCampaigns() {
+		records = append(records, &campaignRecord{Campaign: c, FailureBase: c.failureBase})
+	}
+	return writeJSONFile(filepath.Join(cache.dir, campaignFile), records)
+}
+
+// 调度和终端上报引起的变更无法返回错误，写入失败时只记录日志，下次变更时重新写入
+func (cache *CampaignCache) persist() {
+	if err := cache.write(); err != nil {
+		log.Error().Err(err).Msg("Fail to save campaigns")
+	}
+}
@@ -5,3 +5,7 @@ import "github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 type StatusChangeHandler func(model.DeviceStatus) error
 
 type ReportAlarmMsgHandler func(msg *model.AlarmMsg) error
+
+type ReportTransparentHandler func(event *model.TransparentEvent) error
+
+type ReportGeofenceHandler func(event *model.GeofenceEvent) error
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrFenceNotFound = errors.New("fence not found")
+	ErrInvalidFence  = errors.New("invalid fence")
+)
+
+const (
+	defaultFenceDir     = "./geofence/"
+	fenceFile           = "fences.json"
+	fenceAssignmentFile = "assignments.json"
+)
+
+type FenceType string
+
+const (
+	FenceCircle   FenceType = "circle"
+	FencePolygon  FenceType = "polygon"
+	FenceCorridor FenceType = "corridor" // 以折线为中心线、指定宽度的走廊
+)
+
+// FencePoint 经纬度，单位度，南纬和西经为负数
+type FencePoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (p *FencePoint) valid() bool {
+	return p != nil && p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// Fence 平台侧电子围栏，由平台根据终端上报的位置判断进出，不下发到终端
+type Fence struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Type      FenceType     `json:"type"`
+	Center    *FencePoint   `json:"center,omitempty"` // 圆心，圆形围栏有
+	Radius    float64       `json:"radius,omitempty"` // 半径，单位米，圆形围栏有
+	Points    []*FencePoint `json:"points,omitempty"` // 多边形顶点或走廊中心线拐点
+	Width     float64       `json:"width,omitempty"`  // 走廊宽度，单位米
+	DwellTime int           `json:"dwellTime"`        // 停留超过该时长时产生停留事件，单位秒，0表示不检查
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+func (f *Fence) Validate() error {
+	for _, p := range f.Points {
+		if !p.valid() {
+			return errors.Wrap(ErrInvalidFence, "point is out of range")
+		}
+	}
+	if f.DwellTime < 0 {
+		return errors.Wrapf(ErrInvalidFence, "dwellTime=%d", f.DwellTime)
+	}
+	switch f.Type {
+	case FenceCircle:
+		if !f.Center.valid() || f.Radius <= 0 {
+			return errors.Wrap(ErrInvalidFence, "circle requires center and radius")
+		}
+	case FencePolygon:
+		if len(f.Points) < 3 {
+			return errors.Wrap(ErrInvalidFence, "polygon requires at least 3 points")
+		}
+	case FenceCorridor:
+		if len(f.Points) < 2 || f.Width <= 0 {
+			return errors.Wrap(ErrInvalidFence, "corridor requires at least 2 points and width")
+		}
+	default:
+		return errors.Wrapf(ErrInvalidFence, "type=%s", f.Type)
+	}
+	return nil
+}
+
+// FenceStore 电子围栏和终端绑定关系，常驻内存，修改时写入本地文件
+type FenceStore struct {
+	dir         string
+	fences      map[string]*Fence
+	assignments map[string][]string // key为终端手机号，value为围栏ID
+	revision    uint64              // 围栏每次变更时递增，用于判断空间索引是否需要重建
+	seq         int
+	mutex       *sync.RWMutex
+}
+
+var fenceStoreSingleton *FenceStore
+var fenceStoreInitOnce sync.Once
+
+func GetFenceStore() *FenceStore {
+	fenceStoreInitOnce.Do(func() {
+		fenceStoreSingleton = &FenceStore{
+			dir:         defaultFenceDir,
+			fences:      make(map[string]*Fence),
+			assignments: make(map[string][]string),
+			mutex:       &sync.RWMutex{},
+		}
+	})
+	return fenceStoreSingleton
+}
+
+// SetFenceDir 设置保存目录并加载已保存的围栏，为空时使用默认目录
+func (store *FenceStore) SetFenceDir(dir string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if dir == "" {
+		dir = defaultFenceDir
+	}
+	fences := make(map[string]*Fence)
+	if err := readJSONFile(filepath.Join(dir, fenceFile), &fences); err != nil {
+		return err
+	}
+	assignments := make(map[string][]string)
+	if err := readJSONFile(filepath.Join(dir, fenceAssignmentFile), &assignments); err != nil {
+		return err
+	}
+	store.dir, store.fences, store.assignments = dir, fences, assignments
+	store.revision++
+	return nil
+}
+
+// SaveFence 新增或修改围栏，ID为空时生成
+func (store *FenceStore) SaveFence(f *Fence) (*Fence, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	if f.ID == "" {
+		store.seq++
+		f.ID = fmt.Sprintf("%s-%d", now.Format("20060102150405"), store.seq)
+	}
+	f.CreatedAt, f.UpdatedAt = now, now
+	if old, ok := store.fences[f.ID]; ok {
+		f.CreatedAt = old.CreatedAt
+	}
+	fences := make(map[string]*Fence, len(store.fences)+1)
+	for id, fence := range store.fences {
+		fences[id] = fence
+	}
+	fences[f.ID] = f
+	if err := store.write(fenceFile, fences); err != nil {
+		return nil, err
+	}
+	store.fences = fences
+	store.revision++
+	return f, nil
+}
+
+// DelFence 删除围栏，并解除与终端的绑定
+func (store *FenceStore) DelFence(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.fences[id]; !ok {
+		return errors.Wrapf(ErrFenceNotFound, "id=%s", id)
+	}
+	fences := make(map[string]*Fence, len(store.fences))
+	for fid, fence := range store.fences {
+		if fid != id {
+			fences[fid] = fence
+		}
+	}
+	assignments := make(map[string][]string, len(store.assignments))
+	for phone, ids := range store.assignments {
+		remain := make([]string, 0, len(ids))
+		for _, fid := range ids {
+			if fid != id {
+				remain = append(remain, fid)
+			}
+		}
+		if len(remain) > 0 {
+			assignments[phone] = remain
+		}
+	}
+	if err := store.write(fenceFile, fences); err != nil {
+		return err
+	}
+	if err := store.write(fenceAssignmentFile, assignments); err != nil {
+		return err
+	}
+	store.fences, store.assignments = fences, assignments
+	store.revision++
+	return nil
+}
+
+func (store *FenceStore) GetFence(id string) (*Fence, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	f, ok := store.fences[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrFenceNotFound, "id=%s", id)
+	}
+	return f, nil
+}
+
+// ListFences 围栏列表，按创建时间排列
+func (store *FenceStore) ListFences() []*Fence {
+	fences, _ := store.Snapshot()
+	sort.Slice(fences, func(i, j int) bool {
+		if fences[i].CreatedAt.Equal(fences[j].CreatedAt) {
+			return fences[i].ID < fences[j].ID
+		}
+		return fences[i].CreatedAt.Before(fences[j].CreatedAt)
+	})
+	return fences
+}
+
+// Snapshot 全部围栏和当前版本号，围栏保存后不再修改，可以直接读取
+func (store *FenceStore) Snapshot() ([]*Fence, uint64) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	fences := make([]*Fence, 0, len(store.fences))
+	for _, f := range store.fences {
+		fences = append(fences, f)
+	}
+	return fences, store.revision
+}
+
+// SetAssignments 设置终端绑定的围栏，ids为空时解除全部绑定
+func (store *FenceStore) SetAssignments(phone string, ids []string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	uniq := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := store.fences[id]; !ok {
+			return errors.Wrapf(ErrFenceNotFound, "id=%s", id)
+		}
+		uniq[id] = struct{}{}
+	}
+	assigned := make([]string, 0, len(uniq))
+	for id := range uniq {
+		assigned = append(assigned, id)
+	}
+	sort.Strings(assigned)
+
+	assignments := make(map[string][]string, len(store.assignments)+1)
+	for p, fids := range store.assignments {
+		assignments[p] = fids
+	}
+	if len(assigned) == 0 {
+		delete(assignments, phone)
+	} else {
+		assignments[phone] = assigned
+	}
+	if err := store.write(fenceAssignmentFile, assignments); err != nil {
+		return err
+	}
+	store.assignments = assignments
+	return nil
+}
+
+// GetAssignments 终端绑定的围栏ID
+func (store *FenceStore) GetAssignments(phone string) []string {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	return store.assignments[phone]
+}
+
+func (store *FenceStore) write(file string, v any) error {
+	if err := os.MkdirAll(store.dir, 0755); err != nil {
+		return errors.Wrapf(err, "Fail to create fence dir, dir=%s", store.dir)
+	}
+	return writeJSONFile(filepath.Join(store.dir, file), v)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrFirmwareNotFound = errors.New("firmware not found")
+
+const (
+	defaultFirmwareDir = "./firmware/"
+	firmwareIndexFile  = "index.json"
+	firmwareExt        = ".bin"
+)
+
+// Firmware 升级包信息，以文件内容的MD5作为ID，重复上传相同文件时覆盖信息
+type Firmware struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`           // 上传时的文件名
+	UpgradeType    uint8     `json:"upgradeType"`    // 升级类型，0:终端;12:道路运输证IC卡读卡器;52:北斗卫星定位模块
+	ManufacturerID string    `json:"manufacturerId"` // 制造商ID
+	Version        string    `json:"version"`        // 升级后的版本号，终端鉴权时上报的软件版本号与之比对
+	Size           int       `json:"size"`
+	UploadedAt     time.Time `json:"uploadedAt"`
+}
+
+// 升级包存储，文件和索引保存在同一目录
+type FirmwareStore struct {
+	dir   string
+	mutex *sync.Mutex
+}
+
+var firmwareStoreSingleton *FirmwareStore
+var firmwareStoreInitOnce sync.Once
+
+func GetFirmwareStore() *FirmwareStore {
+	firmwareStoreInitOnce.Do(func() {
+		firmwareStoreSingleton = &FirmwareStore{
+			dir:   defaultFirmwareDir,
+			mutex: &sync.Mutex{},
+		}
+	})
+	return firmwareStoreSingleton
+}
+
+// SetFirmwareDir 设置升级包保存目录，为空时使用默认目录
+func (store *FirmwareStore) SetFirmwareDir(dir string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if dir == "" {
+		dir = defaultFirmwareDir
+	}
+	store.dir = dir
+}
+
+// SaveFirmware 保存升级包文件并更新索引
+func (store *FirmwareStore) SaveFirmware(fw *Firmware, data []byte) (*Firmware, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if err := os.MkdirAll(store.dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "Fail to create firmware dir, dir=%s", store.dir)
+	}
+	sum := md5.Sum(data)
+	fw.ID = hex.EncodeToString(sum[:])
+	fw.Size = len(data)
+	fw.UploadedAt = time.Now()
+	if err := os.WriteFile(filepath.Join(store.dir, fw.ID+firmwareExt), data, 0644); err != nil {
+		return nil, errors.Wrapf(err, "Fail to write firmware file, id=%s", fw.ID)
+	}
+
+	fws, err := store.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	fws[fw.ID] = fw
+	if err = writeJSONFile(filepath.Join(store.dir, firmwareIndexFile), fws); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// ListFirmware 升级包列表，按上传时间排列
+func (store *FirmwareStore) ListFirmware() ([]*Firmware, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	fws, err := store.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Firmware, 0, len(fws))
+	for _, fw := range fws {
+		res = append(res, fw)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].UploadedAt.Before(res[j].UploadedAt)
+	})
+	return res, nil
+}
+
+// GetFirmware 升级包信息
+func (store *FirmwareStore) GetFirmware(id string) (*Firmware, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	fws, err := store.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	fw, ok := fws[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrFirmwareNotFound, "id=%s", id)
+	}
+	return fw, nil
+}
+
+// LoadFirmware 读取升级包文件内容
+func (store *FirmwareStore) LoadFirmware(id string) ([]byte, error) {
+	fw, err := store.GetFirmware(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(store.dir, fw.ID+firmwareExt))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read firmware file, id=%s", fw.ID)
+	}
+	return data, nil
+}
+
+func (store *FirmwareStore) readIndex() (map[string]*Firmware, error) {
+	fws := make(map[string]*Firmware)
+	data, err := os.ReadFile(filepath.Join(store.dir, firmwareIndexFile))
+	if os.IsNotExist(err) {
+		return fws, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read firmware index, dir=%s", store.dir)
+	}
+	if err = json.Unmarshal(data, &fws); err != nil {
+		return nil, errors.Wrapf(err, "Fail to parse firmware index, dir=%s", store.dir)
+	}
+	return fws, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrFragmentNotFound = errors.New("fragment not found")
+
+// 已发送分包的保留时间，超时后不再响应补传请求
+const sentFragmentTTL = 5 * time.Minute
+
+type sentFragments struct {
+	frames   [][]byte // 按包序号排列的已编码数据帧
+	expireAt time.Time
+}
+
+// 平台下发的分包消息缓存，用于响应终端的0x0005补传分包请求
+type FragmentCache struct {
+	cacheByKey map[string]*sentFragments
+	mutex      *sync.Mutex
+}
+
+var fragmentCacheSingleton *FragmentCache
+var fragmentCacheInitOnce sync.Once
+
+func getFragmentCache() *FragmentCache {
+	fragmentCacheInitOnce.Do(func() {
+		fragmentCacheSingleton = &FragmentCache{
+			cacheByKey: make(map[string]*sentFragments),
+			mutex:      &sync.Mutex{},
+		}
+	})
+	return fragmentCacheSingleton
+}
+
+// 以终端手机号和第一个分包的流水号作为key
+func fragmentKey(phone string, firstSerial uint16) string {
+	return fmt.Sprintf("%s/%d", phone, firstSerial)
+}
+
+// CacheSentFragments 缓存已发送的分包数据帧，同时清理过期的缓存
+func CacheSentFragments(phone string, firstSerial uint16, frames [][]byte) {
+	cache := getFragmentCache()
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := time.Now()
+	for k, v := range cache.cacheByKey {
+		if now.After(v.expireAt) {
+			delete(cache.cacheByKey, k)
+		}
+	}
+	cache.cacheByKey[fragmentKey(phone, firstSerial)] = &sentFragments{
+		frames:   frames,
+		expireAt: now.Add(sentFragmentTTL),
+	}
+}
+
+// GetSentFragments 按包序号(从1开始)获取已发送的分包数据帧。
+//
+// 每次补传都会顺延保留时间，升级包等大消息可以多次补传直到终端收齐
+func GetSentFragments(phone string, firstSerial uint16, indexes []uint16) ([][]byte, error) {
+	cache := getFragmentCache()
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := time.Now()
+	sent, ok := cache.cacheByKey[fragmentKey(phone, firstSerial)]
+	if !ok || now.After(sent.expireAt) {
+		return nil, ErrFragmentNotFound
+	}
+	sent.expireAt = now.Add(sentFragmentTTL)
+	frames := make([][]byte, 0, len(indexes))
+	for _, i := range indexes {
+		if i == 0 || int(i) > len(sent.frames) {
+			return nil, errors.Wrapf(ErrFragmentNotFound, "index=%d", i)
+		}
+		frames = append(frames, sent.frames[i-1])
+	}
+	return frames, nil
+}
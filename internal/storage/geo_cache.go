@@ -14,8 +14,9 @@ const RingCapacity int32 = 100
 var ErrGisNotFound = errors.New("gis not found")
 
 type GeoCache struct {
-	cacheByPhone map[string]*container.RingBuffer
-	mutex        *sync.Mutex
+	cacheByPhone  map[string]*container.RingBuffer
+	latestByPhone map[string]*model.DeviceGeo // 定位时间最新的位置，补报的历史位置不会覆盖
+	mutex         *sync.Mutex
 }
 
 var geoCacheSingleton *GeoCache
@@ -24,8 +25,9 @@ var geoCacheInitOnce sync.Once
 func GetGeoCache() *GeoCache {
 	geoCacheInitOnce.Do(func() {
 		geoCacheSingleton = &GeoCache{
-			cacheByPhone: make(map[string]*container.RingBuffer),
-			mutex:        &sync.Mutex{},
+			cacheByPhone:  make(map[string]*container.RingBuffer),
+			latestByPhone: make(map[string]*model.DeviceGeo),
+			mutex:         &sync.Mutex{},
 		}
 	})
 	return geoCacheSingleton
@@ -42,14 +44,25 @@ func (cache *GeoCache) GetGeoRingByPhone(phone string) *container.RingBuffer {
 	return cache.cacheByPhone[phone]
 }
 
-func (cache *GeoCache) SaveGeoInfoByPhone(phone string, dg *model.DeviceGeo) {
+// SaveGeoInfoByPhone 保存位置到历史记录，定位时间不早于当前最新位置时更新最新位置。
+// 返回是否更新了最新位置
+func (cache *GeoCache) SaveGeoInfoByPhone(phone string, dg *model.DeviceGeo) bool {
 	rb := cache.GetGeoRingByPhone(phone)
 	rb.Write(dg)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if latest, ok := cache.latestByPhone[phone]; ok && dg.Time.Before(latest.Time) {
+		return false
+	}
+	cache.latestByPhone[phone] = dg
+	return true
 }
 
 func (cache *GeoCache) GetGeoLatestByPhone(phone string) (*model.DeviceGeo, error) {
-	rb := cache.GetGeoRingByPhone(phone)
-	if latest, ok := rb.Latest().(*model.DeviceGeo); ok {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if latest, ok := cache.latestByPhone[phone]; ok {
 		return latest, nil
 	}
 	return nil, ErrGisNotFound
@@ -59,4 +72,5 @@ func (cache *GeoCache) DelGeoByPhone(phone string) {
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
 	delete(cache.cacheByPhone, phone)
+	delete(cache.latestByPhone, phone)
 }
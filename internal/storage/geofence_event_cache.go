@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+// 每个终端保留的最近围栏事件数
+const maxGeofenceEvents = 50
+
+type GeofenceEventCache struct {
+	cacheByPhone map[string][]*model.GeofenceEvent
+
+	// 产生围栏事件时的回调函数
+	hook ReportGeofenceHandler
+
+	mutex *sync.Mutex
+}
+
+var geofenceEventCacheSingleton *GeofenceEventCache
+var geofenceEventCacheInitOnce sync.Once
+
+func GetGeofenceEventCache() *GeofenceEventCache {
+	geofenceEventCacheInitOnce.Do(func() {
+		geofenceEventCacheSingleton = &GeofenceEventCache{
+			cacheByPhone: make(map[string][]*model.GeofenceEvent),
+			mutex:        &sync.Mutex{},
+		}
+	})
+	return geofenceEventCacheSingleton
+}
+
+func (cache *GeofenceEventCache) SetGeofenceHook(handler ReportGeofenceHandler) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.hook = handler
+}
+
+// CacheEvent 保存围栏事件并回调，超出数量时丢弃最早的事件
+func (cache *GeofenceEventCache) CacheEvent(event *model.GeofenceEvent) {
+	cache.mutex.Lock()
+	events := append(cache.cacheByPhone[event.Phone], event)
+	if len(events) > maxGeofenceEvents {
+		events = events[len(events)-maxGeofenceEvents:]
+	}
+	cache.cacheByPhone[event.Phone] = events
+	hook := cache.hook
+	cache.mutex.Unlock()
+
+	if hook != nil {
+		_ = hook(event)
+	}
+}
+
+// ListEvents 终端最近的围栏事件，按产生顺序排列
+func (cache *GeofenceEventCache) ListEvents(phone string) []*model.GeofenceEvent {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	events := cache.cacheByPhone[phone]
+	return append(make([]*model.GeofenceEvent, 0, len(events)), events...)
+}
+
+func (cache *GeofenceEventCache) DelEvents(phone string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.cacheByPhone, phone)
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// 先写临时文件再替换，避免写入中断时文件损坏
+func writeJSONFile(file string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Fail to serialize json, file=%s", file)
+	}
+	tmp := file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrapf(err, "Fail to write file, file=%s", file)
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		return errors.Wrapf(err, "Fail to write file, file=%s", file)
+	}
+	return nil
+}
+
+// 读取json文件，文件不存在时保持v不变
+func readJSONFile(file string, v any) error {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Fail to read file, file=%s", file)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "Fail to parse file, file=%s", file)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/rsa"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var ErrKeyNotFound = errors.New("rsa key not found")
+
+type deviceKey struct {
+	pub             *rsa.PublicKey // 终端RSA公钥，用于加密下发的消息体
+	platformKeySent bool           // 是否已向终端下发平台公钥
+}
+
+// 终端RSA公钥缓存，通过0x0A00消息上报
+type KeyCache struct {
+	cacheByPhone map[string]*deviceKey
+	mutex        *sync.Mutex
+}
+
+var keyCacheSingleton *KeyCache
+var keyCacheInitOnce sync.Once
+
+func GetKeyCache() *KeyCache {
+	keyCacheInitOnce.Do(func() {
+		keyCacheSingleton = &KeyCache{
+			cacheByPhone: make(map[string]*deviceKey),
+			mutex:        &sync.Mutex{},
+		}
+	})
+	return keyCacheSingleton
+}
+
+func (cache *KeyCache) GetDeviceKey(phone string) (*rsa.PublicKey, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if k, ok := cache.cacheByPhone[phone]; ok && k.pub != nil {
+		return k.pub, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (cache *KeyCache) StoreDeviceKey(phone string, pub *rsa.PublicKey) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if k, ok := cache.cacheByPhone[phone]; ok {
+		k.pub = pub
+		return
+	}
+	cache.cacheByPhone[phone] = &deviceKey{pub: pub}
+}
+
+// MarkPlatformKeySent 记录已下发平台公钥，返回之前是否已下发
+func (cache *KeyCache) MarkPlatformKeySent(phone string) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	k, ok := cache.cacheByPhone[phone]
+	if !ok {
+		k = &deviceKey{}
+		cache.cacheByPhone[phone] = k
+	}
+	sent := k.platformKeySent
+	k.platformKeySent = true
+	return sent
+}
+
+func (cache *KeyCache) DelDeviceKey(phone string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.cacheByPhone, phone)
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrMediaNotFound    = errors.New("media not found")
+	ErrInvalidMediaPath = errors.New("invalid media path")
+)
+
+const (
+	defaultMediaDir = "./media/"
+	mediaIndexFile  = "index.json" // 每个终端目录下的多媒体索引文件
+)
+
+// MediaIndex 多媒体文件索引
+type MediaIndex struct {
+	Phone      string    `json:"phone"`
+	MediaID    uint32    `json:"mediaId"`
+	MediaType  uint8     `json:"mediaType"` // 0:图像;1:音频;2:视频
+	Format     uint8     `json:"format"`    // 0:JPEG;1:TIF;2:MP3;3:WAV;4:WMV
+	EventID    uint8     `json:"eventId"`   // 0:平台下发指令;1:定时动作;2:抢劫报警触发;3:碰撞侧翻报警触发
+	ChannelID  uint8     `json:"channelId"`
+	Latitude   uint32    `json:"latitude"`
+	Longitude  uint32    `json:"longitude"`
+	GeoTime    string    `json:"geoTime"` // 拍摄时的定位时间
+	FileName   string    `json:"fileName"`
+	Size       int       `json:"size"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// 多媒体文件存储，按终端手机号分目录保存文件和索引
+type MediaStore struct {
+	dir   string
+	mutex *sync.Mutex
+}
+
+var mediaStoreSingleton *MediaStore
+var mediaStoreInitOnce sync.Once
+
+func GetMediaStore() *MediaStore {
+	mediaStoreInitOnce.Do(func() {
+		mediaStoreSingleton = &MediaStore{
+			dir:   defaultMediaDir,
+			mutex: &sync.Mutex{},
+		}
+	})
+	return mediaStoreSingleton
+}
+
+// SetMediaDir 设置多媒体文件保存目录，为空时使用默认目录
+func (store *MediaStore) SetMediaDir(dir string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if dir == "" {
+		dir = defaultMediaDir
+	}
+	store.dir = dir
+}
+
+// 终端的多媒体目录，手机号只能是数字，避免拼接出其他目录
+func (store *MediaStore) phoneDir(phone string) (string, error) {
+	if phone == "" {
+		return "", ErrInvalidMediaPath
+	}
+	for _, c := range phone {
+		if c < '0' || c > '9' {
+			return "", errors.Wrapf(ErrInvalidMediaPath, "phone=%s", phone)
+		}
+	}
+	return filepath.Join(store.dir, phone), nil
+}
+
+// SaveMedia 保存多媒体文件并更新索引，相同多媒体ID的文件覆盖保存
+func (store *MediaStore) SaveMedia(index *MediaIndex, ext string, data []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	dir, err := store.phoneDir(index.Phone)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "Fail to create media dir, dir=%s", dir)
+	}
+
+	index.FileName = fmt.Sprintf("%d.%s", index.MediaID, ext)
+	index.Size = len(data)
+	if err = os.WriteFile(filepath.Join(dir, index.FileName), data, 0644); err != nil {
+		return errors.Wrapf(err, "Fail to write media file, file=%s", index.FileName)
+	}
+
+	indexes, err := readMediaIndexes(dir)
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, idx := range indexes {
+		if idx.MediaID == index.MediaID {
+			if idx.FileName != index.FileName {
+				_ = os.Remove(filepath.Join(dir, idx.FileName))
+			}
+			indexes[i] = index
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		indexes = append(indexes, index)
+	}
+	return writeMediaIndexes(dir, indexes)
+}
+
+// ListMedia 终端的多媒体索引，按多媒体ID排列
+func (store *MediaStore) ListMedia(phone string) ([]*MediaIndex, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	dir, err := store.phoneDir(phone)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := readMediaIndexes(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].MediaID < indexes[j].MediaID
+	})
+	return indexes, nil
+}
+
+// GetMediaFile 多媒体索引及文件路径
+func (store *MediaStore) GetMediaFile(phone string, mediaID uint32) (*MediaIndex, string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	dir, err := store.phoneDir(phone)
+	if err != nil {
+		return nil, "", err
+	}
+	indexes, err := readMediaIndexes(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, idx := range indexes {
+		if idx.MediaID == mediaID {
+			return idx, filepath.Join(dir, idx.FileName), nil
+		}
+	}
+	return nil, "", ErrMediaNotFound
+}
+
+func readMediaIndexes(dir string) ([]*MediaIndex, error) {
+	indexes := []*MediaIndex{}
+	if err := readJSONFile(filepath.Join(dir, mediaIndexFile), &indexes); err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+func writeMediaIndexes(dir string, indexes []*MediaIndex) error {
+	return writeJSONFile(filepath.Join(dir, mediaIndexFile), indexes)
+}
@@ -1,45 +1,214 @@
 package storage
 
 import (
-	"strings"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/pkg/routines"
+)
+
+const (
+	segmentTimeout       = 30 * time.Second // 分包超时时间，超时未收齐时请求补传
+	segmentMaxRetransmit = 3                // 最多请求补传的次数，超过后丢弃
+	segmentSweepInterval = 5 * time.Second
+	segmentTTL           = 5 * time.Minute // 分包消息的最长缓存时间，从第一个分包到达开始计算
+	segmentMaxPerDevice  = 16              // 每个终端最多缓存的未收齐分包消息数，超过时丢弃最早的
 )
 
+var ErrSegmentTotalMismatch = errors.New("segment total mismatch")
+
+// 分包超时未收齐时的回调，用于下发0x8003补传分包请求
+type SegmentRetransmitHook func(seg *model.Segment, missingIDs []uint16)
+
+// 分包缓存统计
+type SegmentStats struct {
+	Pending    int    `json:"pending"`    // 未收齐的分包消息数
+	Completed  uint64 `json:"completed"`  // 组装完成的分包消息数
+	Expired    uint64 `json:"expired"`    // 超时丢弃的分包消息数
+	Evicted    uint64 `json:"evicted"`    // 超过终端缓存上限丢弃的分包消息数
+	Retransmit uint64 `json:"retransmit"` // 请求补传的次数
+}
+
+type pendingSegment struct {
+	seg        *model.Segment
+	retransmit int // 已请求补传的次数
+}
+
 type SegmentCache struct {
-	cacheByKey map[string]*model.Segment
-	mutex      *sync.Mutex
+	cacheByPhone map[string]map[string]*pendingSegment // <终端手机号, <消息ID和第一个分包的流水号, 分包>>
+	hook         SegmentRetransmitHook
+	stats        SegmentStats
+	mutex        *sync.Mutex
 }
 
 var segmentCacheSingleton *SegmentCache
 var segmentCacheInitOnce sync.Once
 
 func getSegmentCache() *SegmentCache {
-	sessionCacheInitOnce.Do(func() {
+	segmentCacheInitOnce.Do(func() {
 		segmentCacheSingleton = &SegmentCache{
-			cacheByKey: make(map[string]*model.Segment),
-			mutex:      &sync.Mutex{},
+			cacheByPhone: make(map[string]map[string]*pendingSegment),
+			mutex:        &sync.Mutex{},
 		}
+		sweepSegments() // 定期检查超时的分包
 	})
 	return segmentCacheSingleton
 }
 
-func CacheSegment(seg *model.Segment) bool {
-	b := new(strings.Builder)
-	b.WriteString(seg.Phone)
-	b.WriteString("/")
-	b.WriteRune(rune(seg.MsgID))
-	key := b.String()
+// 以消息ID和第一个分包的流水号作为key
+func segmentKey(seg *model.Segment) string {
+	return fmt.Sprintf("%d/%d", seg.MsgID, seg.FirstSerial)
+}
+
+// CacheSegment 缓存分包，返回合并后的分包和是否已收齐。收齐后从缓存中移除，并组装好消息体。
+//
+// 补传的分包使用新的流水号，按消息ID和总包数合并到等待补传且缺少该包的消息中。总包数与已缓存的分包不一致时返回错误
+func CacheSegment(seg *model.Segment) (*model.Segment, bool, error) {
 	cache := getSegmentCache()
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
-	s, ok := cache.cacheByKey[key]
+
+	segs, ok := cache.cacheByPhone[seg.Phone]
+	if !ok {
+		segs = make(map[string]*pendingSegment)
+		cache.cacheByPhone[seg.Phone] = segs
+	}
+	key := segmentKey(seg)
+	pending, ok := segs[key]
+	if ok && pending.seg.SegTotal != seg.SegTotal {
+		return nil, false, errors.Wrapf(ErrSegmentTotalMismatch, "phone=%s, msgId=0x%04x, total=%d, cached=%d",
+			seg.Phone, seg.MsgID, seg.SegTotal, pending.seg.SegTotal)
+	}
 	if !ok {
-		cache.cacheByKey[key] = seg
-		return s.IsComplete()
+		key, pending, ok = findRetransmitted(segs, seg)
+	}
+	if ok {
+		pending.seg.Merge(seg)
+	} else {
+		cache.evict(segs)
+		key = segmentKey(seg)
+		pending = &pendingSegment{seg: seg}
+		segs[key] = pending
+	}
+
+	if !pending.seg.Assemble() {
+		return pending.seg, false, nil
+	}
+	cache.remove(pending.seg.Phone, key)
+	cache.stats.Completed++
+	return pending.seg, true, nil
+}
+
+// 补传的分包，合并到最早的等待补传且缺少该包的消息中
+func findRetransmitted(segs map[string]*pendingSegment, seg *model.Segment) (string, *pendingSegment, bool) {
+	var index uint16
+	for i := range seg.Parts {
+		index = i
+	}
+	var foundKey string
+	var found *pendingSegment
+	for key, pending := range segs {
+		s := pending.seg
+		if pending.retransmit == 0 || s.MsgID != seg.MsgID || s.SegTotal != seg.SegTotal || !s.IsMissing(index) {
+			continue
+		}
+		if found == nil || s.CreatedAt.Before(found.seg.CreatedAt) {
+			foundKey, found = key, pending
+		}
+	}
+	return foundKey, found, found != nil
+}
+
+// 终端缓存的分包消息达到上限时丢弃最早的
+func (cache *SegmentCache) evict(segs map[string]*pendingSegment) {
+	for len(segs) >= segmentMaxPerDevice {
+		var oldestKey string
+		var oldest *model.Segment
+		for key, pending := range segs {
+			if oldest == nil || pending.seg.CreatedAt.Before(oldest.CreatedAt) {
+				oldestKey, oldest = key, pending.seg
+			}
+		}
+		delete(segs, oldestKey)
+		cache.stats.Evicted++
+		log.Debug().Str("device", oldest.Phone).Uint16("msgId", oldest.MsgID).Msg("Evict segments over device limit")
+	}
+}
+
+func (cache *SegmentCache) remove(phone, key string) {
+	segs := cache.cacheByPhone[phone]
+	delete(segs, key)
+	if len(segs) == 0 {
+		delete(cache.cacheByPhone, phone)
+	}
+}
+
+// SetSegmentRetransmitHook 设置请求补传分包的回调
+func SetSegmentRetransmitHook(hook SegmentRetransmitHook) {
+	cache := getSegmentCache()
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.hook = hook
+}
+
+// GetSegmentStats 分包缓存统计
+func GetSegmentStats() SegmentStats {
+	cache := getSegmentCache()
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	stats := cache.stats
+	for _, segs := range cache.cacheByPhone {
+		stats.Pending += len(segs)
+	}
+	return stats
+}
+
+func sweepSegments() {
+	routines.GoSafe(func() {
+		for {
+			time.Sleep(segmentSweepInterval)
+			ExpireSegments(time.Now())
+		}
+	})
+}
+
+// ExpireSegments 超时未收齐的分包请求补传，多次请求后仍未收齐或超过最长缓存时间则丢弃
+func ExpireSegments(now time.Time) {
+	cache := getSegmentCache()
+	cache.mutex.Lock()
+	type request struct {
+		seg     *model.Segment
+		missing []uint16
+	}
+	requests := make([]request, 0)
+	for phone, segs := range cache.cacheByPhone {
+		for key, pending := range segs {
+			if now.Sub(pending.seg.UpdatedAt) < segmentTimeout && now.Sub(pending.seg.CreatedAt) < segmentTTL {
+				continue
+			}
+			if pending.retransmit >= segmentMaxRetransmit || cache.hook == nil || now.Sub(pending.seg.CreatedAt) >= segmentTTL {
+				cache.remove(phone, key)
+				cache.stats.Expired++
+				log.Debug().Str("device", pending.seg.Phone).Uint16("msgId", pending.seg.MsgID).
+					Msg("Drop expired segments")
+				continue
+			}
+			pending.retransmit++
+			pending.seg.UpdatedAt = now
+			cache.stats.Retransmit++
+			requests = append(requests, request{seg: pending.seg, missing: pending.seg.MissingIDs()})
+		}
+	}
+	hook := cache.hook
+	cache.mutex.Unlock()
+
+	// 回调中会发送消息，不能持有锁
+	for _, r := range requests {
+		hook(r.seg, r.missing)
 	}
-	s.Merge(seg)
-	// cache.cacheByKey[key] = s
-	return s.IsComplete()
 }
@@ -38,8 +38,16 @@ func getSessionCache() *SessionCache {
 func monitorSessionCnt() {
 	routines.GoSafe(func() {
 		for {
+			depth, dropped := 0, uint64(0)
+			for _, s := range ListSession() {
+				stats := s.WriteQueueStats()
+				depth += stats.Depth
+				dropped += stats.Dropped
+			}
 			log.Debug().
 				Int("totalConnSessions", countSession()).
+				Int("totalWriteQueueDepth", depth).
+				Uint64("totalWriteQueueDropped", dropped).
 				Msg("Monitoring total conn count")
 			time.Sleep(monitorSessionCntInterval)
 		}
@@ -76,5 +84,19 @@ func ClearSession(id string) {
 // 统计session个数
 func countSession() int {
 	c := getSessionCache()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	return len(c.cacheByID)
 }
+
+// 列出所有session
+func ListSession() []*model.Session {
+	c := getSessionCache()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sessions := make([]*model.Session, 0, len(c.cacheByID))
+	for _, s := range c.cacheByID {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+)
+
+// 每个终端保留的最近透传事件数
+const maxTransparentEvents = 20
+
+type TransparentCache struct {
+	cacheByPhone map[string][]*model.TransparentEvent
+
+	// 收到透传数据时的回调函数
+	hook ReportTransparentHandler
+
+	mutex *sync.Mutex
+}
+
+var transparentCacheSingleton *TransparentCache
+var transparentCacheInitOnce sync.Once
+
+func GetTransparentCache() *TransparentCache {
+	transparentCacheInitOnce.Do(func() {
+		transparentCacheSingleton = &TransparentCache{
+			cacheByPhone: make(map[string][]*model.TransparentEvent),
+			mutex:        &sync.Mutex{},
+		}
+	})
+	return transparentCacheSingleton
+}
+
+func (cache *TransparentCache) SetTransparentHook(handler ReportTransparentHandler) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.hook = handler
+}
+
+// CacheEvent 保存透传事件并回调，超出数量时丢弃最早的事件
+func (cache *TransparentCache) CacheEvent(event *model.TransparentEvent) {
+	cache.mutex.Lock()
+	events := append(cache.cacheByPhone[event.Phone], event)
+	if len(events) > maxTransparentEvents {
+		events = events[len(events)-maxTransparentEvents:]
+	}
+	cache.cacheByPhone[event.Phone] = events
+	hook := cache.hook
+	cache.mutex.Unlock()
+
+	if hook != nil {
+		_ = hook(event)
+	}
+}
+
+// ListEvents 终端最近的透传事件，按接收时间排列
+func (cache *TransparentCache) ListEvents(phone string) []*model.TransparentEvent {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	events := cache.cacheByPhone[phone]
+	return append(make([]*model.TransparentEvent, 0, len(events)), events...)
+}
+
+func (cache *TransparentCache) DelEvents(phone string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.cacheByPhone, phone)
+}
@@ -1,25 +1,35 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/fakeyanss/jt808-server-go/wrapper"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 
 	"github.com/fakeyanss/jt808-server-go/internal/config"
+	"github.com/fakeyanss/jt808-server-go/internal/geofence"
+	"github.com/fakeyanss/jt808-server-go/internal/handler"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol"
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
+	"github.com/fakeyanss/jt808-server-go/internal/server"
 	"github.com/fakeyanss/jt808-server-go/internal/storage"
 	"github.com/fakeyanss/jt808-server-go/pkg/logger"
 	"github.com/fakeyanss/jt808-server-go/pkg/routines"
 )
 
+// 收到退出信号后，等待处理中的消息完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	routines.Recover()
 
@@ -47,158 +57,151 @@ func main() {
 		fmt.Println(banner)
 	}
 
-	// serv := server.NewTCPServer()
-	serv := wrapper.New()
-	addr := ":" + cfg.Server.Port.TCPPort
-	err := serv.Listen(addr)
-	if err != nil {
-		log.Error().Err(err).Str("addr", addr).Msg("Fail to listen tcp addr")
-		os.Exit(1)
+	if wqConf := cfg.Server.WriteQueue; wqConf != nil {
+		model.SetWriteQueueOptions(&model.WriteQueueOptions{
+			Capacity:     wqConf.Capacity,
+			Overflow:     model.OverflowPolicy(wqConf.Overflow),
+			WriteTimeout: time.Duration(wqConf.WriteTimeout) * time.Second,
+		})
 	}
-	routines.GoSafe(func() { serv.Start() })
 
-	// web server structure
-	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-	cache := storage.GetDeviceCache()
-	geoCache := storage.GetGeoCache()
-
-	router.GET("/device", func(c *gin.Context) {
-		c.JSON(http.StatusOK, cache.ListDevice())
-	})
-
-	router.GET("/device/:phone/geo", func(c *gin.Context) {
-		phone := c.Param("phone")
+	if admConf := cfg.Server.Admission; admConf != nil {
+		server.SetAdmissionOptions(&server.AdmissionOptions{
+			MaxConns:         admConf.MaxConns,
+			MaxConnsPerIP:    admConf.MaxConnsPerIP,
+			AcceptRate:       admConf.AcceptRate,
+			HandshakeTimeout: time.Duration(admConf.HandshakeTimeout) * time.Second,
+		})
+	}
 
-		device, err := cache.GetDeviceByPhone(phone)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
-		}
+	if idleConf := cfg.Server.Idle; idleConf != nil {
+		server.SetIdleOptions(&server.IdleOptions{
+			PreAuthTimeout:  time.Duration(idleConf.PreAuthTimeout) * time.Second,
+			PostAuthTimeout: time.Duration(idleConf.PostAuthTimeout) * time.Second,
+			ReapInterval:    time.Duration(idleConf.ReapInterval) * time.Second,
+		})
+	}
 
-		res := make(map[string]any)
-		err = mapstructure.Decode(device, &res)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
-		}
-		gis, err := geoCache.GetGeoLatestByPhone(phone)
+	if proxyConf := cfg.Server.ProxyProtocol; proxyConf != nil {
+		err := server.SetProxyProtocolOptions(&server.ProxyProtocolOptions{
+			Enable:       proxyConf.Enable,
+			TrustedCIDRs: proxyConf.TrustedCIDRs,
+		})
 		if err != nil {
-			return
+			log.Error().Err(err).Msg("Fail to set proxy protocol options")
+			os.Exit(1)
 		}
-		res["gis"] = gis
+	}
 
-		c.JSON(http.StatusOK, res)
-	})
+	if verConf := cfg.Server.Version; verConf != nil {
+		protocol.SetVersion2011Phones(verConf.Version2011Phones)
+	}
 
-	router.GET("/device/:phone/params", func(c *gin.Context) {
-		phone := c.Param("phone")
-		device, err := cache.GetDeviceByPhone(phone)
+	if encConf := cfg.Server.Encryption; encConf != nil {
+		err := protocol.SetEncryptionOptions(encConf.PrivateKeyFile, encConf.MandatoryPhones)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
-		}
-		session, err := storage.GetSession(device.SessionID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-		}
-		header := model.GenMsgHeader(device, 0x8104, session.GetNextSerialNum())
-		msg := model.Msg8104{
-			Header: header,
+			log.Error().Err(err).Msg("Fail to set encryption options")
+			os.Exit(1)
 		}
-		serv.Send(session.ID, &msg)
-		// todo: read channel from process 0104 msg
-	})
+	}
 
-	router.PUT("/device/:phone/params", func(c *gin.Context) {
-		phone := c.Param("phone")
-		params := model.DeviceParams{}
-		if err := c.ShouldBind(&params); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-		}
-		device, err := cache.GetDeviceByPhone(phone)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
-		}
-		session, err := storage.GetSession(device.SessionID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-		}
-		header := model.GenMsgHeader(device, 0x8103, session.GetNextSerialNum())
-		msg := model.Msg8103{
-			Header:     header,
-			Parameters: &params,
-		}
-		serv.Send(session.ID, &msg)
-	})
+	if mediaConf := cfg.Server.Media; mediaConf != nil {
+		storage.GetMediaStore().SetMediaDir(mediaConf.Dir)
+	}
 
-	router.GET("/device/:phone/params/v2", func(c *gin.Context) {
-		phone := c.Param("phone")
-		device, err := cache.GetDeviceByPhone(phone)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
-		}
-		session, err := storage.GetSession(device.SessionID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+	upgradeScheduler := protocol.NewUpgradeScheduler()
+	if upgradeConf := cfg.Server.Upgrade; upgradeConf != nil {
+		storage.GetFirmwareStore().SetFirmwareDir(upgradeConf.Dir)
+		if err := storage.GetCampaignCache().SetCampaignDir(upgradeConf.Dir); err != nil {
+			log.Error().Err(err).Str("dir", upgradeConf.Dir).Msg("Fail to load campaigns")
+			os.Exit(1)
 		}
+		upgradeScheduler.SetResultTimeout(time.Duration(upgradeConf.ResultTimeout) * time.Second)
+	}
+	upgradeScheduler.Start()
 
-		header := model.GenMsgHeader(device, 0x8104, session.GetNextSerialNum())
-		msg := model.Msg8104{
-			Header: header,
+	if geofenceConf := cfg.Server.Geofence; geofenceConf != nil {
+		if err := storage.GetFenceStore().SetFenceDir(geofenceConf.Dir); err != nil {
+			log.Error().Err(err).Str("dir", geofenceConf.Dir).Msg("Fail to load geofences")
+			os.Exit(1)
 		}
+		geofence.GetEngine().SetHysteresis(geofenceConf.Buffer, geofenceConf.ConfirmCount)
+	}
 
-		serv.SendV2(device.Phone, &msg, func(m any) error {
-			rsp := m.(*model.Msg0104)
-			settings, err := json.Marshal(rsp.Parameters)
-
-			log.Debug().
-				Msgf("执行0x8104消息返回结果：%s => code:%v, res:%s", rsp.GetHeader().PhoneNumber, err, settings)
-
-			return nil
-		})
-	})
+	// serv := server.NewTCPServer()
+	serv := wrapper.New()
+	addr := ":" + cfg.Server.Port.TCPPort
+	err := serv.Listen(addr)
+	if err != nil {
+		log.Error().Err(err).Str("addr", addr).Msg("Fail to listen tcp addr")
+		os.Exit(1)
+	}
+	routines.GoSafe(func() { serv.Start() })
+	servers := []server.Server{serv}
 
-	router.PUT("/device/:phone/params/v2", func(c *gin.Context) {
-		phone := c.Param("phone")
-		params := model.DeviceParams{}
-		if err := c.ShouldBind(&params); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-		}
-		device, err := cache.GetDeviceByPhone(phone)
+	if cfg.Server.Port.UDPPort != "" {
+		udpServ := server.NewUDPServer()
+		udpAddr := ":" + cfg.Server.Port.UDPPort
+		err = udpServ.Listen(udpAddr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-			return
+			log.Error().Err(err).Str("addr", udpAddr).Msg("Fail to listen udp addr")
+			os.Exit(1)
 		}
-		session, err := storage.GetSession(device.SessionID)
+		routines.GoSafe(func() { udpServ.Start() })
+		servers = append(servers, udpServ)
+	}
+
+	if tlsConf := cfg.Server.TLS; tlsConf != nil && tlsConf.Enable {
+		tlsServ := server.NewTCPServer()
+		tlsAddr := ":" + tlsConf.Port
+		err = tlsServ.ListenTLS(tlsAddr, tlsConf.CertFile, tlsConf.KeyFile, tlsConf.ClientCAFile)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
-		}
-		header := model.GenMsgHeader(device, 0x8103, session.GetNextSerialNum())
-		msg := model.Msg8103{
-			Header:     header,
-			Parameters: &params,
+			log.Error().Err(err).Str("addr", tlsAddr).Msg("Fail to listen tls addr")
+			os.Exit(1)
 		}
+		routines.GoSafe(func() { tlsServ.Start() })
+		servers = append(servers, tlsServ)
+	}
 
-		serv.SendV2(device.Phone, &msg, func(m any) error {
-			rsp := m.(*model.Msg0001)
-			log.Debug().
-				Msgf("执行0x8103消息返回结果：%s", rsp.Result2Str())
-			return nil
-		})
-	})
+	// web server structure
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.Default()
+	handler.Register(router, serv)
 
 	httpAddr := ":" + cfg.Server.Port.HTTPPort
+	httpServ := &http.Server{Addr: httpAddr, Handler: router}
 	routines.GoSafe(func() {
 		log.Debug().Msgf("Listening and serving HTTP on :%s", cfg.Server.Port.HTTPPort)
-		err = router.Run(httpAddr)
-		if err != nil {
+		err := httpServ.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error().Err(err).Str("addr", httpAddr).Msg("Fail to run gin router")
 			os.Exit(1)
 		}
 	})
 
-	select {} // block here
+	// 等待退出信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	log.Info().Str("signal", sig.String()).Msg("Shutting down server...")
+
+	shutdown(servers, httpServ)
+}
+
+// 依次停止jt808 server、保活检查、http server，超时后强制退出
+func shutdown(servers []server.Server, httpServ *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, s := range servers {
+		if err := s.Stop(ctx); err != nil {
+			log.Error().Err(err).Msg("Fail to stop jt808 server gracefully")
+		}
+	}
+	protocol.NewKeepaliveTimer().Stop()
+	protocol.NewUpgradeScheduler().Stop()
+	if err := httpServ.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Fail to shutdown http server gracefully")
+	}
+	log.Info().Msg("Server exited")
 }
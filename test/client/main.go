@@ -9,10 +9,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 
 	"github.com/fakeyanss/jt808-server-go/internal/client"
 	"github.com/fakeyanss/jt808-server-go/internal/config"
+	"github.com/fakeyanss/jt808-server-go/internal/protocol"
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 	"github.com/fakeyanss/jt808-server-go/internal/storage"
 	"github.com/fakeyanss/jt808-server-go/pkg/logger"
@@ -25,6 +27,11 @@ const (
 	retryIntervalInSecond = 2
 )
 
+var (
+	clients   sync.Map // <phone, *client.TCPClient>
+	redirects sync.Map // <phone, *model.ConnectServerParams>, 待切换的服务器
+)
+
 type (
 	DeviceConfCtxKey struct{}
 
@@ -80,9 +87,7 @@ func buildDeviceGeo(ctx context.Context) {
 	device := getDevice(ctx)
 	deviceGeoConf := ctx.Value(DeviceGeoConfCtxKey{}).(*config.DeviceGeoConf)
 	deviceGeo := datagen.GenDeviceGeo(deviceGeoConf, device)
-	geoCache := storage.GetGeoCache()
-	rb := geoCache.GetGeoRingByPhone(device.Phone)
-	rb.Write(deviceGeo)
+	storage.GetGeoCache().SaveGeoInfoByPhone(device.Phone, deviceGeo)
 }
 
 func reportLocation(ctx context.Context, cli *client.TCPClient) {
@@ -131,24 +136,40 @@ done:
 func do(cfg *config.Config,
 	cliWg *sync.WaitGroup,
 	cli *client.TCPClient,
-	pctx context.Context) {
+	pctx context.Context,
+	d *model.Device) {
 
 	tctx, cancel := context.WithCancel(pctx)
-	routines.GoSafe(func() {
-		defer cliWg.Done()
+	ctx := context.WithValue(tctx, DeviceConfCtxKey{}, cfg.Client.Device)
+	newDevice := d == nil
+	if newDevice {
+		d = buildDevice(ctx, cli)
+	} else {
+		rebindDevice(d, cli)
+	}
+	ctx = context.WithValue(ctx, DevicePhoneCtxKey{}, d.Phone)
+	ctx = context.WithValue(ctx, DeviceGeoConfCtxKey{}, cfg.Client.DeviceGeo)
+	clients.Store(d.Phone, cli)
 
+	routines.GoSafe(func() {
 		log.Debug().Msgf("start tcp client...")
 		cli.Start()
 
 		cancel()
+
+		// 收到连接指定服务器的控制命令，沿用当前终端重新连接
+		if p, ok := redirects.LoadAndDelete(d.Phone); ok {
+			redirect(cfg, cliWg, d, p.(*model.ConnectServerParams), pctx)
+			return
+		}
+		clients.Delete(d.Phone)
+		cliWg.Done()
 	})
 
 	routines.GoSafe(func() {
-		ctx := context.WithValue(tctx, DeviceConfCtxKey{}, cfg.Client.Device)
-		d := buildDevice(ctx, cli)
-		ctx = context.WithValue(ctx, DevicePhoneCtxKey{}, d.Phone)
-		ctx = context.WithValue(ctx, DeviceGeoConfCtxKey{}, cfg.Client.DeviceGeo)
-		buildDeviceGeo(ctx)
+		if newDevice {
+			buildDeviceGeo(ctx)
+		}
 
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -195,13 +216,73 @@ func do(cfg *config.Config,
 	})
 }
 
+// 终端沿用到新连接，需要重新注册
+func rebindDevice(d *model.Device, cli *client.TCPClient) {
+	d.SessionID = cli.Session.ID
+	d.Conn = cli.Session.Conn
+	d.Status = model.DeviceStatusOffline
+	storage.GetDeviceCache().CacheDevice(d)
+}
+
+// 处理平台下发的终端控制，目前只模拟连接指定服务器
+func handleTerminalControl(phone string, msg *model.Msg8105) error {
+	if msg.Command != model.TerminalCommandConnectServer {
+		log.Info().Str("phone", phone).Msgf("Ignore terminal control command: %s", model.TerminalCommand2Str(msg.Command))
+		return nil
+	}
+	p, err := msg.ConnectServerParams()
+	if err != nil {
+		return err
+	}
+	cli, ok := clients.Load(phone)
+	if !ok {
+		return errors.Errorf("Fail to find client, phone=%s", phone)
+	}
+	redirects.Store(phone, p)
+	// 通用应答已放入发送队列，关闭会话时写完再断开
+	cli.(*client.TCPClient).Stop()
+	return nil
+}
+
+// 连接指定服务器，时限内未连接成功时连回原服务器
+func redirect(cfg *config.Config, cliWg *sync.WaitGroup, d *model.Device, p *model.ConnectServerParams, pctx context.Context) {
+	defaultAddr := cfg.Client.Conn.RemoteAddr
+	cli := client.NewTCPClient()
+	if p.ConnectionControl == model.ConnectDefaultServer {
+		dial(cli, defaultAddr, pctx)
+		do(cfg, cliWg, cli, pctx, d)
+		return
+	}
+
+	addr := p.TCPAddr()
+	log.Info().Str("phone", d.Phone).Str("addr", addr).Msg("Redirect to the specified server")
+	if err := dialWithin(cli, addr, time.Duration(p.TimeLimit)*time.Minute); err != nil {
+		log.Error().Err(err).Str("addr", addr).Msg("Fail to dial to the specified server, back to the default server")
+		dial(cli, defaultAddr, pctx)
+	}
+	do(cfg, cliWg, cli, pctx, d)
+}
+
+// 在时限内重试连接，时限为0时只尝试一次
+func dialWithin(cli *client.TCPClient, addr string, limit time.Duration) error {
+	deadline := time.Now().Add(limit)
+	for {
+		err := cli.Dial(addr)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		log.Error().Err(err).Str("addr", addr).Msg("Fail to dial to the tcp addr, retry")
+		time.Sleep(retryIntervalInSecond * time.Second)
+	}
+}
+
 func dialAndSend(cfg *config.Config, cliWg *sync.WaitGroup, tctx context.Context) {
 	for i := 0; i < cfg.Client.Concurrency; i++ {
 		cli := client.NewTCPClient()
 		addr := cfg.Client.Conn.RemoteAddr
 
 		dial(cli, addr, tctx)
-		do(cfg, cliWg, cli, tctx)
+		do(cfg, cliWg, cli, tctx, nil)
 	}
 }
 
@@ -224,6 +305,8 @@ func main() {
 	logCfg := config.ParseLoggerConfig(cfg.Log)
 	log.Logger = *logger.Configure(logCfg).Logger
 
+	protocol.RegisterTerminalControlHandler(handleTerminalControl)
+
 	var cliWg sync.WaitGroup
 	cliWg.Add(cfg.Client.Concurrency)
 	tctx, _ := context.WithCancel(context.Background())
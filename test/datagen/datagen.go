@@ -83,7 +83,7 @@ func genMsgHeader(msgID uint16, device *model.Device) *model.MsgHeader {
 		msgHeader.ProtocolVersion = 1
 	} else {
 		msgHeader.Attr.VersionSign = 0
-		msgHeader.Attr.VersionDesc = model.Version2013
+		msgHeader.Attr.VersionDesc = device.VersionDesc // 2011和2013版本
 		msgHeader.ProtocolVersion = 0
 	}
 	return msgHeader
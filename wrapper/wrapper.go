@@ -56,6 +56,10 @@ type EventHandlers struct {
 	OnDeviceStatusChange func(DeviceStatus) error
 	// json string
 	OnReportAlarmMsg func([]byte) error
+	// json string
+	OnTransparentData func([]byte) error
+	// json string
+	OnGeofenceEvent func([]byte) error
 }
 
 func (s *Jt808Server) SetLogger(c *LogConf) {
@@ -82,6 +86,24 @@ func (s *Jt808Server) SetHooks(handlers EventHandlers) error {
 
 		return handlers.OnReportAlarmMsg(data)
 	})
+	if handlers.OnTransparentData != nil {
+		storage.GetTransparentCache().SetTransparentHook(func(event *model.TransparentEvent) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			return handlers.OnTransparentData(data)
+		})
+	}
+	if handlers.OnGeofenceEvent != nil {
+		storage.GetGeofenceEventCache().SetGeofenceHook(func(event *model.GeofenceEvent) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			return handlers.OnGeofenceEvent(data)
+		})
+	}
 
 	return nil
 }
@@ -155,6 +177,76 @@ func (s *Jt808Server) SetDeviceConfig(data []byte, to int) error {
 	return err
 }
 
+type TerminalCommand = model.TerminalCommand
+
+const (
+	TerminalCommandConnectServer = model.TerminalCommandConnectServer
+	TerminalCommandPowerOff      = model.TerminalCommandPowerOff
+	TerminalCommandReset         = model.TerminalCommandReset
+	TerminalCommandFactoryReset  = model.TerminalCommandFactoryReset
+	TerminalCommandCloseDataComm = model.TerminalCommandCloseDataComm
+	TerminalCommandCloseWireless = model.TerminalCommandCloseWireless
+)
+
+// TerminalControl
+// 向指定终端下发控制命令，如复位、恢复出厂设置、关机、关闭无线通信，phones不能为空，任一终端应答非成功时返回错误
+func (s *Jt808Server) TerminalControl(phones []string, cmd TerminalCommand, to int) error {
+	if cmd == TerminalCommandConnectServer {
+		return errors.New("connect server command should use ConnectServer")
+	}
+	return s.sendTerminalControl(phones, cmd, nil, to)
+}
+
+// ConnectServer
+// 控制指定终端连接指定服务器，json string，字段参考model.ConnectServerParams，phones不能为空
+func (s *Jt808Server) ConnectServer(phones []string, data []byte, to int) error {
+	var params = &model.ConnectServerParams{}
+	if err := json.Unmarshal(data, params); err != nil {
+		return err
+	}
+	return s.sendTerminalControl(phones, TerminalCommandConnectServer, params, to)
+}
+
+func (s *Jt808Server) sendTerminalControl(phones []string, cmd TerminalCommand, params *model.ConnectServerParams, to int) error {
+	// 控制命令影响终端运行，必须指定终端，不允许广播
+	if len(phones) == 0 {
+		return errors.New("no target phones, terminal control must specify devices")
+	}
+	// 先校验参数，避免下发到终端后才失败
+	if _, err := model.NewMsg8105(cmd, params); err != nil {
+		return err
+	}
+
+	cache := storage.GetDeviceCache()
+	devices := make([]*model.Device, 0, len(phones))
+	for _, phone := range phones {
+		device, err := cache.GetDeviceByPhone(phone)
+		if err != nil {
+			return err
+		}
+		devices = append(devices, device)
+	}
+
+	return s.sendToDevices(devices, 0x8105, func(header *model.MsgHeader) model.JT808Msg {
+		msg, _ := model.NewMsg8105(cmd, params)
+		msg.Header = header
+		return msg
+	}, func(m any) error {
+		rsp := m.(*model.Msg0001)
+		r := result{
+			phone: rsp.GetHeader().PhoneNumber,
+			code:  rsp.Result,
+			desc:  model.Msg0001Result2Str(rsp.Result),
+		}
+		log.Debug().
+			Msgf("执行0x8105消息(%s)返回结果：%s => %d,%s", model.TerminalCommand2Str(cmd), r.phone, r.code, r.desc)
+		if r.code != 0 {
+			return errors.Errorf("%s:%d,%s", r.phone, r.code, r.desc)
+		}
+		return nil
+	}, to)
+}
+
 func (s *Jt808Server) send2Devices(msgId uint16,
 	buildMsgFn func(msg *model.MsgHeader) model.JT808Msg,
 	procRspFn func(m any) error,
@@ -163,12 +255,20 @@ func (s *Jt808Server) send2Devices(msgId uint16,
 
 	// 我们现在的使用情况来看，只会有一个设备
 	all := cache.ListDevice()
-	total := len(all)
-	if total == 0 {
+	if len(all) == 0 {
 		err := errors.New("no devices, set device config failed")
 		return err
 	}
+	return s.sendToDevices(all, msgId, buildMsgFn, procRspFn, to)
+}
 
+// 向指定终端下发消息，等待全部应答或超时
+func (s *Jt808Server) sendToDevices(all []*model.Device,
+	msgId uint16,
+	buildMsgFn func(msg *model.MsgHeader) model.JT808Msg,
+	procRspFn func(m any) error,
+	to int) error {
+	total := len(all)
 	waitChan := make(chan error, total)
 	for _, device := range all {
 		session, err := storage.GetSession(device.SessionID)
@@ -1,6 +1,7 @@
 package wrapper
 
 import (
+	"context"
 	"github.com/fakeyanss/jt808-server-go/internal/protocol/model"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -19,7 +20,7 @@ func TestNew(t *testing.T) {
 	}()
 
 	time.Sleep(10 * time.Second)
-	s.Stop()
+	_ = s.Stop(context.Background())
 }
 
 func TestJt808Server_SetHooks(t *testing.T) {
@@ -50,7 +51,7 @@ func TestJt808Server_SetHooks(t *testing.T) {
 	}()
 
 	time.Sleep(100 * time.Second)
-	s.Stop()
+	_ = s.Stop(context.Background())
 }
 
 func TestJt808Server_SetDeviceConfig(t *testing.T) {
@@ -102,7 +103,7 @@ NEXT:
 	assert.Nil(t, err)
 
 	// time.Sleep(100 * time.Second)
-	s.Stop()
+	_ = s.Stop(context.Background())
 }
 
 func TestJt808Server_GetDeviceConfig(t *testing.T) {
@@ -154,5 +155,13 @@ NEXT:
 	t.Logf("get config:%s", conf)
 
 	// time.Sleep(100 * time.Second)
-	s.Stop()
+	_ = s.Stop(context.Background())
+}
+
+func TestJt808Server_TerminalControlRequiresPhones(t *testing.T) {
+	s := New()
+
+	assert.Error(t, s.TerminalControl(nil, TerminalCommandReset, 1))
+	assert.Error(t, s.ConnectServer([]string{}, []byte(`{"connect":1}`), 1))
+	assert.Error(t, s.TerminalControl([]string{"13800000000"}, TerminalCommandPowerOff, 1))
 }